@@ -8,7 +8,15 @@ import (
 	"github.com/sp00nznet/octopus/internal/api"
 	"github.com/sp00nznet/octopus/internal/config"
 	"github.com/sp00nznet/octopus/internal/db"
+	"github.com/sp00nznet/octopus/internal/discovery"
 	"github.com/sp00nznet/octopus/internal/scheduler"
+
+	// Blank-imported so their init() functions register with the
+	// internal/cloud driver registry; nothing here calls them directly.
+	_ "github.com/sp00nznet/octopus/internal/providers/aws"
+	_ "github.com/sp00nznet/octopus/internal/providers/azure"
+	_ "github.com/sp00nznet/octopus/internal/providers/gcp"
+	_ "github.com/sp00nznet/octopus/internal/providers/vmware"
 )
 
 func main() {
@@ -31,9 +39,13 @@ func main() {
 	}
 
 	// Initialize scheduler for cutover/failover tasks
-	sched := scheduler.New(database)
+	sched := scheduler.New(database, cfg)
 	go sched.Start()
 
+	// Initialize background VM discovery reconciliation
+	disc := discovery.New(database, cfg)
+	go disc.Start()
+
 	// Initialize API server
 	server := api.NewServer(cfg, database, sched)
 
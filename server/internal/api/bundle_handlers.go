@@ -0,0 +1,364 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sp00nznet/octopus/internal/bundle"
+	"github.com/sp00nznet/octopus/internal/db"
+)
+
+// exportBundle handles GET /export?include=environments,migrations,vms,
+// producing a versioned binary bundle of the selected tables. Source
+// environment passwords are wrapped under the key in the key query
+// parameter (falling back to the server's configured
+// BundleEncryptionKey), so the exported file can be handed to another
+// instance's /import without exposing credentials in the clear.
+func (s *Server) exportBundle(w http.ResponseWriter, r *http.Request) {
+	include := strings.Split(r.URL.Query().Get("include"), ",")
+	if r.URL.Query().Get("include") == "" {
+		include = []string{"environments", "vms", "migrations"}
+	}
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		key = s.config.BundleEncryptionKey
+	}
+
+	wantEnvironments := containsString(include, "environments")
+	wantVMs := containsString(include, "vms")
+	wantMigrations := containsString(include, "migrations")
+
+	b := bundle.NewBundle(include)
+
+	if wantEnvironments {
+		sourceEnvs, err := s.allSourceEnvironments()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to load source environments")
+			return
+		}
+		for _, env := range sourceEnvs {
+			plaintext, err := s.secrets.Decrypt(env.Password)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to decrypt source environment credentials")
+				return
+			}
+			wrapped, err := bundle.WrapCredential(key, plaintext)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to wrap source environment credentials")
+				return
+			}
+			env.Password = wrapped
+			if err := b.Add(bundle.TypeSourceEnvironment, env); err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to encode source environment")
+				return
+			}
+		}
+
+		targetEnvs, err := s.allTargetEnvironments()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to load target environments")
+			return
+		}
+		for _, env := range targetEnvs {
+			if err := b.Add(bundle.TypeTargetEnvironment, env); err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to encode target environment")
+				return
+			}
+		}
+	}
+
+	if wantVMs {
+		vms, err := s.allVMs()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to load VMs")
+			return
+		}
+		for _, vm := range vms {
+			if err := b.Add(bundle.TypeVM, vm); err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to encode VM")
+				return
+			}
+		}
+	}
+
+	if wantMigrations {
+		jobs, err := s.allMigrationJobs()
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to load migration jobs")
+			return
+		}
+		for _, job := range jobs {
+			if err := b.Add(bundle.TypeMigrationJob, job); err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to encode migration job")
+				return
+			}
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", "attachment; filename=\"octopus-export.bundle\"")
+	if err := bundle.Write(w, b); err != nil {
+		log.Printf("bundle: failed to write export: %v", err)
+	}
+}
+
+// importBundle handles POST /import?key=<export key>, loading a bundle
+// produced by exportBundle. Primary keys are rewritten as each record is
+// inserted (SQLite assigns new autoincrement IDs), and foreign keys on VM
+// and MigrationJob records are remapped using the IDs observed earlier in
+// the same bundle. The response is the remap table so the caller can
+// reconcile references it holds outside the bundle.
+func (s *Server) importBundle(w http.ResponseWriter, r *http.Request) {
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		key = s.config.BundleEncryptionKey
+	}
+
+	b, err := bundle.Read(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid bundle: "+err.Error())
+		return
+	}
+
+	remap := map[string]map[int64]int64{
+		bundle.TypeSourceEnvironment: {},
+		bundle.TypeTargetEnvironment: {},
+		bundle.TypeVM:                {},
+		bundle.TypeMigrationJob:      {},
+	}
+
+	for _, rec := range b.Records {
+		switch rec.Type {
+		case bundle.TypeSourceEnvironment:
+			var env db.SourceEnvironment
+			if err := rec.Decode(&env); err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid source environment record: "+err.Error())
+				return
+			}
+			plaintext, err := bundle.UnwrapCredential(key, env.Password)
+			if err != nil {
+				respondError(w, http.StatusBadRequest, "Failed to unwrap source environment credentials: "+err.Error())
+				return
+			}
+			encrypted, err := s.secrets.Encrypt(plaintext)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to encrypt source environment credentials")
+				return
+			}
+			newID, err := s.insertImportedSourceEnvironment(env, encrypted)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to import source environment")
+				return
+			}
+			remap[bundle.TypeSourceEnvironment][env.ID] = newID
+
+		case bundle.TypeTargetEnvironment:
+			var env db.TargetEnvironment
+			if err := rec.Decode(&env); err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid target environment record: "+err.Error())
+				return
+			}
+			newID, err := s.insertImportedTargetEnvironment(env)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to import target environment")
+				return
+			}
+			remap[bundle.TypeTargetEnvironment][env.ID] = newID
+
+		case bundle.TypeVM:
+			var vm db.VM
+			if err := rec.Decode(&vm); err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid VM record: "+err.Error())
+				return
+			}
+			vm.SourceEnvID = remap[bundle.TypeSourceEnvironment][vm.SourceEnvID]
+			newID, err := s.insertImportedVM(vm)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to import VM")
+				return
+			}
+			remap[bundle.TypeVM][vm.ID] = newID
+
+		case bundle.TypeMigrationJob:
+			var job db.MigrationJob
+			if err := rec.Decode(&job); err != nil {
+				respondError(w, http.StatusBadRequest, "Invalid migration job record: "+err.Error())
+				return
+			}
+			job.VMID = remap[bundle.TypeVM][job.VMID]
+			job.SourceEnvID = remap[bundle.TypeSourceEnvironment][job.SourceEnvID]
+			job.TargetEnvID = remap[bundle.TypeTargetEnvironment][job.TargetEnvID]
+			newID, err := s.insertImportedMigrationJob(job)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, "Failed to import migration job")
+				return
+			}
+			remap[bundle.TypeMigrationJob][job.ID] = newID
+
+		default:
+			respondError(w, http.StatusBadRequest, "Unknown record type in bundle: "+rec.Type)
+			return
+		}
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"schema_version": b.Manifest.SchemaVersion,
+		"imported_at":    time.Now(),
+		"id_remap":       remap,
+	})
+}
+
+func containsString(list []string, needle string) bool {
+	for _, v := range list {
+		if strings.TrimSpace(v) == needle {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) allSourceEnvironments() ([]db.SourceEnvironment, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, type, host, username, password, datacenter, cluster, COALESCE(config_json, ''), created_at, updated_at
+		FROM source_environments
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var envs []db.SourceEnvironment
+	for rows.Next() {
+		var env db.SourceEnvironment
+		if err := rows.Scan(&env.ID, &env.Name, &env.Type, &env.Host, &env.Username, &env.Password,
+			&env.Datacenter, &env.Cluster, &env.ConfigJSON, &env.CreatedAt, &env.UpdatedAt); err != nil {
+			continue
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+func (s *Server) allTargetEnvironments() ([]db.TargetEnvironment, error) {
+	rows, err := s.db.Query(`
+		SELECT id, name, type, config_json, created_at, updated_at
+		FROM target_environments
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var envs []db.TargetEnvironment
+	for rows.Next() {
+		var env db.TargetEnvironment
+		if err := rows.Scan(&env.ID, &env.Name, &env.Type, &env.ConfigJSON, &env.CreatedAt, &env.UpdatedAt); err != nil {
+			continue
+		}
+		envs = append(envs, env)
+	}
+	return envs, nil
+}
+
+func (s *Server) allVMs() ([]db.VM, error) {
+	rows, err := s.db.Query(`
+		SELECT id, source_env_id, name, uuid, cpu_count, memory_mb, disk_size_gb, guest_os,
+			power_state, ip_addresses, mac_addresses, port_groups, hardware_version, vmware_tools_status,
+			last_synced, created_at
+		FROM vms
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var vms []db.VM
+	for rows.Next() {
+		var vm db.VM
+		if err := rows.Scan(&vm.ID, &vm.SourceEnvID, &vm.Name, &vm.UUID, &vm.CPUCount, &vm.MemoryMB,
+			&vm.DiskSizeGB, &vm.GuestOS, &vm.PowerState, &vm.IPAddresses, &vm.MACAddresses, &vm.PortGroups,
+			&vm.HardwareVersion, &vm.VMwareToolsStatus, &vm.LastSynced, &vm.CreatedAt); err != nil {
+			continue
+		}
+		vms = append(vms, vm)
+	}
+	return vms, nil
+}
+
+func (s *Server) allMigrationJobs() ([]db.MigrationJob, error) {
+	rows, err := s.db.Query(`
+		SELECT id, vm_id, source_env_id, target_env_id, status, progress, preserve_mac,
+			preserve_port_groups, sync_interval_minutes, scheduled_cutover, error_message,
+			created_by, created_at, started_at, completed_at
+		FROM migration_jobs
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []db.MigrationJob
+	for rows.Next() {
+		var job db.MigrationJob
+		if err := rows.Scan(&job.ID, &job.VMID, &job.SourceEnvID, &job.TargetEnvID, &job.Status, &job.Progress,
+			&job.PreserveMAC, &job.PreservePortGroups, &job.SyncIntervalMinutes, &job.ScheduledCutover,
+			&job.ErrorMessage, &job.CreatedBy, &job.CreatedAt, &job.StartedAt, &job.CompletedAt); err != nil {
+			continue
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+func (s *Server) insertImportedSourceEnvironment(env db.SourceEnvironment, password string) (int64, error) {
+	result, err := s.db.Exec(`
+		INSERT INTO source_environments (name, type, host, username, password, datacenter, cluster, config_json)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, env.Name, env.Type, env.Host, env.Username, password, env.Datacenter, env.Cluster, env.ConfigJSON)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (s *Server) insertImportedTargetEnvironment(env db.TargetEnvironment) (int64, error) {
+	result, err := s.db.Exec(`
+		INSERT INTO target_environments (name, type, config_json)
+		VALUES (?, ?, ?)
+	`, env.Name, env.Type, env.ConfigJSON)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (s *Server) insertImportedVM(vm db.VM) (int64, error) {
+	result, err := s.db.Exec(`
+		INSERT INTO vms (source_env_id, name, uuid, cpu_count, memory_mb, disk_size_gb, guest_os,
+			power_state, ip_addresses, mac_addresses, port_groups, hardware_version, vmware_tools_status, last_synced)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, vm.SourceEnvID, vm.Name, vm.UUID, vm.CPUCount, vm.MemoryMB, vm.DiskSizeGB, vm.GuestOS,
+		vm.PowerState, vm.IPAddresses, vm.MACAddresses, vm.PortGroups, vm.HardwareVersion,
+		vm.VMwareToolsStatus, vm.LastSynced)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
+
+func (s *Server) insertImportedMigrationJob(job db.MigrationJob) (int64, error) {
+	result, err := s.db.Exec(`
+		INSERT INTO migration_jobs (vm_id, source_env_id, target_env_id, status, progress, preserve_mac,
+			preserve_port_groups, sync_interval_minutes, scheduled_cutover, error_message, created_by,
+			started_at, completed_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, job.VMID, job.SourceEnvID, job.TargetEnvID, job.Status, job.Progress, job.PreserveMAC,
+		job.PreservePortGroups, job.SyncIntervalMinutes, job.ScheduledCutover, job.ErrorMessage,
+		job.CreatedBy, job.StartedAt, job.CompletedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.LastInsertId()
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sp00nznet/octopus/internal/db"
+)
+
+// listUserTasks returns the actionable cards internal/discovery has
+// materialized, most recently updated first. Pass ?state=open to hide
+// already-acknowledged ones.
+func (s *Server) listUserTasks(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+
+	query := `
+		SELECT id, source_env_id, task_type, affected_resources_json, state, created_at, updated_at, acknowledged_at
+		FROM user_tasks
+	`
+	var args []interface{}
+	if state != "" {
+		query += " WHERE state = ?"
+		args = append(args, state)
+	}
+	query += " ORDER BY updated_at DESC"
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	defer rows.Close()
+
+	var tasks []db.UserTask
+	for rows.Next() {
+		var t db.UserTask
+		if err := rows.Scan(&t.ID, &t.SourceEnvID, &t.TaskType, &t.AffectedResourcesJSON,
+			&t.State, &t.CreatedAt, &t.UpdatedAt, &t.AcknowledgedAt); err != nil {
+			continue
+		}
+		tasks = append(tasks, t)
+	}
+
+	respondJSON(w, http.StatusOK, tasks)
+}
+
+// ackUserTask acknowledges a user_tasks row, so the admin UI can dismiss its
+// card without waiting for the underlying discovery failure to clear on its
+// own (the discovery controller doesn't reopen an acknowledged task - a
+// fresh failure after acknowledgement starts a new one).
+func (s *Server) ackUserTask(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	now := time.Now()
+	result, err := s.db.Exec(`
+		UPDATE user_tasks SET state = 'acknowledged', acknowledged_at = ?, updated_at = ?
+		WHERE id = ? AND state = 'open'
+	`, now, now, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to acknowledge task")
+		return
+	}
+	affected, _ := result.RowsAffected()
+	if affected == 0 {
+		var exists bool
+		err := s.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM user_tasks WHERE id = ?)`, id).Scan(&exists)
+		if err != nil || !exists {
+			respondError(w, http.StatusNotFound, "Task not found")
+			return
+		}
+		respondError(w, http.StatusConflict, "Task is already acknowledged")
+		return
+	}
+
+	username, _ := r.Context().Value("username").(string)
+	taskIDInt, _ := strconv.ParseInt(id, 10, 64)
+	s.logActivity(username, "ack_user_task", "user_task", taskIDInt, "", r.RemoteAddr, requestIDFromContext(r.Context()))
+
+	respondJSON(w, http.StatusOK, map[string]string{"status": "acknowledged"})
+}
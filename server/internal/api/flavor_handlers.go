@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sp00nznet/octopus/internal/cloud"
+	"github.com/sp00nznet/octopus/internal/db"
+)
+
+// flavorCacheTTL bounds how long a target's flavor catalog is cached on
+// disk before estimateFlavor re-lists it, so repeated dry-runs against the
+// same target don't hammer the provider's API.
+const flavorCacheTTL = 1 * time.Hour
+
+// estimateFlavor is a --dry-run style estimator: given a target environment
+// and a desired shape, it resolves the cheapest real instance type/flavor
+// that satisfies the shape under the default overcommit policy, without
+// provisioning anything, so an operator can see the suggested cutover
+// target before triggering one. Only providers implementing
+// cloud.FlavorAware (gcp today) support this; others get a 501.
+func (s *Server) estimateFlavor(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var target db.TargetEnvironment
+	err := s.db.QueryRow(`
+		SELECT id, name, type, config_json FROM target_environments WHERE id = ?
+	`, id).Scan(&target.ID, &target.Name, &target.Type, &target.ConfigJSON)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Target environment not found")
+		return
+	}
+
+	var req struct {
+		CPU      int     `json:"cpu"`
+		MemoryGB float64 `json:"memory_gb"`
+		GPU      bool    `json:"gpu"`
+		LocalSSD bool    `json:"local_ssd"`
+		Arch     string  `json:"arch"`
+		Region   string  `json:"region"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.CPU <= 0 || req.MemoryGB <= 0 {
+		respondError(w, http.StatusBadRequest, "cpu and memory_gb are required")
+		return
+	}
+
+	decryptedJSON, err := s.decryptSecretFields(target.Type, []byte(target.ConfigJSON))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to decrypt target config: "+err.Error())
+		return
+	}
+
+	provider, err := cloud.New(target.Type, decryptedJSON)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to initialize target provider: "+err.Error())
+		return
+	}
+
+	flavorAware, ok := provider.(cloud.FlavorAware)
+	if !ok {
+		respondError(w, http.StatusNotImplemented, "Target type "+target.Type+" does not support live flavor resolution yet")
+		return
+	}
+
+	flavorReq := cloud.FlavorRequest{
+		CPU:      req.CPU,
+		MemoryGB: req.MemoryGB,
+		GPU:      req.GPU,
+		LocalSSD: req.LocalSSD,
+		Arch:     req.Arch,
+		Region:   req.Region,
+	}
+
+	cache := cloud.NewFlavorCache(s.config.FlavorCacheDir, flavorCacheTTL)
+	candidate, rationale, err := cloud.ResolveFlavor(flavorAware.FlavorResolver(), cache, flavorReq, cloud.DefaultOvercommitPolicy)
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+
+	username, _ := r.Context().Value("username").(string)
+	s.logActivity(username, "estimate_flavor", "target_environment", target.ID, rationale, r.RemoteAddr, requestIDFromContext(r.Context()))
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"flavor":    candidate,
+		"rationale": rationale,
+	})
+}
@@ -1,18 +1,39 @@
 package api
 
 import (
+	"context"
+	"crypto/rand"
 	"database/sql"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/sp00nznet/octopus/internal/auth"
 	"github.com/sp00nznet/octopus/internal/db"
+	"github.com/sp00nznet/octopus/internal/lease"
+	"github.com/sp00nznet/octopus/internal/migrationstream"
+	"github.com/sp00nznet/octopus/internal/operations"
 	"github.com/sp00nznet/octopus/internal/providers/vmware"
 	"github.com/sp00nznet/octopus/internal/sync"
+	"github.com/sp00nznet/octopus/internal/syncer"
+	"github.com/sp00nznet/octopus/internal/syncjob"
+	"github.com/sp00nznet/octopus/internal/webhooks"
 )
 
+// oidcStateCookie names the short-lived cookie used to carry the anti-CSRF
+// state value between /auth/oidc/login and /auth/oidc/callback.
+const oidcStateCookie = "octopus_oidc_state"
+
+// migrationLeaseTTL bounds how long a sync or cutover may run before its
+// heartbeat must renew the lease; see internal/lease.
+const migrationLeaseTTL = 30 * time.Second
+
 // Authentication handlers
 func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 	var creds struct {
@@ -25,14 +46,158 @@ func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, token, err := s.auth.Authenticate(creds.Username, creds.Password)
+	user, accessToken, refreshToken, err := s.auth.Authenticate(creds.Username, creds.Password)
 	if err != nil {
 		respondError(w, http.StatusUnauthorized, "Invalid credentials")
 		return
 	}
 
-	// Update or create user in database
-	_, err = s.db.Exec(`
+	s.upsertUser(user)
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"user":          user,
+	})
+}
+
+// refreshToken redeems a refresh token for a new access/refresh token pair.
+func (s *Server) refreshToken(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.RefreshToken == "" {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	user, accessToken, refreshToken, err := s.auth.Refresh(body.RefreshToken)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "Invalid refresh token")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"user":          user,
+	})
+}
+
+// logout revokes the caller's access token so it can no longer be used even
+// though it hasn't expired yet.
+func (s *Server) logout(w http.ResponseWriter, r *http.Request) {
+	jti, _ := r.Context().Value("jti").(string)
+	if jti == "" {
+		respondError(w, http.StatusBadRequest, "No token to revoke")
+		return
+	}
+
+	if err := s.auth.Revoke(jti); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to revoke token")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"revoked": true})
+}
+
+// jwks publishes the JSON Web Key Set for the configured asymmetric signing
+// key so downstream services can validate Octopus-issued tokens without
+// sharing the HMAC secret. Returns an empty key set when signing with HS256.
+func (s *Server) jwks(w http.ResponseWriter, r *http.Request) {
+	jwkSet, ok := s.auth.JWKS()
+	if !ok {
+		respondJSON(w, http.StatusOK, auth.JWKSet{Keys: []auth.JWK{}})
+		return
+	}
+	respondJSON(w, http.StatusOK, jwkSet)
+}
+
+// oidcLogin redirects the browser to the configured IdP's authorization
+// endpoint, stashing an anti-CSRF state value in a short-lived cookie.
+func (s *Server) oidcLogin(w http.ResponseWriter, r *http.Request) {
+	state, err := randomState()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to start OIDC login")
+		return
+	}
+
+	authURL, err := s.auth.OIDCLoginURL(state)
+	if err != nil {
+		respondError(w, http.StatusNotImplemented, "OIDC is not configured")
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/",
+		MaxAge:   300,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, authURL, http.StatusFound)
+}
+
+// oidcCallback completes the authorization-code flow started by oidcLogin.
+func (s *Server) oidcCallback(w http.ResponseWriter, r *http.Request) {
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || r.URL.Query().Get("state") != cookie.Value {
+		respondError(w, http.StatusBadRequest, "Invalid or missing OIDC state")
+		return
+	}
+	http.SetCookie(w, &http.Cookie{Name: oidcStateCookie, Value: "", Path: "/", MaxAge: -1})
+
+	code := r.URL.Query().Get("code")
+	if code == "" {
+		respondError(w, http.StatusBadRequest, "Missing authorization code")
+		return
+	}
+
+	user, accessToken, refreshToken, err := s.auth.OIDCCallback(r.Context(), code)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "OIDC authentication failed")
+		return
+	}
+
+	s.upsertUser(user)
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"user":          user,
+	})
+}
+
+// samlACS validates the SAMLResponse posted by the IdP and mints a session
+// for the resulting user.
+func (s *Server) samlACS(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid SAML response")
+		return
+	}
+
+	user, accessToken, refreshToken, err := s.auth.SAMLACS(r)
+	if err != nil {
+		respondError(w, http.StatusUnauthorized, "SAML authentication failed")
+		return
+	}
+
+	s.upsertUser(user)
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"user":          user,
+	})
+}
+
+// upsertUser records a successful login in the database, tolerating failures
+// the same way the password login handler always has.
+func (s *Server) upsertUser(user *auth.User) {
+	_, err := s.db.Exec(`
 		INSERT INTO users (username, display_name, last_login)
 		VALUES (?, ?, ?)
 		ON CONFLICT(username) DO UPDATE SET last_login = ?
@@ -40,11 +205,15 @@ func (s *Server) login(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		// Log error but don't fail login
 	}
+}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"token": token,
-		"user":  user,
-	})
+// randomState generates a URL-safe random value for OIDC anti-CSRF state.
+func randomState() (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
 // Source environment handlers
@@ -90,10 +259,20 @@ func (s *Server) createSourceEnvironment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	password := env.Password
+	if password != "" {
+		encrypted, err := s.secrets.Encrypt(password)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to encrypt password: "+err.Error())
+			return
+		}
+		password = encrypted
+	}
+
 	result, err := s.db.Exec(`
 		INSERT INTO source_environments (name, type, host, username, password, datacenter, cluster)
 		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`, env.Name, env.Type, env.Host, env.Username, env.Password, env.Datacenter, env.Cluster)
+	`, env.Name, env.Type, env.Host, env.Username, password, env.Datacenter, env.Cluster)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to create environment")
 		return
@@ -141,11 +320,16 @@ func (s *Server) updateSourceEnvironment(w http.ResponseWriter, r *http.Request)
 
 	// If password is empty, don't update it
 	if env.Password != "" {
-		_, err := s.db.Exec(`
+		encrypted, err := s.secrets.Encrypt(env.Password)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to encrypt password: "+err.Error())
+			return
+		}
+		_, err = s.db.Exec(`
 			UPDATE source_environments
 			SET name=?, type=?, host=?, username=?, password=?, datacenter=?, cluster=?, updated_at=?
 			WHERE id=?
-		`, env.Name, env.Type, env.Host, env.Username, env.Password, env.Datacenter, env.Cluster, time.Now(), id)
+		`, env.Name, env.Type, env.Host, env.Username, encrypted, env.Datacenter, env.Cluster, time.Now(), id)
 		if err != nil {
 			respondError(w, http.StatusInternalServerError, "Failed to update environment")
 			return
@@ -190,10 +374,13 @@ func (s *Server) deleteSourceEnvironment(w http.ResponseWriter, r *http.Request)
 	respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+// syncSourceEnvironment kicks off a VM discovery sync against a source
+// environment's vCenter and returns immediately with an operation the caller
+// can poll or subscribe to, since a large inventory can take a while to
+// enumerate.
 func (s *Server) syncSourceEnvironment(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
-	// Get environment details
 	var env db.SourceEnvironment
 	var password string
 	err := s.db.QueryRow(`
@@ -205,42 +392,58 @@ func (s *Server) syncSourceEnvironment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Connect to vCenter and fetch VMs
-	client, err := vmware.NewClient(env.Host, env.Username, password, env.Datacenter, true)
+	password, err = s.secrets.Decrypt(password)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to connect to vCenter: "+err.Error())
+		respondError(w, http.StatusInternalServerError, "Failed to decrypt password: "+err.Error())
 		return
 	}
-	defer client.Logout()
 
-	vms, err := client.ListVMs()
-	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to list VMs: "+err.Error())
-		return
-	}
+	op := s.operations.Create(operations.ClassTask, "source_environment", env.ID)
+	op.Run(func(ctx context.Context) error {
+		client, err := vmware.NewClient(env.Host, env.Username, password, env.Datacenter, true)
+		if err != nil {
+			return fmt.Errorf("failed to connect to vCenter: %w", err)
+		}
+		defer client.Logout(ctx)
 
-	// Update VMs in database
-	for _, vm := range vms {
-		_, err = s.db.Exec(`
-			INSERT INTO vms (source_env_id, name, uuid, cpu_count, memory_mb, disk_size_gb, guest_os,
-				power_state, ip_addresses, mac_addresses, port_groups, hardware_version, vmware_tools_status, last_synced)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-			ON CONFLICT(uuid) DO UPDATE SET
-				name=?, cpu_count=?, memory_mb=?, disk_size_gb=?, guest_os=?,
-				power_state=?, ip_addresses=?, mac_addresses=?, port_groups=?,
-				hardware_version=?, vmware_tools_status=?, last_synced=?
-		`, env.ID, vm.Name, vm.UUID, vm.CPUCount, vm.MemoryMB, vm.DiskSizeGB, vm.GuestOS,
-			vm.PowerState, vm.IPAddresses, vm.MACAddresses, vm.PortGroups, vm.HardwareVersion,
-			vm.VMwareToolsStatus, time.Now(),
-			vm.Name, vm.CPUCount, vm.MemoryMB, vm.DiskSizeGB, vm.GuestOS,
-			vm.PowerState, vm.IPAddresses, vm.MACAddresses, vm.PortGroups,
-			vm.HardwareVersion, vm.VMwareToolsStatus, time.Now())
-	}
+		vms, err := client.ListVMs(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list VMs: %w", err)
+		}
+		if len(vms) == 0 {
+			return nil
+		}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"status":   "synced",
-		"vm_count": len(vms),
+		for i, vm := range vms {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
+			_, err = s.db.Exec(`
+				INSERT INTO vms (source_env_id, name, uuid, cpu_count, memory_mb, disk_size_gb, guest_os,
+					power_state, ip_addresses, mac_addresses, port_groups, hardware_version, vmware_tools_status, last_synced)
+				VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+				ON CONFLICT(uuid) DO UPDATE SET
+					name=?, cpu_count=?, memory_mb=?, disk_size_gb=?, guest_os=?,
+					power_state=?, ip_addresses=?, mac_addresses=?, port_groups=?,
+					hardware_version=?, vmware_tools_status=?, last_synced=?
+			`, env.ID, vm.Name, vm.UUID, vm.CPUCount, vm.MemoryMB, vm.DiskSizeGB, vm.GuestOS,
+				vm.PowerState, vm.IPAddresses, vm.MACAddresses, vm.PortGroups, vm.HardwareVersion,
+				vm.VMwareToolsStatus, time.Now(),
+				vm.Name, vm.CPUCount, vm.MemoryMB, vm.DiskSizeGB, vm.GuestOS,
+				vm.PowerState, vm.IPAddresses, vm.MACAddresses, vm.PortGroups,
+				vm.HardwareVersion, vm.VMwareToolsStatus, time.Now())
+			if err != nil {
+				return fmt.Errorf("failed to upsert VM %s: %w", vm.Name, err)
+			}
+
+			op.SetProgress((i+1)*100/len(vms), map[string]interface{}{"vm_count": len(vms)})
+		}
+
+		return nil
 	})
+
+	respondOperation(w, op)
 }
 
 // Target environment handlers
@@ -263,6 +466,9 @@ func (s *Server) listTargetEnvironments(w http.ResponseWriter, r *http.Request)
 		if err != nil {
 			continue
 		}
+		if redacted, err := s.redactSecretFields(env.Type, []byte(env.ConfigJSON)); err == nil {
+			env.ConfigJSON = string(redacted)
+		}
 		envs = append(envs, env)
 	}
 
@@ -281,10 +487,16 @@ func (s *Server) createTargetEnvironment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	encrypted, err := s.encryptSecretFields(env.Type, env.ConfigJSON)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to encrypt target config: "+err.Error())
+		return
+	}
+
 	result, err := s.db.Exec(`
 		INSERT INTO target_environments (name, type, config_json)
 		VALUES (?, ?, ?)
-	`, env.Name, env.Type, string(env.ConfigJSON))
+	`, env.Name, env.Type, string(encrypted))
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to create target environment")
 		return
@@ -309,6 +521,9 @@ func (s *Server) getTargetEnvironment(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Database error")
 		return
 	}
+	if redacted, err := s.redactSecretFields(env.Type, []byte(env.ConfigJSON)); err == nil {
+		env.ConfigJSON = string(redacted)
+	}
 	respondJSON(w, http.StatusOK, env)
 }
 
@@ -325,10 +540,16 @@ func (s *Server) updateTargetEnvironment(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	_, err := s.db.Exec(`
+	encrypted, err := s.encryptSecretFields(env.Type, env.ConfigJSON)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to encrypt target config: "+err.Error())
+		return
+	}
+
+	_, err = s.db.Exec(`
 		UPDATE target_environments SET name=?, type=?, config_json=?, updated_at=?
 		WHERE id=?
-	`, env.Name, env.Type, string(env.ConfigJSON), time.Now(), id)
+	`, env.Name, env.Type, string(encrypted), time.Now(), id)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to update target environment")
 		return
@@ -355,22 +576,29 @@ func (s *Server) deleteTargetEnvironment(w http.ResponseWriter, r *http.Request)
 // VM handlers
 func (s *Server) listVMs(w http.ResponseWriter, r *http.Request) {
 	sourceID := r.URL.Query().Get("source_id")
+	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
+
 	query := `
 		SELECT id, source_env_id, name, uuid, cpu_count, memory_mb, disk_size_gb, guest_os,
-			power_state, ip_addresses, mac_addresses, port_groups, hardware_version, vmware_tools_status, last_synced
+			power_state, ip_addresses, mac_addresses, port_groups, hardware_version, vmware_tools_status,
+			last_synced, deleted_at
 		FROM vms
 	`
-	var rows *sql.Rows
-	var err error
-
+	var conditions []string
+	var args []interface{}
 	if sourceID != "" {
-		query += " WHERE source_env_id = ? ORDER BY name"
-		rows, err = s.db.Query(query, sourceID)
-	} else {
-		query += " ORDER BY name"
-		rows, err = s.db.Query(query)
+		conditions = append(conditions, "source_env_id = ?")
+		args = append(args, sourceID)
+	}
+	if !includeDeleted {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
+	query += " ORDER BY name"
 
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Database error")
 		return
@@ -382,7 +610,7 @@ func (s *Server) listVMs(w http.ResponseWriter, r *http.Request) {
 		var vm db.VM
 		err := rows.Scan(&vm.ID, &vm.SourceEnvID, &vm.Name, &vm.UUID, &vm.CPUCount, &vm.MemoryMB,
 			&vm.DiskSizeGB, &vm.GuestOS, &vm.PowerState, &vm.IPAddresses, &vm.MACAddresses,
-			&vm.PortGroups, &vm.HardwareVersion, &vm.VMwareToolsStatus, &vm.LastSynced)
+			&vm.PortGroups, &vm.HardwareVersion, &vm.VMwareToolsStatus, &vm.LastSynced, &vm.DeletedAt)
 		if err != nil {
 			continue
 		}
@@ -394,14 +622,17 @@ func (s *Server) listVMs(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) getVM(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
+	includeDeleted := r.URL.Query().Get("include_deleted") == "true"
+
 	var vm db.VM
 	err := s.db.QueryRow(`
 		SELECT id, source_env_id, name, uuid, cpu_count, memory_mb, disk_size_gb, guest_os,
-			power_state, ip_addresses, mac_addresses, port_groups, hardware_version, vmware_tools_status, last_synced
+			power_state, ip_addresses, mac_addresses, port_groups, hardware_version, vmware_tools_status,
+			last_synced, deleted_at
 		FROM vms WHERE id = ?
 	`, id).Scan(&vm.ID, &vm.SourceEnvID, &vm.Name, &vm.UUID, &vm.CPUCount, &vm.MemoryMB,
 		&vm.DiskSizeGB, &vm.GuestOS, &vm.PowerState, &vm.IPAddresses, &vm.MACAddresses,
-		&vm.PortGroups, &vm.HardwareVersion, &vm.VMwareToolsStatus, &vm.LastSynced)
+		&vm.PortGroups, &vm.HardwareVersion, &vm.VMwareToolsStatus, &vm.LastSynced, &vm.DeletedAt)
 	if err == sql.ErrNoRows {
 		respondError(w, http.StatusNotFound, "VM not found")
 		return
@@ -410,6 +641,10 @@ func (s *Server) getVM(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Database error")
 		return
 	}
+	if vm.DeletedAt != nil && !includeDeleted {
+		respondError(w, http.StatusNotFound, "VM not found")
+		return
+	}
 	respondJSON(w, http.StatusOK, vm)
 }
 
@@ -576,6 +811,29 @@ func (s *Server) getMigration(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, m)
 }
 
+// getSyncProgress returns the migration job's most recent live sync
+// progress sample (bytes transferred, EWMA-smoothed throughput, and ETA).
+// It 404s until the job's first sync has reported at least one sample.
+func (s *Server) getSyncProgress(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	jobID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid migration job ID")
+		return
+	}
+
+	progress, err := s.scheduler.GetSyncProgress(jobID)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "No sync progress recorded for this migration job")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondJSON(w, http.StatusOK, progress)
+}
+
 func (s *Server) updateMigration(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	var req struct {
@@ -615,6 +873,11 @@ func (s *Server) cancelMigration(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Failed to cancel migration")
 		return
 	}
+
+	idInt, _ := strconv.ParseInt(id, 10, 64)
+	s.webhooks.Dispatch(webhooks.EventCancelled, "migration_job", idInt, "cancelled", "")
+	s.scheduler.Stream().Publish(idInt, migrationstream.EventModified, "cancelled", 0, "migration cancelled")
+
 	respondJSON(w, http.StatusOK, map[string]string{"status": "cancelled"})
 }
 
@@ -622,40 +885,75 @@ func (s *Server) triggerSync(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	idInt, _ := strconv.ParseInt(id, 10, 64)
 
-	// Update status to syncing
-	_, err := s.db.Exec(`UPDATE migration_jobs SET status='syncing' WHERE id=?`, id)
+	lse, leaseCtx, err := s.scheduler.Leases().Acquire(context.Background(), idInt, migrationLeaseTTL)
+	if errors.Is(err, lease.ErrLeaseHeld) {
+		respondError(w, http.StatusConflict, "Migration job is already syncing on another worker")
+		return
+	}
 	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to acquire sync lease")
+		return
+	}
+
+	// Update status to syncing
+	if _, err := s.db.Exec(`UPDATE migration_jobs SET status='syncing' WHERE id=?`, id); err != nil {
+		s.scheduler.Leases().Release(lse)
 		respondError(w, http.StatusInternalServerError, "Failed to trigger sync")
 		return
 	}
 
-	// Trigger async sync
-	go s.scheduler.TriggerSync(idInt)
+	op := s.operations.Create(operations.ClassTask, "migration_job", idInt)
+	go func() {
+		<-leaseCtx.Done()
+		op.Cancel()
+	}()
+	op.Run(func(ctx context.Context) error {
+		defer s.scheduler.Leases().Release(lse)
+		return s.scheduler.TriggerSync(ctx, idInt)
+	})
 
-	respondJSON(w, http.StatusOK, map[string]string{"status": "sync_started"})
+	respondOperation(w, op)
 }
 
 func (s *Server) triggerCutover(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	idInt, _ := strconv.ParseInt(id, 10, 64)
 
-	// Update status to cutting over
-	_, err := s.db.Exec(`UPDATE migration_jobs SET status='cutting_over' WHERE id=?`, id)
+	lse, leaseCtx, err := s.scheduler.Leases().Acquire(context.Background(), idInt, migrationLeaseTTL)
+	if errors.Is(err, lease.ErrLeaseHeld) {
+		respondError(w, http.StatusConflict, "Migration job is already cutting over on another worker")
+		return
+	}
 	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to acquire cutover lease")
+		return
+	}
+
+	// Update status to cutting over
+	if _, err := s.db.Exec(`UPDATE migration_jobs SET status='cutting_over' WHERE id=?`, id); err != nil {
+		s.scheduler.Leases().Release(lse)
 		respondError(w, http.StatusInternalServerError, "Failed to trigger cutover")
 		return
 	}
 
-	// Trigger async cutover
-	go s.scheduler.TriggerCutover(idInt)
+	op := s.operations.Create(operations.ClassTask, "migration_job", idInt)
+	go func() {
+		<-leaseCtx.Done()
+		op.Cancel()
+	}()
+	op.Run(func(ctx context.Context) error {
+		defer s.scheduler.Leases().Release(lse)
+		return s.scheduler.TriggerCutover(ctx, idInt)
+	})
 
-	respondJSON(w, http.StatusOK, map[string]string{"status": "cutover_started"})
+	respondOperation(w, op)
 }
 
 // Scheduled task handlers
 func (s *Server) listScheduledTasks(w http.ResponseWriter, r *http.Request) {
 	rows, err := s.db.Query(`
-		SELECT id, job_id, task_type, scheduled_time, status, result, created_by, created_at, executed_at
+		SELECT id, job_id, task_type, scheduled_time, status, result, created_by, created_at, executed_at,
+			cron_expression, next_run, timezone, maintenance_window
 		FROM scheduled_tasks
 		ORDER BY scheduled_time DESC
 	`)
@@ -668,11 +966,16 @@ func (s *Server) listScheduledTasks(w http.ResponseWriter, r *http.Request) {
 	var tasks []db.ScheduledTask
 	for rows.Next() {
 		var t db.ScheduledTask
+		var cronExpression, timezone, maintenanceWindow sql.NullString
 		err := rows.Scan(&t.ID, &t.JobID, &t.TaskType, &t.ScheduledTime, &t.Status,
-			&t.Result, &t.CreatedBy, &t.CreatedAt, &t.ExecutedAt)
+			&t.Result, &t.CreatedBy, &t.CreatedAt, &t.ExecutedAt,
+			&cronExpression, &t.NextRun, &timezone, &maintenanceWindow)
 		if err != nil {
 			continue
 		}
+		t.CronExpression = cronExpression.String
+		t.Timezone = timezone.String
+		t.MaintenanceWindow = maintenanceWindow.String
 		tasks = append(tasks, t)
 	}
 
@@ -681,9 +984,12 @@ func (s *Server) listScheduledTasks(w http.ResponseWriter, r *http.Request) {
 
 func (s *Server) createScheduledTask(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		JobID         int64  `json:"job_id"`
-		TaskType      string `json:"task_type"`
-		ScheduledTime string `json:"scheduled_time"`
+		JobID             int64  `json:"job_id"`
+		TaskType          string `json:"task_type"`
+		ScheduledTime     string `json:"scheduled_time"`
+		CronExpression    string `json:"cron_expression"`
+		Timezone          string `json:"timezone"`
+		MaintenanceWindow string `json:"maintenance_window"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -691,14 +997,42 @@ func (s *Server) createScheduledTask(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	username := r.Context().Value("username").(string)
+
+	// Recurring task: scheduled_time/next_run are derived from the cron
+	// expression's first fire time rather than supplied directly.
+	if req.CronExpression != "" {
+		fireTimes, err := s.scheduler.PreviewFireTimes(req.CronExpression, req.Timezone, 1)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if len(fireTimes) == 0 {
+			respondError(w, http.StatusBadRequest, "cron_expression has no upcoming fire time")
+			return
+		}
+		next := fireTimes[0]
+
+		result, err := s.db.Exec(`
+			INSERT INTO scheduled_tasks (job_id, task_type, scheduled_time, created_by, cron_expression, next_run, timezone, maintenance_window)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		`, req.JobID, req.TaskType, next, username, req.CronExpression, next, req.Timezone, req.MaintenanceWindow)
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to create scheduled task")
+			return
+		}
+
+		id, _ := result.LastInsertId()
+		respondJSON(w, http.StatusCreated, map[string]int64{"id": id})
+		return
+	}
+
 	scheduledTime, err := time.Parse(time.RFC3339, req.ScheduledTime)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, "Invalid scheduled time format")
 		return
 	}
 
-	username := r.Context().Value("username").(string)
-
 	result, err := s.db.Exec(`
 		INSERT INTO scheduled_tasks (job_id, task_type, scheduled_time, created_by)
 		VALUES (?, ?, ?, ?)
@@ -712,14 +1046,60 @@ func (s *Server) createScheduledTask(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusCreated, map[string]int64{"id": id})
 }
 
+// previewScheduledTask previews the next N fire times a scheduled task's
+// cron_expression will produce, without waiting for the scheduler's ticker
+// to advance next_run, so an operator can sanity-check a recurring job
+// before (or after) scheduling a cutover/failover/sync against it. N
+// defaults to 5 and is capped at 50; set via the ?count= query param.
+func (s *Server) previewScheduledTask(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var cronExpression, timezone sql.NullString
+	err := s.db.QueryRow(`SELECT cron_expression, timezone FROM scheduled_tasks WHERE id = ?`, id).
+		Scan(&cronExpression, &timezone)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "Scheduled task not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	if !cronExpression.Valid || cronExpression.String == "" {
+		respondError(w, http.StatusBadRequest, "Scheduled task has no cron_expression to preview")
+		return
+	}
+
+	count := 5
+	if raw := r.URL.Query().Get("count"); raw != "" {
+		if n, err := strconv.Atoi(raw); err == nil && n > 0 {
+			count = n
+		}
+	}
+	if count > 50 {
+		count = 50
+	}
+
+	fireTimes, err := s.scheduler.PreviewFireTimes(cronExpression.String, timezone.String, count)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"fire_times": fireTimes})
+}
+
 func (s *Server) getScheduledTask(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 	var t db.ScheduledTask
+	var cronExpression, timezone, maintenanceWindow sql.NullString
 	err := s.db.QueryRow(`
-		SELECT id, job_id, task_type, scheduled_time, status, result, created_by, created_at, executed_at
+		SELECT id, job_id, task_type, scheduled_time, status, result, created_by, created_at, executed_at,
+			cron_expression, next_run, timezone, maintenance_window
 		FROM scheduled_tasks WHERE id = ?
 	`, id).Scan(&t.ID, &t.JobID, &t.TaskType, &t.ScheduledTime, &t.Status,
-		&t.Result, &t.CreatedBy, &t.CreatedAt, &t.ExecutedAt)
+		&t.Result, &t.CreatedBy, &t.CreatedAt, &t.ExecutedAt,
+		&cronExpression, &t.NextRun, &timezone, &maintenanceWindow)
 	if err == sql.ErrNoRows {
 		respondError(w, http.StatusNotFound, "Scheduled task not found")
 		return
@@ -728,6 +1108,9 @@ func (s *Server) getScheduledTask(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Database error")
 		return
 	}
+	t.CronExpression = cronExpression.String
+	t.Timezone = timezone.String
+	t.MaintenanceWindow = maintenanceWindow.String
 	respondJSON(w, http.StatusOK, t)
 }
 
@@ -741,6 +1124,63 @@ func (s *Server) cancelScheduledTask(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "cancelled"})
 }
 
+// retryScheduledTask resets a failed, dead_letter, or cancelled task back to
+// pending so the scheduler picks it up on its next poll.
+func (s *Server) retryScheduledTask(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	taskID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid task id")
+		return
+	}
+
+	if err := s.scheduler.RetryTask(taskID); err != nil {
+		respondError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	username := r.Context().Value("username").(string)
+	s.logActivity(username, "retry_scheduled_task", "scheduled_task", taskID, "", r.RemoteAddr, requestIDFromContext(r.Context()))
+
+	respondJSON(w, http.StatusOK, map[string]string{"status": "pending"})
+}
+
+// scheduledTaskEvents returns this task's activity_logs timeline - claims,
+// heartbeat losses, retries, and its final settle - newest first, for the
+// admin UI to render a history view.
+func (s *Server) scheduledTaskEvents(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	rows, err := s.db.Query(`
+		SELECT id, action, details, created_at
+		FROM activity_logs
+		WHERE entity_type = 'scheduled_task' AND entity_id = ?
+		ORDER BY created_at DESC
+	`, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	defer rows.Close()
+
+	type taskEvent struct {
+		ID        int64     `json:"id"`
+		Action    string    `json:"action"`
+		Details   string    `json:"details"`
+		CreatedAt time.Time `json:"created_at"`
+	}
+	events := []taskEvent{}
+	for rows.Next() {
+		var e taskEvent
+		if err := rows.Scan(&e.ID, &e.Action, &e.Details, &e.CreatedAt); err != nil {
+			continue
+		}
+		events = append(events, e)
+	}
+
+	respondJSON(w, http.StatusOK, events)
+}
+
 // Unified Environment handlers
 func (s *Server) listEnvironments(w http.ResponseWriter, r *http.Request) {
 	rows, err := s.db.Query(`
@@ -761,12 +1201,79 @@ func (s *Server) listEnvironments(w http.ResponseWriter, r *http.Request) {
 		if err != nil {
 			continue
 		}
+		if redacted, err := s.redactSecretFields(env.Type, []byte(env.ConfigJSON)); err == nil {
+			env.ConfigJSON = string(redacted)
+		}
 		envs = append(envs, env)
 	}
 
 	respondJSON(w, http.StatusOK, envs)
 }
 
+// validateEnvironmentConfig checks typ against the registered driver for
+// that environment type and validates configJSON against its Schema,
+// rejecting a bad config before it's ever persisted or reaches sync.
+func (s *Server) validateEnvironmentConfig(typ string, configJSON []byte) error {
+	var config map[string]interface{}
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return fmt.Errorf("invalid config: %w", err)
+	}
+	return s.syncers.Validate(typ, config)
+}
+
+// transformSecretFields decodes configJSON, applies transform to every
+// field typ's driver marks as Secret, and re-encodes it. Used to encrypt
+// on the way into the database, decrypt on the way to a sync driver, and
+// redact on the way out to API clients.
+func (s *Server) transformSecretFields(typ string, configJSON []byte, transform func(string) (string, error)) ([]byte, error) {
+	fields := s.syncers.SecretFields(typ)
+	if len(fields) == 0 {
+		return configJSON, nil
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(configJSON, &config); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	for _, field := range fields {
+		v, ok := config[field].(string)
+		if !ok || v == "" {
+			continue
+		}
+		transformed, err := transform(v)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: %w", field, err)
+		}
+		config[field] = transformed
+	}
+
+	return json.Marshal(config)
+}
+
+// encryptSecretFields encrypts typ's secret config fields before they're
+// persisted.
+func (s *Server) encryptSecretFields(typ string, configJSON []byte) ([]byte, error) {
+	return s.transformSecretFields(typ, configJSON, s.secrets.Encrypt)
+}
+
+// decryptSecretFields decrypts typ's secret config fields for internal use
+// (e.g. handing credentials to a syncer.Driver). Never expose its output to
+// an API response directly - use redactSecretFields for that.
+func (s *Server) decryptSecretFields(typ string, configJSON []byte) ([]byte, error) {
+	return s.transformSecretFields(typ, configJSON, s.secrets.Decrypt)
+}
+
+// redactedSecretValue replaces a secret field's value in API responses.
+const redactedSecretValue = "[redacted]"
+
+// redactSecretFields masks typ's secret config fields for API responses.
+func (s *Server) redactSecretFields(typ string, configJSON []byte) ([]byte, error) {
+	return s.transformSecretFields(typ, configJSON, func(string) (string, error) {
+		return redactedSecretValue, nil
+	})
+}
+
 func (s *Server) createEnvironment(w http.ResponseWriter, r *http.Request) {
 	var env struct {
 		Name       string          `json:"name"`
@@ -779,10 +1286,21 @@ func (s *Server) createEnvironment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if err := s.validateEnvironmentConfig(env.Type, env.ConfigJSON); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	encrypted, err := s.encryptSecretFields(env.Type, env.ConfigJSON)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to encrypt environment config: "+err.Error())
+		return
+	}
+
 	result, err := s.db.Exec(`
 		INSERT INTO environments (name, type, config_json)
 		VALUES (?, ?, ?)
-	`, env.Name, env.Type, string(env.ConfigJSON))
+	`, env.Name, env.Type, string(encrypted))
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to create environment: "+err.Error())
 		return
@@ -807,6 +1325,32 @@ func (s *Server) getEnvironment(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusInternalServerError, "Database error")
 		return
 	}
+
+	reveal := r.URL.Query().Get("reveal") == "true"
+	isAdmin, _ := r.Context().Value("isAdmin").(bool)
+	if reveal && isAdmin {
+		decrypted, err := s.decryptSecretFields(env.Type, []byte(env.ConfigJSON))
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to decrypt environment config: "+err.Error())
+			return
+		}
+		env.ConfigJSON = string(decrypted)
+
+		username, _ := r.Context().Value("username").(string)
+		s.logActivity(username, "reveal_environment_secrets", "environment", env.ID, env.Name, r.RemoteAddr, requestIDFromContext(r.Context()))
+	} else {
+		if reveal {
+			respondError(w, http.StatusForbidden, "Admin access required to reveal secrets")
+			return
+		}
+		redacted, err := s.redactSecretFields(env.Type, []byte(env.ConfigJSON))
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, "Failed to redact environment config: "+err.Error())
+			return
+		}
+		env.ConfigJSON = string(redacted)
+	}
+
 	respondJSON(w, http.StatusOK, env)
 }
 
@@ -823,10 +1367,21 @@ func (s *Server) updateEnvironment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err := s.db.Exec(`
+	if err := s.validateEnvironmentConfig(env.Type, env.ConfigJSON); err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	encrypted, err := s.encryptSecretFields(env.Type, env.ConfigJSON)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to encrypt environment config: "+err.Error())
+		return
+	}
+
+	_, err = s.db.Exec(`
 		UPDATE environments SET name=?, type=?, config_json=?, updated_at=?
 		WHERE id=?
-	`, env.Name, env.Type, string(env.ConfigJSON), time.Now(), id)
+	`, env.Name, env.Type, string(encrypted), time.Now(), id)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, "Failed to update environment")
 		return
@@ -845,6 +1400,12 @@ func (s *Server) deleteEnvironment(w http.ResponseWriter, r *http.Request) {
 	respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
 }
 
+// syncEnvironment kicks off an inventory pull for a unified environment,
+// through whichever syncer.Driver is registered for its type, as a
+// background SyncJob and returns immediately, since iterating every VM
+// inline can hold the HTTP request open long enough to time out proxies
+// and clients. Callers can poll GET /sync_jobs/{id} or stream progress from
+// GET /sync_jobs/{id}/events.
 func (s *Server) syncEnvironment(w http.ResponseWriter, r *http.Request) {
 	id := mux.Vars(r)["id"]
 
@@ -858,58 +1419,151 @@ func (s *Server) syncEnvironment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Only VMware environments can be synced
-	if env.Type != "vmware" && env.Type != "vmware-vxrail" {
-		respondError(w, http.StatusBadRequest, "Only VMware environments can be synced")
+	driver, ok := s.syncers.Get(env.Type)
+	if !ok {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("No sync driver registered for environment type %q", env.Type))
 		return
 	}
 
-	// Parse config
-	var config struct {
-		Host       string `json:"host"`
-		Username   string `json:"username"`
-		Password   string `json:"password"`
-		Datacenter string `json:"datacenter"`
+	decryptedJSON, err := s.decryptSecretFields(env.Type, []byte(env.ConfigJSON))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to decrypt environment config: "+err.Error())
+		return
 	}
-	if err := json.Unmarshal([]byte(env.ConfigJSON), &config); err != nil {
+
+	var config map[string]interface{}
+	if err := json.Unmarshal(decryptedJSON, &config); err != nil {
 		respondError(w, http.StatusInternalServerError, "Invalid environment config")
 		return
 	}
 
-	// Connect to vCenter and fetch VMs
-	client, err := vmware.NewClient(config.Host, config.Username, config.Password, config.Datacenter, true)
+	job, err := s.syncJobs.Create(env.ID)
+	if errors.Is(err, syncjob.ErrSyncInProgress) {
+		respondError(w, http.StatusConflict, "A sync is already in progress for this environment")
+		return
+	}
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to connect to vCenter: "+err.Error())
+		respondError(w, http.StatusInternalServerError, "Failed to create sync job")
 		return
 	}
-	defer client.Logout()
 
-	vms, err := client.ListVMs()
+	job.Run(func(ctx context.Context) error {
+		session, err := driver.Connect(ctx, config)
+		if err != nil {
+			return fmt.Errorf("failed to connect to %s: %w", env.Type, err)
+		}
+		defer session.Close()
+
+		vms, err := driver.ListVMs(ctx, session)
+		if err != nil {
+			return fmt.Errorf("failed to list VMs: %w", err)
+		}
+		job.SetTotal(len(vms))
+
+		syncStart := time.Now()
+		if err := s.upsertSyncedVMs(ctx, env.ID, vms, syncStart, job); err != nil {
+			return err
+		}
+
+		deleted, err := s.reconcileDeletedVMs(env.ID, syncStart)
+		if err != nil {
+			return fmt.Errorf("failed to reconcile deleted VMs: %w", err)
+		}
+		job.SetDeleted(deleted)
+
+		return nil
+	})
+
+	w.Header().Set("Location", fmt.Sprintf("/api/v1/sync_jobs/%d", job.Snapshot().ID))
+	respondJSON(w, http.StatusAccepted, job.Snapshot())
+}
+
+// upsertSyncedVMs persists vms for envID inside a single transaction,
+// reusing one prepared statement rather than issuing a fresh round-trip per
+// VM, and reports each row as created or updated to job as it goes.
+func (s *Server) upsertSyncedVMs(ctx context.Context, envID int64, vms []syncer.VM, syncedAt time.Time, job *syncjob.SyncJob) error {
+	if len(vms) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		respondError(w, http.StatusInternalServerError, "Failed to list VMs: "+err.Error())
-		return
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	existing := make(map[string]bool)
+	rows, err := tx.QueryContext(ctx, `SELECT uuid FROM vms WHERE source_env_id = ? AND uuid IS NOT NULL`, envID)
+	if err != nil {
+		return fmt.Errorf("load existing VMs: %w", err)
 	}
+	for rows.Next() {
+		var uuid string
+		if err := rows.Scan(&uuid); err != nil {
+			rows.Close()
+			return fmt.Errorf("load existing VMs: %w", err)
+		}
+		existing[uuid] = true
+	}
+	rows.Close()
+
+	stmt, err := tx.PrepareContext(ctx, `
+		INSERT INTO vms (source_env_id, name, uuid, cpu_count, memory_mb, disk_size_gb, guest_os,
+			power_state, ip_addresses, mac_addresses, port_groups, hardware_version, vmware_tools_status,
+			last_synced, deleted_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, NULL)
+		ON CONFLICT(uuid) DO UPDATE SET
+			name=?, cpu_count=?, memory_mb=?, disk_size_gb=?, guest_os=?,
+			power_state=?, ip_addresses=?, mac_addresses=?, port_groups=?,
+			hardware_version=?, vmware_tools_status=?, last_synced=?, deleted_at=NULL
+	`)
+	if err != nil {
+		return fmt.Errorf("prepare upsert: %w", err)
+	}
+	defer stmt.Close()
 
-	// Update VMs in database - use environment ID as source_env_id
 	for _, vm := range vms {
-		_, err = s.db.Exec(`
-			INSERT INTO vms (source_env_id, name, uuid, cpu_count, memory_mb, disk_size_gb, guest_os,
-				power_state, ip_addresses, mac_addresses, port_groups, hardware_version, vmware_tools_status, last_synced)
-			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
-			ON CONFLICT(uuid) DO UPDATE SET
-				name=?, cpu_count=?, memory_mb=?, disk_size_gb=?, guest_os=?,
-				power_state=?, ip_addresses=?, mac_addresses=?, port_groups=?,
-				hardware_version=?, vmware_tools_status=?, last_synced=?
-		`, env.ID, vm.Name, vm.UUID, vm.CPUCount, vm.MemoryMB, vm.DiskSizeGB, vm.GuestOS,
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		_, err = stmt.ExecContext(ctx,
+			envID, vm.Name, vm.UUID, vm.CPUCount, vm.MemoryMB, vm.DiskSizeGB, vm.GuestOS,
 			vm.PowerState, vm.IPAddresses, vm.MACAddresses, vm.PortGroups, vm.HardwareVersion,
-			vm.VMwareToolsStatus, time.Now(),
+			vm.VMwareToolsStatus, syncedAt,
 			vm.Name, vm.CPUCount, vm.MemoryMB, vm.DiskSizeGB, vm.GuestOS,
 			vm.PowerState, vm.IPAddresses, vm.MACAddresses, vm.PortGroups,
-			vm.HardwareVersion, vm.VMwareToolsStatus, time.Now())
+			vm.HardwareVersion, vm.VMwareToolsStatus, syncedAt)
+		if err != nil {
+			return fmt.Errorf("failed to upsert VM %s: %w", vm.Name, err)
+		}
+
+		if existing[vm.UUID] {
+			job.RecordUpdated()
+		} else {
+			job.RecordCreated()
+		}
+		job.Increment()
 	}
 
-	respondJSON(w, http.StatusOK, map[string]interface{}{
-		"status":   "synced",
-		"vm_count": len(vms),
-	})
+	return tx.Commit()
+}
+
+// reconcileDeletedVMs soft-deletes every VM belonging to envID that wasn't
+// touched by the sync pass starting at syncedAt - it fell out of last_synced
+// range because the provider no longer reports it - and returns how many
+// rows were marked.
+func (s *Server) reconcileDeletedVMs(envID int64, syncedAt time.Time) (int, error) {
+	result, err := s.db.Exec(`
+		UPDATE vms SET deleted_at = ?
+		WHERE source_env_id = ? AND deleted_at IS NULL AND (last_synced IS NULL OR last_synced < ?)
+	`, time.Now(), envID, syncedAt)
+	if err != nil {
+		return 0, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(affected), nil
 }
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/sp00nznet/octopus/internal/migrationstream"
+)
+
+var migrationStreamUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// streamKeepaliveInterval is how often streamMigration and migrationWebSocket
+// send an idle keepalive, so a reverse proxy or load balancer sitting in
+// front of a long-lived connection doesn't time it out between progress
+// events.
+const streamKeepaliveInterval = 15 * time.Second
+
+// streamMigration streams migration progress as Server-Sent Events, modeled
+// on the Kubernetes watch pattern: ADDED when the job starts a phase,
+// MODIFIED for each progress update, ERROR on failure. The event's Status
+// field carries the specific lifecycle transition (e.g. "syncing",
+// "cutting_over", "failed") rather than a separate typed-event name, so
+// there's one vocabulary for both this stream and GET /migrations/{id}. A
+// reconnecting client can resume from where it left off via the
+// Last-Event-ID header, which is replayed from the hub's ring buffer; if
+// the hub has nothing buffered for this job at all (most likely the
+// server restarted since the client last connected), history is
+// reconstructed from sync_history instead so the client's timeline isn't
+// just empty. It's registered as both /migrations/{id}/stream and
+// /migrations/{id}/events.
+func (s *Server) streamMigration(w http.ResponseWriter, r *http.Request) {
+	jobID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid migration id")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondError(w, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	var lastEventID uint64
+	if raw := r.Header.Get("Last-Event-ID"); raw != "" {
+		lastEventID, _ = strconv.ParseUint(raw, 10, 64)
+	}
+
+	hub := s.scheduler.Stream()
+	updates, replay, unsubscribe := hub.Subscribe(jobID, lastEventID)
+	defer unsubscribe()
+	if lastEventID > 0 && hub.OldestBufferedID(jobID) == 0 {
+		replay = append(s.replayFromSyncHistory(jobID), replay...)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	for _, event := range replay {
+		if !writeSSEEvent(w, event) {
+			return
+		}
+	}
+	flusher.Flush()
+
+	keepalive := time.NewTicker(streamKeepaliveInterval)
+	defer keepalive.Stop()
+
+	ctx := r.Context()
+	for {
+		select {
+		case event, ok := <-updates:
+			if !ok {
+				return
+			}
+			if !writeSSEEvent(w, event) {
+				return
+			}
+			flusher.Flush()
+		case <-keepalive.C:
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func writeSSEEvent(w http.ResponseWriter, event migrationstream.Event) bool {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return false
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err == nil
+}
+
+// replayFromSyncHistory reconstructs a best-effort event timeline for jobID
+// from sync_history, for use when the in-memory hub has nothing buffered to
+// replay. It isn't a precise continuation of the hub's own ID sequence -
+// sync_history rows don't share one - just enough that a client reconnecting
+// after a server restart repaints something instead of starting blank.
+func (s *Server) replayFromSyncHistory(jobID int64) []migrationstream.Event {
+	rows, err := s.db.Query(`
+		SELECT status, bytes_transferred, replication_lag_seconds, error_message, created_at
+		FROM sync_history WHERE job_id = ? ORDER BY created_at ASC
+	`, jobID)
+	if err != nil {
+		return nil
+	}
+	defer rows.Close()
+
+	var events []migrationstream.Event
+	var id uint64
+	for rows.Next() {
+		var status sql.NullString
+		var errorMsg sql.NullString
+		var bytesTransferred, lagSeconds sql.NullInt64
+		var createdAt time.Time
+		if err := rows.Scan(&status, &bytesTransferred, &lagSeconds, &errorMsg, &createdAt); err != nil {
+			continue
+		}
+
+		id++
+		eventType := migrationstream.EventModified
+		progress := 0
+		message := status.String
+		switch status.String {
+		case "started":
+			eventType = migrationstream.EventAdded
+		case "failed":
+			eventType = migrationstream.EventError
+			message = errorMsg.String
+		case "completed":
+			progress = 100
+			if lagSeconds.Valid {
+				message = fmt.Sprintf("replication lag %ds", lagSeconds.Int64)
+			} else {
+				message = fmt.Sprintf("%d bytes transferred", bytesTransferred.Int64)
+			}
+		}
+
+		events = append(events, migrationstream.Event{
+			ID:        id,
+			Type:      eventType,
+			JobID:     jobID,
+			Status:    status.String,
+			Progress:  progress,
+			Message:   message,
+			Timestamp: createdAt,
+		})
+	}
+	return events
+}
+
+// migrationWebSocket is the WebSocket alternative to streamMigration for
+// clients that prefer a persistent bidirectional connection over SSE. It
+// replays the same way streamMigration does, and sends a ping control frame
+// on the same keepalive interval so idle connections survive intermediaries
+// that close quiet sockets.
+func (s *Server) migrationWebSocket(w http.ResponseWriter, r *http.Request) {
+	jobID, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid migration id")
+		return
+	}
+
+	var lastEventID uint64
+	if raw := r.URL.Query().Get("last_event_id"); raw != "" {
+		lastEventID, _ = strconv.ParseUint(raw, 10, 64)
+	}
+
+	conn, err := migrationStreamUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	hub := s.scheduler.Stream()
+	updates, replay, unsubscribe := hub.Subscribe(jobID, lastEventID)
+	defer unsubscribe()
+	if lastEventID > 0 && hub.OldestBufferedID(jobID) == 0 {
+		replay = append(s.replayFromSyncHistory(jobID), replay...)
+	}
+
+	for _, event := range replay {
+		if err := conn.WriteJSON(event); err != nil {
+			return
+		}
+	}
+
+	keepalive := time.NewTicker(streamKeepaliveInterval)
+	defer keepalive.Stop()
+
+	for {
+		select {
+		case event, ok := <-updates:
+			if !ok {
+				return
+			}
+			if err := conn.WriteJSON(event); err != nil {
+				return
+			}
+		case <-keepalive.C:
+			if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/sp00nznet/octopus/internal/operations"
+)
+
+// operationsUpgrader upgrades /operations/{id}/events requests to a
+// WebSocket. Origin checking is left to the reverse proxy in front of this
+// service, matching corsMiddleware's blanket allow for the REST API.
+var operationsUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	CheckOrigin:     func(r *http.Request) bool { return true },
+}
+
+// defaultOperationWaitTimeout bounds GET /operations/{id}/wait when the
+// caller doesn't specify ?timeout=.
+const defaultOperationWaitTimeout = 30 * time.Second
+
+// respondOperation replies 202 Accepted with a Location header and body
+// pointing at the newly created operation, for handlers that used to fire a
+// goroutine and return a bare status string.
+func respondOperation(w http.ResponseWriter, op *operations.Operation) {
+	location := "/api/v1/operations/" + op.ID
+	w.Header().Set("Location", location)
+	respondJSON(w, http.StatusAccepted, map[string]interface{}{
+		"operation": location,
+		"id":        op.ID,
+	})
+}
+
+func (s *Server) listOperations(w http.ResponseWriter, r *http.Request) {
+	ops := s.operations.List()
+	snapshots := make([]operations.Snapshot, 0, len(ops))
+	for _, op := range ops {
+		snapshots = append(snapshots, op.Snapshot())
+	}
+	respondJSON(w, http.StatusOK, snapshots)
+}
+
+func (s *Server) getOperation(w http.ResponseWriter, r *http.Request) {
+	op, ok := s.operations.Get(mux.Vars(r)["id"])
+	if !ok {
+		respondError(w, http.StatusNotFound, "Operation not found")
+		return
+	}
+	respondJSON(w, http.StatusOK, op.Snapshot())
+}
+
+// cancelOperation requests cancellation of a running operation via its
+// context. The handler executing the operation must itself observe
+// ctx.Done() for this to actually stop work in progress.
+func (s *Server) cancelOperation(w http.ResponseWriter, r *http.Request) {
+	op, ok := s.operations.Get(mux.Vars(r)["id"])
+	if !ok {
+		respondError(w, http.StatusNotFound, "Operation not found")
+		return
+	}
+	op.Cancel()
+	respondJSON(w, http.StatusOK, op.Snapshot())
+}
+
+// waitOperation blocks until the operation reaches a terminal status or
+// ?timeout= (seconds) elapses, then returns its current snapshot.
+func (s *Server) waitOperation(w http.ResponseWriter, r *http.Request) {
+	op, ok := s.operations.Get(mux.Vars(r)["id"])
+	if !ok {
+		respondError(w, http.StatusNotFound, "Operation not found")
+		return
+	}
+
+	timeout := defaultOperationWaitTimeout
+	if raw := r.URL.Query().Get("timeout"); raw != "" {
+		if secs, err := strconv.Atoi(raw); err == nil && secs > 0 {
+			timeout = time.Duration(secs) * time.Second
+		}
+	}
+
+	op.Wait(timeout)
+	respondJSON(w, http.StatusOK, op.Snapshot())
+}
+
+// operationEvents streams status transitions for an operation over a
+// WebSocket, sending the current snapshot immediately and then one Update
+// per change until the operation reaches a terminal status.
+func (s *Server) operationEvents(w http.ResponseWriter, r *http.Request) {
+	op, ok := s.operations.Get(mux.Vars(r)["id"])
+	if !ok {
+		respondError(w, http.StatusNotFound, "Operation not found")
+		return
+	}
+
+	conn, err := operationsUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	updates, unsubscribe := op.Subscribe()
+	defer unsubscribe()
+
+	if err := conn.WriteJSON(op.Snapshot()); err != nil {
+		return
+	}
+
+	for update := range updates {
+		if err := conn.WriteJSON(update); err != nil {
+			return
+		}
+	}
+}
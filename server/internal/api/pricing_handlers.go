@@ -0,0 +1,72 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/sp00nznet/octopus/internal/pricing"
+)
+
+// pricingCacheTTL bounds how long a resolved price is cached on disk before
+// EstimateCost re-queries the provider's pricing API.
+const pricingCacheTTL = 24 * time.Hour
+
+// pricingManager builds a pricing.Manager wired to whichever provider
+// sources can be constructed from the ambient credential chain. A provider
+// whose source fails to construct (e.g. no AWS/GCP credentials configured on
+// this host) is simply left out - EstimateCost falls back to its hardcoded
+// table for that provider rather than failing the whole estimate.
+func (s *Server) pricingManager(r *http.Request) *pricing.Manager {
+	ctx := r.Context()
+	sources := map[string]pricing.PriceSource{
+		"azure": pricing.NewAzureSource(),
+	}
+	if awsSource, err := pricing.NewAWSSource(ctx); err == nil {
+		sources["aws"] = awsSource
+	}
+	if gcpSource, err := pricing.NewGCPSource(ctx); err == nil {
+		sources["gcp"] = gcpSource
+	}
+
+	cache := pricing.NewCache(s.config.PricingCacheDir, pricingCacheTTL)
+	return pricing.NewManager(cache, sources)
+}
+
+// refreshPricingCache force-refreshes one cached price, bypassing the TTL,
+// so an operator can pull in a rate change (or retry after a provider outage)
+// without waiting for the cache to expire on its own.
+func (s *Server) refreshPricingCache(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Provider       string `json:"provider"`
+		Region         string `json:"region"`
+		InstanceFamily string `json:"instance_family"`
+		DiskType       string `json:"disk_type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Provider == "" || req.Region == "" {
+		respondError(w, http.StatusBadRequest, "provider and region are required")
+		return
+	}
+
+	query := pricing.Query{
+		Provider:       req.Provider,
+		Region:         req.Region,
+		InstanceFamily: req.InstanceFamily,
+		DiskType:       req.DiskType,
+	}
+
+	price, err := s.pricingManager(r).RefreshPrice(r.Context(), query)
+	if err != nil {
+		respondError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+
+	username, _ := r.Context().Value("username").(string)
+	s.logActivity(username, "refresh_pricing_cache", "pricing", 0, req.Provider+"/"+req.Region, r.RemoteAddr, requestIDFromContext(r.Context()))
+
+	respondJSON(w, http.StatusOK, price)
+}
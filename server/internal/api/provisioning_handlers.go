@@ -0,0 +1,113 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sp00nznet/octopus/internal/db"
+	"github.com/sp00nznet/octopus/internal/events"
+	"github.com/sp00nznet/octopus/internal/operations"
+	"github.com/sp00nznet/octopus/internal/providers/vmware"
+)
+
+// provisionableTypes are the environment types ProvisionVM supports today -
+// anything backed by vCenter. Other syncer.Driver types (esxi, libvirt)
+// only support read-only inventory sync so far.
+var provisionableTypes = map[string]bool{
+	"vmware":        true,
+	"vmware-vxrail": true,
+}
+
+// provisionVM clones a template VM within env, seeds it with a cloud-init
+// NoCloud ISO, and powers it on, tracked as an async Operation since
+// clone+customize can take minutes - far longer than an HTTP request
+// should stay open.
+func (s *Server) provisionVM(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	idInt, _ := strconv.ParseInt(id, 10, 64)
+
+	var env db.Environment
+	err := s.db.QueryRow(`
+		SELECT id, name, type, config_json FROM environments WHERE id = ?
+	`, id).Scan(&env.ID, &env.Name, &env.Type, &env.ConfigJSON)
+	if err != nil {
+		respondError(w, http.StatusNotFound, "Environment not found")
+		return
+	}
+
+	if !provisionableTypes[env.Type] {
+		respondError(w, http.StatusBadRequest, fmt.Sprintf("Environment type %q does not support VM provisioning", env.Type))
+		return
+	}
+
+	var req struct {
+		Template string `json:"template"`
+		Name     string `json:"name"`
+		CPU      int    `json:"cpu"`
+		MemoryMB int    `json:"memory_mb"`
+		DiskGB   int    `json:"disk_gb"`
+		Network  string `json:"network"`
+		UserData string `json:"user_data"`
+		MetaData string `json:"meta_data"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	if req.Template == "" || req.Name == "" {
+		respondError(w, http.StatusBadRequest, "template and name are required")
+		return
+	}
+
+	decryptedJSON, err := s.decryptSecretFields(env.Type, []byte(env.ConfigJSON))
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to decrypt environment config: "+err.Error())
+		return
+	}
+	var config struct {
+		Host       string `json:"host"`
+		Username   string `json:"username"`
+		Password   string `json:"password"`
+		Datacenter string `json:"datacenter"`
+		Insecure   bool   `json:"insecure"`
+	}
+	if err := json.Unmarshal(decryptedJSON, &config); err != nil {
+		respondError(w, http.StatusInternalServerError, "Invalid environment config")
+		return
+	}
+
+	op := s.operations.Create(operations.ClassTask, "environment", idInt)
+	op.Run(func(ctx context.Context) error {
+		client, err := vmware.NewClient(config.Host, config.Username, config.Password, config.Datacenter, config.Insecure)
+		if err != nil {
+			return fmt.Errorf("failed to connect to vCenter: %w", err)
+		}
+		defer client.Logout(ctx)
+
+		info, err := client.ProvisionVM(ctx, vmware.ProvisionSpec{
+			Template: req.Template,
+			Name:     req.Name,
+			CPU:      req.CPU,
+			MemoryMB: req.MemoryMB,
+			DiskGB:   req.DiskGB,
+			Network:  req.Network,
+			UserData: []byte(req.UserData),
+			MetaData: []byte(req.MetaData),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to provision VM: %w", err)
+		}
+
+		op.SetProgress(100, map[string]interface{}{"vm_name": info.Name, "vm_uuid": info.UUID})
+		s.events.Publish(events.TypeInstanceCreated, info.Name, map[string]interface{}{
+			"environment_id": idInt, "vm_name": info.Name, "vm_uuid": info.UUID,
+		})
+		return nil
+	})
+
+	respondOperation(w, op)
+}
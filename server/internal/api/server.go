@@ -8,45 +8,79 @@ import (
 	"github.com/sp00nznet/octopus/internal/auth"
 	"github.com/sp00nznet/octopus/internal/config"
 	"github.com/sp00nznet/octopus/internal/db"
+	"github.com/sp00nznet/octopus/internal/events"
+	"github.com/sp00nznet/octopus/internal/metrics"
+	"github.com/sp00nznet/octopus/internal/operations"
 	"github.com/sp00nznet/octopus/internal/scheduler"
+	"github.com/sp00nznet/octopus/internal/secrets"
+	"github.com/sp00nznet/octopus/internal/syncer"
+	"github.com/sp00nznet/octopus/internal/syncjob"
+	"github.com/sp00nznet/octopus/internal/webhooks"
 )
 
 // Server represents the API server
 type Server struct {
-	config    *config.Config
-	db        *db.Database
-	auth      *auth.Authenticator
-	scheduler *scheduler.Scheduler
+	config     *config.Config
+	db         *db.Database
+	auth       *auth.Authenticator
+	scheduler  *scheduler.Scheduler
+	operations *operations.Manager
+	webhooks   *webhooks.Manager
+	syncJobs   *syncjob.Manager
+	syncers    *syncer.Registry
+	secrets    *secrets.Protector
+	events     *events.Publisher
 }
 
 // NewServer creates a new API server
 func NewServer(cfg *config.Config, database *db.Database, sched *scheduler.Scheduler) *Server {
 	return &Server{
-		config:    cfg,
-		db:        database,
-		auth:      auth.New(cfg),
-		scheduler: sched,
+		config:     cfg,
+		db:         database,
+		auth:       auth.New(cfg),
+		scheduler:  sched,
+		operations: operations.NewManager(database),
+		webhooks:   webhooks.NewManager(database),
+		syncJobs:   syncjob.NewManager(database),
+		syncers:    syncer.NewRegistry(),
+		secrets:    secrets.NewProtector(cfg.SecretsKEKURI),
+		events:     events.NewPublisherFromConfig(cfg),
 	}
 }
 
 // Router returns the configured HTTP router
 func (s *Server) Router() *mux.Router {
 	r := mux.NewRouter()
+	r.Use(requestLoggingMiddleware)
 
 	// Serve static files for the web client
 	r.PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.Dir("../client/static"))))
 
+	// Published so downstream services can validate Octopus-issued tokens
+	// without sharing the HMAC secret.
+	r.HandleFunc("/.well-known/jwks.json", s.jwks).Methods("GET")
+
+	// Prometheus scrape endpoint - unauthenticated like most exporters, since
+	// it's expected to sit behind network-level scraper access controls.
+	r.Handle("/metrics", metrics.Handler()).Methods("GET")
+
 	// API routes
 	api := r.PathPrefix("/api/v1").Subrouter()
 
 	// Public routes
 	api.HandleFunc("/health", s.healthCheck).Methods("GET")
 	api.HandleFunc("/auth/login", s.login).Methods("POST")
+	api.HandleFunc("/auth/refresh", s.refreshToken).Methods("POST")
+	api.HandleFunc("/auth/oidc/login", s.oidcLogin).Methods("GET")
+	api.HandleFunc("/auth/oidc/callback", s.oidcCallback).Methods("GET")
+	api.HandleFunc("/auth/saml/acs", s.samlACS).Methods("POST")
 
 	// Protected routes
 	protected := api.PathPrefix("").Subrouter()
 	protected.Use(s.authMiddleware)
 
+	protected.HandleFunc("/auth/logout", s.logout).Methods("POST")
+
 	// Source environments
 	protected.HandleFunc("/sources", s.listSourceEnvironments).Methods("GET")
 	protected.HandleFunc("/sources", s.createSourceEnvironment).Methods("POST")
@@ -61,6 +95,7 @@ func (s *Server) Router() *mux.Router {
 	protected.HandleFunc("/targets/{id}", s.getTargetEnvironment).Methods("GET")
 	protected.HandleFunc("/targets/{id}", s.updateTargetEnvironment).Methods("PUT")
 	protected.HandleFunc("/targets/{id}", s.deleteTargetEnvironment).Methods("DELETE")
+	protected.HandleFunc("/targets/{id}/flavor/estimate", s.estimateFlavor).Methods("POST")
 
 	// VMs
 	protected.HandleFunc("/vms", s.listVMs).Methods("GET")
@@ -75,12 +110,50 @@ func (s *Server) Router() *mux.Router {
 	protected.HandleFunc("/migrations/{id}/cancel", s.cancelMigration).Methods("POST")
 	protected.HandleFunc("/migrations/{id}/sync", s.triggerSync).Methods("POST")
 	protected.HandleFunc("/migrations/{id}/cutover", s.triggerCutover).Methods("POST")
+	protected.HandleFunc("/migrations/{id}/progress", s.getSyncProgress).Methods("GET")
+	protected.HandleFunc("/migrations/{id}/stream", s.streamMigration).Methods("GET")
+	// Alias for /stream - same handler, kept so clients that expect the
+	// more generic "events" name don't need their own SSE parsing path.
+	protected.HandleFunc("/migrations/{id}/events", s.streamMigration).Methods("GET")
+	protected.HandleFunc("/migrations/{id}/ws", s.migrationWebSocket).Methods("GET")
+
+	// Unified environments (legacy, pre source/target split)
+	protected.HandleFunc("/environments", s.listEnvironments).Methods("GET")
+	protected.HandleFunc("/environments", s.createEnvironment).Methods("POST")
+	protected.HandleFunc("/environments/{id}", s.getEnvironment).Methods("GET")
+	protected.HandleFunc("/environments/{id}", s.updateEnvironment).Methods("PUT")
+	protected.HandleFunc("/environments/{id}", s.deleteEnvironment).Methods("DELETE")
+	protected.HandleFunc("/environments/{id}/sync", s.syncEnvironment).Methods("POST")
+	protected.HandleFunc("/environments/{id}/vms", s.provisionVM).Methods("POST")
+
+	// Async sync jobs backing /environments/{id}/sync
+	protected.HandleFunc("/sync_jobs/{id}", s.getSyncJob).Methods("GET")
+	protected.HandleFunc("/sync_jobs/{id}", s.cancelSyncJob).Methods("DELETE")
+	protected.HandleFunc("/sync_jobs/{id}/events", s.syncJobEvents).Methods("GET")
 
 	// Scheduled tasks
 	protected.HandleFunc("/schedules", s.listScheduledTasks).Methods("GET")
 	protected.HandleFunc("/schedules", s.createScheduledTask).Methods("POST")
 	protected.HandleFunc("/schedules/{id}", s.getScheduledTask).Methods("GET")
 	protected.HandleFunc("/schedules/{id}/cancel", s.cancelScheduledTask).Methods("POST")
+	protected.HandleFunc("/schedules/{id}/preview", s.previewScheduledTask).Methods("GET")
+	protected.HandleFunc("/schedules/{id}/retry", s.retryScheduledTask).Methods("POST")
+	protected.HandleFunc("/schedules/{id}/events", s.scheduledTaskEvents).Methods("GET")
+
+	// Async operations (sync, cutover, discovery, ...)
+	protected.HandleFunc("/operations", s.listOperations).Methods("GET")
+	protected.HandleFunc("/operations/{id}", s.getOperation).Methods("GET")
+	protected.HandleFunc("/operations/{id}", s.cancelOperation).Methods("DELETE")
+	protected.HandleFunc("/operations/{id}/wait", s.waitOperation).Methods("GET")
+	protected.HandleFunc("/operations/{id}/events", s.operationEvents).Methods("GET")
+
+	// Webhook notification targets for migration lifecycle events
+	protected.HandleFunc("/webhook_endpoints", s.listWebhookEndpoints).Methods("GET")
+	protected.HandleFunc("/webhook_endpoints", s.createWebhookEndpoint).Methods("POST")
+	protected.HandleFunc("/webhook_endpoints/{id}", s.getWebhookEndpoint).Methods("GET")
+	protected.HandleFunc("/webhook_endpoints/{id}", s.updateWebhookEndpoint).Methods("PUT")
+	protected.HandleFunc("/webhook_endpoints/{id}", s.deleteWebhookEndpoint).Methods("DELETE")
+	protected.HandleFunc("/webhook_endpoints/{id}/deliveries", s.listWebhookDeliveries).Methods("GET")
 
 	// Admin routes
 	admin := api.PathPrefix("/admin").Subrouter()
@@ -92,10 +165,31 @@ func (s *Server) Router() *mux.Router {
 	admin.HandleFunc("/env", s.createEnvVariable).Methods("POST")
 	admin.HandleFunc("/env/{id}", s.updateEnvVariable).Methods("PUT")
 	admin.HandleFunc("/env/{id}", s.deleteEnvVariable).Methods("DELETE")
+	admin.HandleFunc("/env/{id}/resolve", s.resolveEnvVariable).Methods("POST")
 
 	// Activity logs
 	admin.HandleFunc("/logs", s.listActivityLogs).Methods("GET")
 
+	// Portable environment/migration bundle export and import
+	admin.HandleFunc("/export", s.exportBundle).Methods("GET")
+	admin.HandleFunc("/import", s.importBundle).Methods("POST")
+
+	// Rotates the KEK protecting secret fields in environments,
+	// source_environments, target_environments, and secret env_variables
+	admin.HandleFunc("/rekey", s.rekeySecrets).Methods("POST")
+
+	// Force-refreshes one EstimateCost pricing cache entry
+	admin.HandleFunc("/pricing/refresh", s.refreshPricingCache).Methods("POST")
+
+	// Actionable cards materialized by the background discovery controller
+	admin.HandleFunc("/tasks", s.listUserTasks).Methods("GET")
+	admin.HandleFunc("/tasks/{id}/ack", s.ackUserTask).Methods("POST")
+
+	// Lifecycle actions on a completed migration's target instance
+	admin.HandleFunc("/migrations/{id}/vm/start", s.startVM).Methods("POST")
+	admin.HandleFunc("/migrations/{id}/vm/stop", s.stopVM).Methods("POST")
+	admin.HandleFunc("/migrations/{id}/vm/resize", s.resizeVM).Methods("POST")
+
 	// Users
 	admin.HandleFunc("/users", s.listUsers).Methods("GET")
 	admin.HandleFunc("/users/{id}", s.getUser).Methods("GET")
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sp00nznet/octopus/internal/syncjob"
+)
+
+func (s *Server) getSyncJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid sync job id")
+		return
+	}
+
+	job, ok := s.syncJobs.Get(id)
+	if !ok {
+		respondError(w, http.StatusNotFound, "Sync job not found")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, job.Snapshot())
+}
+
+// cancelSyncJob requests cancellation of an in-flight sync job. Cancellation
+// takes effect the next time the running pull checks its context - between
+// VMs, not mid-call.
+func (s *Server) cancelSyncJob(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid sync job id")
+		return
+	}
+
+	job, ok := s.syncJobs.Get(id)
+	if !ok {
+		respondError(w, http.StatusNotFound, "Sync job not found")
+		return
+	}
+
+	job.Cancel()
+	respondJSON(w, http.StatusOK, map[string]string{"status": "cancelling"})
+}
+
+// syncJobEvents streams a sync job's progress as it happens over
+// server-sent events, replaying the current snapshot first so a client that
+// connects after the job started still sees where it stands.
+func (s *Server) syncJobEvents(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid sync job id")
+		return
+	}
+
+	job, ok := s.syncJobs.Get(id)
+	if !ok {
+		respondError(w, http.StatusNotFound, "Sync job not found")
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		respondError(w, http.StatusInternalServerError, "Streaming unsupported")
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	updates, unsubscribe := job.Subscribe()
+	defer unsubscribe()
+
+	writeSyncJobEvent(w, job.Snapshot())
+	flusher.Flush()
+
+	for {
+		select {
+		case snap, ok := <-updates:
+			if !ok {
+				return
+			}
+			writeSyncJobEvent(w, snap)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func writeSyncJobEvent(w http.ResponseWriter, snap syncjob.Snapshot) {
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: progress\ndata: %s\n\n", data)
+}
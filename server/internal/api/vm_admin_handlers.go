@@ -0,0 +1,201 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+	"github.com/sp00nznet/octopus/internal/cloud"
+)
+
+// resolveTargetActions looks up jobID's VM name and target environment, then
+// builds that target's cloud.Provider and type-asserts it for
+// cloud.TargetActions - not every driver supports admin lifecycle actions
+// yet (today, only aws does), the same gap flavor_handlers.estimateFlavor
+// already works around for cloud.FlavorAware. The VM's name doubles as its
+// instance ID, matching how sync.SyncManager already addresses target
+// instances (see powerOnTarget/powerOffSource).
+func (s *Server) resolveTargetActions(jobID string) (actions cloud.TargetActions, instanceID string, err error) {
+	var vmName, targetType, targetConfigJSON, jobStatus string
+	err = s.db.QueryRow(`
+		SELECT v.name, t.type, t.config_json, m.status
+		FROM migration_jobs m
+		JOIN vms v ON m.vm_id = v.id
+		JOIN target_environments t ON m.target_env_id = t.id
+		WHERE m.id = ?
+	`, jobID).Scan(&vmName, &targetType, &targetConfigJSON, &jobStatus)
+	if err != nil {
+		return nil, "", fmt.Errorf("migration job not found: %w", err)
+	}
+	if jobStatus != "completed" {
+		return nil, "", fmt.Errorf("migration job is %q; VM actions require a completed migration", jobStatus)
+	}
+
+	decryptedJSON, err := s.decryptSecretFields(targetType, []byte(targetConfigJSON))
+	if err != nil {
+		return nil, "", fmt.Errorf("decrypt target config: %w", err)
+	}
+
+	provider, err := s.cloudProviderFor(targetType, decryptedJSON)
+	if err != nil {
+		return nil, "", fmt.Errorf("initialize target provider: %w", err)
+	}
+
+	actions, ok := provider.(cloud.TargetActions)
+	if !ok {
+		return nil, "", fmt.Errorf("target type %q does not support VM lifecycle actions yet", targetType)
+	}
+	return actions, vmName, nil
+}
+
+// cloudProviderFor is a thin wrapper around cloud.New so tests could stub it
+// later; kept as a method purely for symmetry with the rest of Server's
+// provider-resolving handlers.
+func (s *Server) cloudProviderFor(targetType string, configJSON []byte) (cloud.Provider, error) {
+	return cloud.New(targetType, configJSON)
+}
+
+// taskRunningForJob reports whether jobID has a scheduled_tasks row
+// currently 'running' - resizeVM refuses to proceed while one does, since a
+// scheduled cutover/failover/sync could be mid-flight against the same
+// instance.
+func (s *Server) taskRunningForJob(jobID string) (bool, error) {
+	var count int
+	err := s.db.QueryRow(`SELECT COUNT(*) FROM scheduled_tasks WHERE job_id = ? AND status = 'running'`, jobID).Scan(&count)
+	if err != nil {
+		return false, fmt.Errorf("check scheduled tasks for job: %w", err)
+	}
+	return count > 0, nil
+}
+
+// startVM powers on a completed migration's target instance.
+func (s *Server) startVM(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	actions, instanceID, err := s.resolveTargetActions(id)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	before, _ := s.instanceStatus(id, instanceID)
+
+	if err := actions.StartVM(r.Context(), instanceID); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to start VM: "+err.Error())
+		return
+	}
+
+	after, _ := s.instanceStatus(id, instanceID)
+	s.logVMAction(r, id, "start_vm", before, after)
+	respondJSON(w, http.StatusOK, map[string]string{"status": "started"})
+}
+
+// stopVM powers off a completed migration's target instance. Body:
+// {"force": bool} - force skips the guest's graceful shutdown.
+func (s *Server) stopVM(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req struct {
+		Force bool `json:"force"`
+	}
+	json.NewDecoder(r.Body).Decode(&req)
+
+	actions, instanceID, err := s.resolveTargetActions(id)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	before, _ := s.instanceStatus(id, instanceID)
+
+	if err := actions.StopVM(r.Context(), instanceID, req.Force); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to stop VM: "+err.Error())
+		return
+	}
+
+	after, _ := s.instanceStatus(id, instanceID)
+	s.logVMAction(r, id, "stop_vm", before, after)
+	respondJSON(w, http.StatusOK, map[string]string{"status": "stopped"})
+}
+
+// resizeVM changes a completed migration's target instance type. Body:
+// {"instance_type": string}. Refused while a scheduled_tasks row for the
+// job is 'running', so a scheduled cutover/failover/sync can't race a
+// manual resize against the same instance.
+func (s *Server) resizeVM(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+
+	var req struct {
+		InstanceType string `json:"instance_type"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.InstanceType == "" {
+		respondError(w, http.StatusBadRequest, "instance_type is required")
+		return
+	}
+
+	running, err := s.taskRunningForJob(id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if running {
+		respondError(w, http.StatusConflict, "a scheduled task for this migration is currently running")
+		return
+	}
+
+	actions, instanceID, err := s.resolveTargetActions(id)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	before, _ := s.instanceStatus(id, instanceID)
+
+	if err := actions.ResizeVM(r.Context(), instanceID, req.InstanceType); err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to resize VM: "+err.Error())
+		return
+	}
+
+	after, _ := s.instanceStatus(id, instanceID)
+	s.logVMAction(r, id, "resize_vm", before, fmt.Sprintf("%s (instance_type=%s)", after, req.InstanceType))
+	respondJSON(w, http.StatusOK, map[string]string{"status": "resized"})
+}
+
+// instanceStatus is a best-effort GetInstanceInfo lookup for before/after
+// activity log details - a lookup failure (e.g. mid-transition) shouldn't
+// block the action itself, so callers ignore its error.
+func (s *Server) instanceStatus(jobID, instanceID string) (string, error) {
+	var targetType, targetConfigJSON string
+	err := s.db.QueryRow(`
+		SELECT t.type, t.config_json
+		FROM migration_jobs m
+		JOIN target_environments t ON m.target_env_id = t.id
+		WHERE m.id = ?
+	`, jobID).Scan(&targetType, &targetConfigJSON)
+	if err != nil {
+		return "", err
+	}
+	decryptedJSON, err := s.decryptSecretFields(targetType, []byte(targetConfigJSON))
+	if err != nil {
+		return "", err
+	}
+	provider, err := s.cloudProviderFor(targetType, decryptedJSON)
+	if err != nil {
+		return "", err
+	}
+	info, err := provider.GetInstanceInfo(instanceID)
+	if err != nil {
+		return "", err
+	}
+	return info.Status, nil
+}
+
+// logVMAction records a VM admin action in activity_logs with its before/after state.
+func (s *Server) logVMAction(r *http.Request, jobID, action, before, after string) {
+	username, _ := r.Context().Value("username").(string)
+	jobIDInt, _ := strconv.ParseInt(jobID, 10, 64)
+	details := fmt.Sprintf("before=%q after=%q", before, after)
+	s.logActivity(username, action, "migration_job", jobIDInt, details, r.RemoteAddr, requestIDFromContext(r.Context()))
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/sp00nznet/octopus/internal/db"
+)
+
+// Webhook endpoint handlers
+func (s *Server) listWebhookEndpoints(w http.ResponseWriter, r *http.Request) {
+	rows, err := s.db.Query(`
+		SELECT id, name, url, events, max_retries, retry_backoff_seconds, is_active, created_at, updated_at
+		FROM webhook_endpoints
+		ORDER BY name
+	`)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	defer rows.Close()
+
+	var endpoints []db.WebhookEndpoint
+	for rows.Next() {
+		var ep db.WebhookEndpoint
+		err := rows.Scan(&ep.ID, &ep.Name, &ep.URL, &ep.Events, &ep.MaxRetries,
+			&ep.RetryBackoffSeconds, &ep.IsActive, &ep.CreatedAt, &ep.UpdatedAt)
+		if err != nil {
+			continue
+		}
+		endpoints = append(endpoints, ep)
+	}
+
+	respondJSON(w, http.StatusOK, endpoints)
+}
+
+func (s *Server) createWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name                string `json:"name"`
+		URL                 string `json:"url"`
+		Events              string `json:"events"`
+		AuthToken           string `json:"auth_token"`
+		MaxRetries          int    `json:"max_retries"`
+		RetryBackoffSeconds int    `json:"retry_backoff_seconds"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	secret, err := randomSigningSecret()
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to generate signing secret")
+		return
+	}
+
+	result, err := s.db.Exec(`
+		INSERT INTO webhook_endpoints (name, url, events, auth_token, signing_secret, max_retries, retry_backoff_seconds)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, req.Name, req.URL, req.Events, req.AuthToken, secret, req.MaxRetries, req.RetryBackoffSeconds)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to create webhook endpoint")
+		return
+	}
+
+	id, _ := result.LastInsertId()
+	respondJSON(w, http.StatusCreated, map[string]interface{}{"id": id, "signing_secret": secret})
+}
+
+func (s *Server) getWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var ep db.WebhookEndpoint
+	err := s.db.QueryRow(`
+		SELECT id, name, url, events, max_retries, retry_backoff_seconds, is_active, created_at, updated_at
+		FROM webhook_endpoints WHERE id = ?
+	`, id).Scan(&ep.ID, &ep.Name, &ep.URL, &ep.Events, &ep.MaxRetries,
+		&ep.RetryBackoffSeconds, &ep.IsActive, &ep.CreatedAt, &ep.UpdatedAt)
+	if err == sql.ErrNoRows {
+		respondError(w, http.StatusNotFound, "Webhook endpoint not found")
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	respondJSON(w, http.StatusOK, ep)
+}
+
+func (s *Server) updateWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	var req struct {
+		Name                string `json:"name"`
+		URL                 string `json:"url"`
+		Events              string `json:"events"`
+		AuthToken           string `json:"auth_token"`
+		MaxRetries          int    `json:"max_retries"`
+		RetryBackoffSeconds int    `json:"retry_backoff_seconds"`
+		IsActive            bool   `json:"is_active"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	_, err := s.db.Exec(`
+		UPDATE webhook_endpoints
+		SET name=?, url=?, events=?, auth_token=?, max_retries=?, retry_backoff_seconds=?, is_active=?, updated_at=?
+		WHERE id=?
+	`, req.Name, req.URL, req.Events, req.AuthToken, req.MaxRetries, req.RetryBackoffSeconds, req.IsActive, time.Now(), id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to update webhook endpoint")
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]string{"status": "updated"})
+}
+
+func (s *Server) deleteWebhookEndpoint(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	_, err := s.db.Exec("DELETE FROM webhook_endpoints WHERE id = ?", id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Failed to delete webhook endpoint")
+		return
+	}
+	respondJSON(w, http.StatusOK, map[string]string{"status": "deleted"})
+}
+
+// listWebhookDeliveries returns the delivery audit log for a single
+// endpoint, most recent first.
+func (s *Server) listWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	rows, err := s.db.Query(`
+		SELECT id, endpoint_id, event, payload_json, status_code, attempt, success, error_message, created_at
+		FROM webhook_deliveries WHERE endpoint_id = ?
+		ORDER BY created_at DESC
+	`, id)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, "Database error")
+		return
+	}
+	defer rows.Close()
+
+	var deliveries []db.WebhookDelivery
+	for rows.Next() {
+		var d db.WebhookDelivery
+		err := rows.Scan(&d.ID, &d.EndpointID, &d.Event, &d.PayloadJSON, &d.StatusCode,
+			&d.Attempt, &d.Success, &d.ErrorMessage, &d.CreatedAt)
+		if err != nil {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	respondJSON(w, http.StatusOK, deliveries)
+}
+
+// randomSigningSecret generates a signing secret for a new webhook endpoint.
+func randomSigningSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -1,17 +1,44 @@
 package auth
 
 import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
+	"log"
+	"net/http"
 	"time"
 
-	"github.com/go-ldap/ldap/v3"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/sp00nznet/octopus/internal/config"
 )
 
-// Authenticator handles user authentication via AD
+// refreshTokenTTL is how long a refresh token remains redeemable. Each
+// successful Refresh rotates it, so a stolen refresh token can't be replayed
+// indefinitely.
+const refreshTokenTTL = 30 * 24 * time.Hour
+
+// Provider authenticates a username/password pair against a single backend
+// (AD bind, local dev accounts, etc). Redirect-based providers such as OIDC
+// and SAML also implement Provider for consistency, but are driven through
+// their dedicated HTTP handlers rather than through the password chain.
+type Provider interface {
+	Authenticate(username, password string) (*User, error)
+	Name() string
+}
+
+// Authenticator dispatches authentication across a chain of Providers and
+// mints access/refresh token pairs for whichever Provider accepts the
+// credentials.
 type Authenticator struct {
-	config *config.Config
+	config     *config.Config
+	providers  []Provider
+	tokenStore TokenStore
+	signingKey *signingKey
+
+	oidc *oidcProvider
+	saml *samlProvider
 }
 
 // User represents an authenticated user
@@ -22,184 +49,306 @@ type User struct {
 	IsAdmin     bool   `json:"is_admin"`
 }
 
-// Claims represents JWT claims
+// Claims represents JWT claims. The embedded RegisteredClaims.ID (jti)
+// identifies the access token so it can be revoked independently of its
+// expiration.
 type Claims struct {
 	Username string `json:"username"`
 	IsAdmin  bool   `json:"is_admin"`
 	jwt.RegisteredClaims
 }
 
-// New creates a new authenticator
+// New creates a new authenticator from cfg.AuthProviders. When no providers
+// are configured, it falls back to the historical behavior: AD bind if
+// cfg.ADServer or cfg.ADURL is set, otherwise local dev accounts.
+//
+// Access tokens are signed with HS256 using cfg.JWTSecret unless
+// cfg.JWTPrivateKeyPath is set, in which case RS256/ES256 is used and the
+// public key is published via JWKS. Refresh tokens and revoked access-token
+// IDs are kept in cfg.TokenStorePath (BoltDB) if set, otherwise in memory.
 func New(cfg *config.Config) *Authenticator {
-	return &Authenticator{config: cfg}
-}
-
-// Authenticate validates credentials against AD and returns a JWT token
-func (a *Authenticator) Authenticate(username, password string) (*User, string, error) {
-	// If AD is not configured, use local auth (for development)
-	if a.config.ADServer == "" {
-		return a.localAuth(username, password)
-	}
+	a := &Authenticator{config: cfg}
 
-	// Connect to AD server
-	l, err := ldap.DialURL(fmt.Sprintf("ldap://%s:389", a.config.ADServer))
+	store, err := newTokenStore(cfg)
 	if err != nil {
-		return nil, "", fmt.Errorf("failed to connect to AD: %w", err)
+		log.Printf("auth: failed to open token store, falling back to in-memory: %v", err)
+		store = newMemoryTokenStore()
+	}
+	a.tokenStore = store
+
+	if cfg.JWTPrivateKeyPath != "" {
+		key, err := loadSigningKey(cfg.JWTPrivateKeyPath)
+		if err != nil {
+			log.Printf("auth: failed to load JWT private key, falling back to HS256: %v", err)
+		} else {
+			a.signingKey = key
+		}
 	}
-	defer l.Close()
 
-	// Bind with service account to search
-	err = l.Bind(a.config.ADBindUser, a.config.ADBindPass)
-	if err != nil {
-		return nil, "", fmt.Errorf("failed to bind to AD: %w", err)
-	}
-
-	// Search for user
-	searchFilter := fmt.Sprintf("(&(objectClass=user)(sAMAccountName=%s))", ldap.EscapeFilter(username))
-	searchRequest := ldap.NewSearchRequest(
-		a.config.ADBaseDN,
-		ldap.ScopeWholeSubtree,
-		ldap.NeverDerefAliases,
-		0, 0, false,
-		searchFilter,
-		[]string{"dn", "cn", "mail", "memberOf"},
-		nil,
-	)
-
-	result, err := l.Search(searchRequest)
-	if err != nil {
-		return nil, "", fmt.Errorf("AD search failed: %w", err)
+	if len(cfg.AuthProviders) == 0 {
+		if cfg.ADServer != "" || cfg.ADURL != "" {
+			a.providers = []Provider{newLDAPProvider(cfg)}
+		} else {
+			a.providers = []Provider{newLocalProvider()}
+		}
+		return a
 	}
 
-	if len(result.Entries) == 0 {
-		return nil, "", fmt.Errorf("user not found")
+	for _, pc := range cfg.AuthProviders {
+		switch pc.Type {
+		case "ldap":
+			a.providers = append(a.providers, newLDAPProvider(cfg))
+		case "local":
+			a.providers = append(a.providers, newLocalProvider())
+		case "oidc":
+			p, err := newOIDCProvider(cfg)
+			if err != nil {
+				log.Printf("auth: failed to initialize oidc provider: %v", err)
+				continue
+			}
+			a.oidc = p
+			a.providers = append(a.providers, p)
+		case "saml":
+			p := newSAMLProvider(cfg)
+			a.saml = p
+			a.providers = append(a.providers, p)
+		default:
+			log.Printf("auth: unknown provider type %q, skipping", pc.Type)
+		}
 	}
 
-	userDN := result.Entries[0].DN
-	displayName := result.Entries[0].GetAttributeValue("cn")
-	email := result.Entries[0].GetAttributeValue("mail")
-	memberOf := result.Entries[0].GetAttributeValues("memberOf")
+	return a
+}
 
-	// Verify user credentials
-	err = l.Bind(userDN, password)
-	if err != nil {
-		return nil, "", fmt.Errorf("invalid credentials")
+func newTokenStore(cfg *config.Config) (TokenStore, error) {
+	if cfg.TokenStorePath == "" {
+		return newMemoryTokenStore(), nil
 	}
+	return newBoltTokenStore(cfg.TokenStorePath)
+}
 
-	// Check if user is in admin group
-	isAdmin := false
-	for _, group := range memberOf {
-		if containsAdminGroup(group) {
-			isAdmin = true
-			break
+// Authenticate tries each configured provider in order and, for the first
+// one that accepts the credentials, mints an access/refresh token pair.
+func (a *Authenticator) Authenticate(username, password string) (*User, string, string, error) {
+	var lastErr error
+	for _, p := range a.providers {
+		user, err := p.Authenticate(username, password)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		access, refresh, err := a.issueTokens(user)
+		if err != nil {
+			return nil, "", "", err
 		}
+		return user, access, refresh, nil
+	}
+
+	if lastErr == nil {
+		lastErr = fmt.Errorf("no authentication providers configured")
+	}
+	return nil, "", "", lastErr
+}
+
+// Refresh redeems a refresh token for a new access/refresh token pair,
+// rotating the refresh token so it can't be replayed.
+func (a *Authenticator) Refresh(refreshToken string) (*User, string, string, error) {
+	rt, err := a.tokenStore.GetRefresh(refreshToken)
+	if err != nil {
+		return nil, "", "", fmt.Errorf("invalid refresh token")
+	}
+	if time.Now().After(rt.ExpiresAt) {
+		a.tokenStore.DeleteRefresh(refreshToken)
+		return nil, "", "", fmt.Errorf("refresh token expired")
+	}
+	if err := a.tokenStore.DeleteRefresh(refreshToken); err != nil {
+		log.Printf("auth: failed to delete redeemed refresh token: %v", err)
 	}
 
 	user := &User{
-		Username:    username,
-		DisplayName: displayName,
-		Email:       email,
-		IsAdmin:     isAdmin,
+		Username:    rt.Username,
+		DisplayName: rt.DisplayName,
+		Email:       rt.Email,
+		IsAdmin:     rt.IsAdmin,
 	}
 
-	// Generate JWT token
-	token, err := a.generateToken(user)
+	access, refresh, err := a.issueTokens(user)
 	if err != nil {
-		return nil, "", err
+		return nil, "", "", err
 	}
+	return user, access, refresh, nil
+}
 
-	return user, token, nil
+// Revoke blacklists an access token's jti so ValidateToken rejects it even
+// though it hasn't expired yet (used for logout).
+func (a *Authenticator) Revoke(tokenID string) error {
+	expiresAt := time.Now().Add(time.Duration(a.config.JWTExpiration) * time.Hour)
+	return a.tokenStore.Revoke(tokenID, expiresAt)
 }
 
-// localAuth provides local authentication for development
-func (a *Authenticator) localAuth(username, password string) (*User, string, error) {
-	// For development: accept admin/admin
-	if username == "admin" && password == "admin" {
-		user := &User{
-			Username:    "admin",
-			DisplayName: "Administrator",
-			Email:       "admin@localhost",
-			IsAdmin:     true,
-		}
-		token, err := a.generateToken(user)
-		return user, token, err
+// issueTokens mints an access/refresh token pair for a user authenticated by
+// any provider, including out-of-band flows like an OIDC callback or SAML
+// ACS handler.
+func (a *Authenticator) issueTokens(user *User) (string, string, error) {
+	access, _, err := a.generateAccessToken(user)
+	if err != nil {
+		return "", "", err
 	}
+	refresh, err := a.generateRefreshToken(user)
+	if err != nil {
+		return "", "", err
+	}
+	return access, refresh, nil
+}
 
-	// Accept any user/user combo in dev mode
-	if username == password && username != "" {
-		user := &User{
-			Username:    username,
-			DisplayName: username,
-			Email:       username + "@localhost",
-			IsAdmin:     false,
-		}
-		token, err := a.generateToken(user)
-		return user, token, err
+// OIDCLoginURL returns the IdP authorization URL for the /auth/oidc/login
+// handler to redirect to, or an error if no oidc provider is configured.
+func (a *Authenticator) OIDCLoginURL(state string) (string, error) {
+	if a.oidc == nil {
+		return "", fmt.Errorf("oidc provider not configured")
+	}
+	return a.oidc.AuthCodeURL(state), nil
+}
+
+// OIDCCallback completes the authorization-code flow for the
+// /auth/oidc/callback handler and mints a token pair for the resulting user.
+func (a *Authenticator) OIDCCallback(ctx context.Context, code string) (*User, string, string, error) {
+	if a.oidc == nil {
+		return nil, "", "", fmt.Errorf("oidc provider not configured")
+	}
+	user, err := a.oidc.Exchange(ctx, code)
+	if err != nil {
+		return nil, "", "", err
+	}
+	access, refresh, err := a.issueTokens(user)
+	if err != nil {
+		return nil, "", "", err
 	}
+	return user, access, refresh, nil
+}
 
-	return nil, "", fmt.Errorf("invalid credentials")
+// SAMLACS validates the SAMLResponse on r for the /auth/saml/acs handler and
+// mints a token pair for the resulting user.
+func (a *Authenticator) SAMLACS(r *http.Request) (*User, string, string, error) {
+	if a.saml == nil {
+		return nil, "", "", fmt.Errorf("saml provider not configured")
+	}
+	user, err := a.saml.HandleACS(r)
+	if err != nil {
+		return nil, "", "", err
+	}
+	access, refresh, err := a.issueTokens(user)
+	if err != nil {
+		return nil, "", "", err
+	}
+	return user, access, refresh, nil
 }
 
-// generateToken creates a JWT token for the user
-func (a *Authenticator) generateToken(user *User) (string, error) {
+// generateAccessToken creates a short-lived JWT for the user, signed with
+// the asymmetric key if one is configured, otherwise HS256. It returns the
+// token along with its jti.
+func (a *Authenticator) generateAccessToken(user *User) (string, string, error) {
+	jti := uuid.NewString()
 	expirationTime := time.Now().Add(time.Duration(a.config.JWTExpiration) * time.Hour)
 
 	claims := &Claims{
 		Username: user.Username,
 		IsAdmin:  user.IsAdmin,
 		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        jti,
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			Issuer:    "octopus",
 		},
 	}
 
+	if a.signingKey != nil {
+		token := jwt.NewWithClaims(a.signingKey.method, claims)
+		token.Header["kid"] = a.signingKey.kid
+		signed, err := token.SignedString(a.signingKey.private)
+		return signed, jti, err
+	}
+
+	secret, err := a.jwtSecret()
+	if err != nil {
+		return "", "", err
+	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(a.config.JWTSecret))
+	signed, err := token.SignedString(secret)
+	return signed, jti, err
+}
+
+// jwtSecret resolves cfg.JWTSecret through the configured Resolver, so a
+// "vault://" or "env://" reference there is read fresh each time a token is
+// signed or validated rather than only once at startup.
+func (a *Authenticator) jwtSecret() ([]byte, error) {
+	secret, err := a.config.Resolver.Resolve(context.Background(), a.config.JWTSecret)
+	if err != nil {
+		return nil, fmt.Errorf("resolve JWT secret: %w", err)
+	}
+	return []byte(secret), nil
+}
+
+// generateRefreshToken issues a random refresh token and persists it in the
+// token store.
+func (a *Authenticator) generateRefreshToken(user *User) (string, error) {
+	id, err := randomTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	rt := &RefreshToken{
+		ID:          id,
+		Username:    user.Username,
+		DisplayName: user.DisplayName,
+		Email:       user.Email,
+		IsAdmin:     user.IsAdmin,
+		ExpiresAt:   time.Now().Add(refreshTokenTTL),
+	}
+	if err := a.tokenStore.SaveRefresh(rt); err != nil {
+		return "", err
+	}
+	return id, nil
+}
+
+func randomTokenID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
 }
 
-// ValidateToken validates a JWT token and returns the claims
+// ValidateToken validates a JWT token and returns its claims. The expected
+// signing method is pinned to whichever one this Authenticator is
+// configured to issue (HS256, or the configured RS/ES method), so a token
+// forged with a different algorithm - the classic alg-confusion attack - is
+// rejected outright rather than merely checked for "is HMAC".
 func (a *Authenticator) ValidateToken(tokenString string) (*Claims, error) {
 	claims := &Claims{}
 
 	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+		if a.signingKey != nil {
+			if token.Method != a.signingKey.method {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+			return a.signingKey.private.Public(), nil
+		}
+		if token.Method != jwt.SigningMethodHS256 {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return []byte(a.config.JWTSecret), nil
+		return a.jwtSecret()
 	})
-
 	if err != nil {
 		return nil, err
 	}
-
 	if !token.Valid {
 		return nil, fmt.Errorf("invalid token")
 	}
 
-	return claims, nil
-}
-
-// containsAdminGroup checks if the group DN contains an admin group
-func containsAdminGroup(groupDN string) bool {
-	// Check for common admin group names
-	adminGroups := []string{
-		"Domain Admins",
-		"Octopus Admins",
-		"CN=Administrators",
+	if revoked, err := a.tokenStore.IsRevoked(claims.ID); err == nil && revoked {
+		return nil, fmt.Errorf("token has been revoked")
 	}
 
-	for _, adminGroup := range adminGroups {
-		if ldap.EscapeFilter(groupDN) != groupDN {
-			continue
-		}
-		if containsIgnoreCase(groupDN, adminGroup) {
-			return true
-		}
-	}
-	return false
-}
-
-func containsIgnoreCase(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsIgnoreCase(s[1:], substr))
+	return claims, nil
 }
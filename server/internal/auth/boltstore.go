@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	refreshBucket = []byte("refresh_tokens")
+	revokedBucket = []byte("revoked_tokens")
+)
+
+// boltTokenStore is a TokenStore backed by a BoltDB file, used when
+// cfg.TokenStorePath is set so refresh tokens and revocations survive
+// restarts.
+type boltTokenStore struct {
+	db *bolt.DB
+}
+
+func newBoltTokenStore(path string) (*boltTokenStore, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open token store: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := tx.CreateBucketIfNotExists(refreshBucket); err != nil {
+			return err
+		}
+		_, err := tx.CreateBucketIfNotExists(revokedBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize token store buckets: %w", err)
+	}
+
+	return &boltTokenStore{db: db}, nil
+}
+
+func (s *boltTokenStore) SaveRefresh(rt *RefreshToken) error {
+	data, err := json.Marshal(rt)
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(refreshBucket).Put([]byte(rt.ID), data)
+	})
+}
+
+func (s *boltTokenStore) GetRefresh(tokenID string) (*RefreshToken, error) {
+	var rt RefreshToken
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(refreshBucket).Get([]byte(tokenID))
+		if data == nil {
+			return ErrTokenNotFound
+		}
+		return json.Unmarshal(data, &rt)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &rt, nil
+}
+
+func (s *boltTokenStore) DeleteRefresh(tokenID string) error {
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(refreshBucket).Delete([]byte(tokenID))
+	})
+}
+
+func (s *boltTokenStore) Revoke(jti string, expiresAt time.Time) error {
+	data, err := expiresAt.MarshalBinary()
+	if err != nil {
+		return err
+	}
+	return s.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(revokedBucket).Put([]byte(jti), data)
+	})
+}
+
+func (s *boltTokenStore) IsRevoked(jti string) (bool, error) {
+	var expiresAt time.Time
+	found := false
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		data := tx.Bucket(revokedBucket).Get([]byte(jti))
+		if data == nil {
+			return nil
+		}
+		found = true
+		return expiresAt.UnmarshalBinary(data)
+	})
+	if err != nil {
+		return false, err
+	}
+	if !found || time.Now().After(expiresAt) {
+		return false, nil
+	}
+	return true, nil
+}
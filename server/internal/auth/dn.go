@@ -0,0 +1,47 @@
+package auth
+
+import (
+	"strings"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// isAdminGroup reports whether groupDN names one of adminGroups. Each side
+// is parsed as an RDN sequence so "CN=Domain Admins,OU=Groups,DC=corp,DC=com"
+// matches the configured group "Domain Admins" (or the full DN) regardless
+// of attribute case or RDN ordering quirks, replacing the old recursive
+// substring scan that was both O(n²) and prone to false positives.
+func isAdminGroup(groupDN string, adminGroups []string) bool {
+	groupCN := firstRDNValue(groupDN, "cn")
+
+	for _, adminGroup := range adminGroups {
+		if strings.EqualFold(groupDN, adminGroup) {
+			return true
+		}
+		if groupCN != "" && strings.EqualFold(groupCN, adminGroup) {
+			return true
+		}
+		if adminCN := firstRDNValue(adminGroup, "cn"); adminCN != "" && strings.EqualFold(groupCN, adminCN) {
+			return true
+		}
+	}
+	return false
+}
+
+// firstRDNValue parses dn and returns the value of its first RDN attribute
+// matching attr (case-insensitively). If dn doesn't parse as a DN, or has no
+// matching attribute, it returns "".
+func firstRDNValue(dn, attr string) string {
+	parsed, err := ldap.ParseDN(dn)
+	if err != nil || len(parsed.RDNs) == 0 {
+		return ""
+	}
+	for _, rdn := range parsed.RDNs {
+		for _, attrTypeAndValue := range rdn.Attributes {
+			if strings.EqualFold(attrTypeAndValue.Type, attr) {
+				return attrTypeAndValue.Value
+			}
+		}
+	}
+	return ""
+}
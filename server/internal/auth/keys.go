@@ -0,0 +1,146 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha1"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey holds the asymmetric key material and derived JWT signing
+// method used when cfg.JWTPrivateKeyPath is set, so downstream services can
+// validate tokens against the published JWKS without sharing a secret.
+type signingKey struct {
+	method  jwt.SigningMethod
+	kid     string
+	private crypto.Signer
+}
+
+// loadSigningKey reads a PEM-encoded RSA or EC private key from path and
+// derives the signing method (RS256, or ES256/ES384/ES512 by curve size)
+// and key ID from it.
+func loadSigningKey(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("JWT private key is not valid PEM")
+	}
+
+	signer, err := parsePrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+
+	var method jwt.SigningMethod
+	switch key := signer.(type) {
+	case *rsa.PrivateKey:
+		method = jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		switch key.Curve.Params().BitSize {
+		case 256:
+			method = jwt.SigningMethodES256
+		case 384:
+			method = jwt.SigningMethodES384
+		case 521:
+			method = jwt.SigningMethodES512
+		default:
+			return nil, fmt.Errorf("unsupported EC curve size %d", key.Curve.Params().BitSize)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported JWT private key type %T", signer)
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(signer.Public())
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal JWT public key: %w", err)
+	}
+	sum := sha1.Sum(pub)
+
+	return &signingKey{
+		method:  method,
+		kid:     base64.RawURLEncoding.EncodeToString(sum[:]),
+		private: signer,
+	}, nil
+}
+
+func parsePrivateKey(der []byte) (crypto.Signer, error) {
+	if key, err := x509.ParsePKCS1PrivateKey(der); err == nil {
+		return key, nil
+	}
+	if key, err := x509.ParseECPrivateKey(der); err == nil {
+		return key, nil
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, fmt.Errorf("unsupported JWT private key format: %w", err)
+	}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return k, nil
+	case *ecdsa.PrivateKey:
+		return k, nil
+	default:
+		return nil, fmt.Errorf("unsupported JWT private key type %T", key)
+	}
+}
+
+// JWK is a single JSON Web Key, formatted per RFC 7517 for an RSA or EC
+// public key.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JWKSet is the document published at /.well-known/jwks.json.
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// JWKS returns the JSON Web Key Set for the configured asymmetric signing
+// key. ok is false when the authenticator is signing with HS256, since
+// there is then no public key to publish.
+func (a *Authenticator) JWKS() (*JWKSet, bool) {
+	if a.signingKey == nil {
+		return nil, false
+	}
+
+	jwk := JWK{
+		Kid: a.signingKey.kid,
+		Use: "sig",
+		Alg: a.signingKey.method.Alg(),
+	}
+
+	switch pub := a.signingKey.private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		jwk.Kty = "EC"
+		jwk.Crv = pub.Curve.Params().Name
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		jwk.X = base64.RawURLEncoding.EncodeToString(pub.X.FillBytes(make([]byte, size)))
+		jwk.Y = base64.RawURLEncoding.EncodeToString(pub.Y.FillBytes(make([]byte, size)))
+	}
+
+	return &JWKSet{Keys: []JWK{jwk}}, true
+}
@@ -0,0 +1,254 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+
+	"github.com/go-ldap/ldap/v3"
+	"github.com/sp00nznet/octopus/internal/config"
+)
+
+// ldapSearchPageSize bounds each page of a paged AD search so large
+// directories don't require buffering the whole result set at once.
+const ldapSearchPageSize = 1000
+
+// ldapMatchingRuleInChain is the AD LDAP_MATCHING_RULE_IN_CHAIN OID, used to
+// resolve nested group membership in a single search instead of relying on
+// the target user's flat memberOf attribute.
+const ldapMatchingRuleInChain = "1.2.840.113556.1.4.1941"
+
+// ldapProvider authenticates against Active Directory via a simple bind. It
+// pools bound service-account connections in p.pool so each login doesn't
+// have to reopen a TCP+TLS+bind sequence just to search for the user's DN.
+type ldapProvider struct {
+	cfg  *config.Config
+	pool sync.Pool
+}
+
+func newLDAPProvider(cfg *config.Config) *ldapProvider {
+	return &ldapProvider{cfg: cfg}
+}
+
+func (p *ldapProvider) Name() string { return "ldap" }
+
+// adURL returns the configured LDAP URL (ldap:// or ldaps://, with port),
+// falling back to the legacy ldap://ADServer:389 form for existing configs.
+func (p *ldapProvider) adURL() string {
+	if p.cfg.ADURL != "" {
+		return p.cfg.ADURL
+	}
+	return fmt.Sprintf("ldap://%s:389", p.cfg.ADServer)
+}
+
+func (p *ldapProvider) tlsConfig() *tls.Config {
+	tlsConfig := &tls.Config{InsecureSkipVerify: p.cfg.ADInsecureSkipVerify}
+
+	if p.cfg.ADCACertPath == "" {
+		return tlsConfig
+	}
+	pemBytes, err := os.ReadFile(p.cfg.ADCACertPath)
+	if err != nil {
+		log.Printf("auth: failed to read AD CA bundle %q: %v", p.cfg.ADCACertPath, err)
+		return tlsConfig
+	}
+	pool := x509.NewCertPool()
+	if pool.AppendCertsFromPEM(pemBytes) {
+		tlsConfig.RootCAs = pool
+	}
+	return tlsConfig
+}
+
+// dialConn opens a new connection to AD, upgrading with StartTLS first when
+// configured.
+func (p *ldapProvider) dialConn() (*ldap.Conn, error) {
+	conn, err := ldap.DialURL(p.adURL(), ldap.DialWithTLSConfig(p.tlsConfig()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to AD: %w", err)
+	}
+
+	if p.cfg.ADStartTLS {
+		if err := conn.StartTLS(p.tlsConfig()); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("failed to start TLS to AD: %w", err)
+		}
+	}
+
+	return conn, nil
+}
+
+// dialServiceConn opens a new connection and binds it as the service
+// account, for use from the pool.
+func (p *ldapProvider) dialServiceConn() (*ldap.Conn, error) {
+	conn, err := p.dialConn()
+	if err != nil {
+		return nil, err
+	}
+	bindPass, err := p.cfg.Resolver.Resolve(context.Background(), p.cfg.ADBindPass)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to resolve AD bind password: %w", err)
+	}
+
+	if err := conn.Bind(p.cfg.ADBindUser, bindPass); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to bind to AD: %w", err)
+	}
+	return conn, nil
+}
+
+// getServiceConn fetches a pooled, bound service-account connection,
+// dialing a fresh one if the pool is empty or the pooled connection has
+// gone stale.
+func (p *ldapProvider) getServiceConn() (*ldap.Conn, error) {
+	if v := p.pool.Get(); v != nil {
+		if conn, ok := v.(*ldap.Conn); ok && !conn.IsClosing() {
+			return conn, nil
+		}
+	}
+	return p.dialServiceConn()
+}
+
+func (p *ldapProvider) putServiceConn(conn *ldap.Conn) {
+	if conn.IsClosing() {
+		return
+	}
+	p.pool.Put(conn)
+}
+
+func (p *ldapProvider) Authenticate(username, password string) (*User, error) {
+	conn, err := p.getServiceConn()
+	if err != nil {
+		return nil, err
+	}
+	defer p.putServiceConn(conn)
+
+	searchFilter := fmt.Sprintf("(&(objectClass=user)(sAMAccountName=%s))", ldap.EscapeFilter(username))
+	searchRequest := ldap.NewSearchRequest(
+		p.cfg.ADBaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0, 0, false,
+		searchFilter,
+		[]string{"dn", "cn", "mail", "memberOf"},
+		nil,
+	)
+
+	result, err := conn.SearchWithPaging(searchRequest, ldapSearchPageSize)
+	if err != nil {
+		return nil, fmt.Errorf("AD search failed: %w", err)
+	}
+
+	if len(result.Entries) == 0 {
+		return nil, fmt.Errorf("user not found")
+	}
+
+	userDN := result.Entries[0].DN
+	displayName := result.Entries[0].GetAttributeValue("cn")
+	email := result.Entries[0].GetAttributeValue("mail")
+	memberOf := result.Entries[0].GetAttributeValues("memberOf")
+
+	// Verify credentials on a separate connection so a bad password doesn't
+	// tear down the pooled service-account bind.
+	userConn, err := p.dialConn()
+	if err != nil {
+		return nil, err
+	}
+	defer userConn.Close()
+	if err := userConn.Bind(userDN, password); err != nil {
+		return nil, fmt.Errorf("invalid credentials")
+	}
+
+	adminGroups := p.cfg.AdminGroups
+	if len(adminGroups) == 0 {
+		adminGroups = []string{"Domain Admins", "Octopus Admins", "Administrators"}
+	}
+
+	isAdmin := false
+	for _, group := range memberOf {
+		if isAdminGroup(group, adminGroups) {
+			isAdmin = true
+			break
+		}
+	}
+
+	if !isAdmin {
+		nested, err := p.resolveNestedAdminGroup(conn, userDN, adminGroups)
+		if err != nil {
+			log.Printf("auth: nested group resolution failed for %s: %v", username, err)
+		} else {
+			isAdmin = nested
+		}
+	}
+
+	return &User{
+		Username:    username,
+		DisplayName: displayName,
+		Email:       email,
+		IsAdmin:     isAdmin,
+	}, nil
+}
+
+// resolveNestedAdminGroup issues a LDAP_MATCHING_RULE_IN_CHAIN search to
+// find admin groups userDN belongs to transitively, catching membership the
+// flat memberOf scan misses when it's inherited through a nested group.
+func (p *ldapProvider) resolveNestedAdminGroup(conn *ldap.Conn, userDN string, adminGroups []string) (bool, error) {
+	searchFilter := fmt.Sprintf("(member:%s:=%s)", ldapMatchingRuleInChain, ldap.EscapeFilter(userDN))
+	searchRequest := ldap.NewSearchRequest(
+		p.cfg.ADBaseDN,
+		ldap.ScopeWholeSubtree,
+		ldap.NeverDerefAliases,
+		0, 0, false,
+		searchFilter,
+		[]string{"dn", "cn"},
+		nil,
+	)
+
+	result, err := conn.SearchWithPaging(searchRequest, ldapSearchPageSize)
+	if err != nil {
+		return false, fmt.Errorf("nested group search failed: %w", err)
+	}
+
+	for _, entry := range result.Entries {
+		if isAdminGroup(entry.DN, adminGroups) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// localProvider accepts dev-only credentials and is used when no AD server
+// is configured.
+type localProvider struct{}
+
+func newLocalProvider() *localProvider {
+	return &localProvider{}
+}
+
+func (p *localProvider) Name() string { return "local" }
+
+func (p *localProvider) Authenticate(username, password string) (*User, error) {
+	if username == "admin" && password == "admin" {
+		return &User{
+			Username:    "admin",
+			DisplayName: "Administrator",
+			Email:       "admin@localhost",
+			IsAdmin:     true,
+		}, nil
+	}
+
+	if username == password && username != "" {
+		return &User{
+			Username:    username,
+			DisplayName: username,
+			Email:       username + "@localhost",
+			IsAdmin:     false,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("invalid credentials")
+}
@@ -0,0 +1,95 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTokenNotFound is returned by TokenStore.GetRefresh when no refresh
+// token record exists for the given ID (never issued, already redeemed, or
+// deleted on logout).
+var ErrTokenNotFound = errors.New("token not found")
+
+// RefreshToken is a persisted refresh token record. The user fields are
+// denormalized so Refresh can mint a new access token without re-querying
+// the provider that originally authenticated the user.
+type RefreshToken struct {
+	ID          string
+	Username    string
+	DisplayName string
+	Email       string
+	IsAdmin     bool
+	ExpiresAt   time.Time
+}
+
+// TokenStore holds refresh tokens and revoked access-token IDs. The BoltDB
+// implementation keeps them across process restarts; BoltDB locks its file,
+// so only one process can have a given store open at a time.
+type TokenStore interface {
+	SaveRefresh(rt *RefreshToken) error
+	GetRefresh(tokenID string) (*RefreshToken, error)
+	DeleteRefresh(tokenID string) error
+	Revoke(jti string, expiresAt time.Time) error
+	IsRevoked(jti string) (bool, error)
+}
+
+// memoryTokenStore is an in-process TokenStore used when cfg.TokenStorePath
+// is empty. Refresh tokens and revocations are lost on restart.
+type memoryTokenStore struct {
+	mu      sync.Mutex
+	refresh map[string]*RefreshToken
+	revoked map[string]time.Time
+}
+
+func newMemoryTokenStore() *memoryTokenStore {
+	return &memoryTokenStore{
+		refresh: make(map[string]*RefreshToken),
+		revoked: make(map[string]time.Time),
+	}
+}
+
+func (s *memoryTokenStore) SaveRefresh(rt *RefreshToken) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.refresh[rt.ID] = rt
+	return nil
+}
+
+func (s *memoryTokenStore) GetRefresh(tokenID string) (*RefreshToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	rt, ok := s.refresh[tokenID]
+	if !ok {
+		return nil, ErrTokenNotFound
+	}
+	return rt, nil
+}
+
+func (s *memoryTokenStore) DeleteRefresh(tokenID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.refresh, tokenID)
+	return nil
+}
+
+func (s *memoryTokenStore) Revoke(jti string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.revoked[jti] = expiresAt
+	return nil
+}
+
+func (s *memoryTokenStore) IsRevoked(jti string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	expiresAt, ok := s.revoked[jti]
+	if !ok {
+		return false, nil
+	}
+	if time.Now().After(expiresAt) {
+		delete(s.revoked, jti)
+		return false, nil
+	}
+	return true, nil
+}
@@ -0,0 +1,138 @@
+// Package bundle implements a versioned, portable container format for
+// moving source/target environments, VMs, and migration jobs between
+// octopus instances (dev to prod, DR site rebuild). Each record carries a
+// type tag and a SHA-256 checksum of its binary-marshaled contents, similar
+// to how InfluxDB's RetentionPolicyInfo.MarshalBinary exposes cluster
+// metadata for portability.
+package bundle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"time"
+)
+
+// magic identifies an octopus export bundle before the gob stream.
+const magic = "OCTOBNDL"
+
+// SchemaVersion is bumped whenever the record types or container layout
+// change incompatibly. Import refuses to load a bundle with a different
+// version.
+const SchemaVersion = 1
+
+// Record type tags, matching the db types with MarshalBinary/UnmarshalBinary
+// methods.
+const (
+	TypeSourceEnvironment = "source_environment"
+	TypeTargetEnvironment = "target_environment"
+	TypeVM                = "vm"
+	TypeMigrationJob      = "migration_job"
+)
+
+// Manifest describes the contents of a Bundle.
+type Manifest struct {
+	SchemaVersion int
+	CreatedAt     time.Time
+	Include       []string
+	RecordCounts  map[string]int
+}
+
+// Record is one binary-marshaled db row plus a checksum of its Data.
+type Record struct {
+	Type     string
+	Checksum [32]byte
+	Data     []byte
+}
+
+// NewRecord marshals v and computes its checksum.
+func NewRecord(typ string, v encoding.BinaryMarshaler) (Record, error) {
+	data, err := v.MarshalBinary()
+	if err != nil {
+		return Record{}, fmt.Errorf("marshal %s: %w", typ, err)
+	}
+	return Record{Type: typ, Checksum: sha256.Sum256(data), Data: data}, nil
+}
+
+// Decode verifies the record's checksum and unmarshals it into v.
+func (r Record) Decode(v encoding.BinaryUnmarshaler) error {
+	if sha256.Sum256(r.Data) != r.Checksum {
+		return fmt.Errorf("checksum mismatch for %s record", r.Type)
+	}
+	return v.UnmarshalBinary(r.Data)
+}
+
+// Bundle is the full set of records exported in one call, along with the
+// manifest header describing them.
+type Bundle struct {
+	Manifest Manifest
+	Records  []Record
+}
+
+// Add appends a new record of type typ built from v to the bundle.
+func (b *Bundle) Add(typ string, v encoding.BinaryMarshaler) error {
+	rec, err := NewRecord(typ, v)
+	if err != nil {
+		return err
+	}
+	b.Records = append(b.Records, rec)
+	b.Manifest.RecordCounts[typ]++
+	return nil
+}
+
+// Write serializes b to w as a magic header followed by a gob-encoded
+// Manifest and Records.
+func Write(w io.Writer, b *Bundle) error {
+	if _, err := w.Write([]byte(magic)); err != nil {
+		return err
+	}
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(b.Manifest); err != nil {
+		return fmt.Errorf("encode manifest: %w", err)
+	}
+	if err := enc.Encode(b.Records); err != nil {
+		return fmt.Errorf("encode records: %w", err)
+	}
+	return nil
+}
+
+// Read parses a Bundle written by Write, validating the magic header and
+// schema version.
+func Read(r io.Reader) (*Bundle, error) {
+	got := make([]byte, len(magic))
+	if _, err := io.ReadFull(r, got); err != nil {
+		return nil, fmt.Errorf("read magic header: %w", err)
+	}
+	if !bytes.Equal(got, []byte(magic)) {
+		return nil, fmt.Errorf("not an octopus export bundle")
+	}
+
+	b := &Bundle{}
+	dec := gob.NewDecoder(r)
+	if err := dec.Decode(&b.Manifest); err != nil {
+		return nil, fmt.Errorf("decode manifest: %w", err)
+	}
+	if b.Manifest.SchemaVersion != SchemaVersion {
+		return nil, fmt.Errorf("unsupported bundle schema version %d (expected %d)", b.Manifest.SchemaVersion, SchemaVersion)
+	}
+	if err := dec.Decode(&b.Records); err != nil {
+		return nil, fmt.Errorf("decode records: %w", err)
+	}
+
+	return b, nil
+}
+
+// NewBundle creates an empty Bundle for the given include set.
+func NewBundle(include []string) *Bundle {
+	return &Bundle{
+		Manifest: Manifest{
+			SchemaVersion: SchemaVersion,
+			CreatedAt:     time.Now(),
+			Include:       include,
+			RecordCounts:  make(map[string]int),
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package bundle
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"io"
+)
+
+// WrapCredential encrypts plaintext (e.g. a source environment password)
+// with AES-256-GCM under a key derived from passphrase, so it travels
+// inside a bundle instead of in the clear. The nonce is prepended to the
+// ciphertext and the whole thing is base64-encoded for safe embedding in a
+// gob-encoded string field.
+func WrapCredential(passphrase, plaintext string) (string, error) {
+	gcm, err := newGCM(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(ciphertext), nil
+}
+
+// UnwrapCredential reverses WrapCredential, decrypting with the same
+// passphrase used to wrap it - typically supplied by the operator performing
+// the import, since it may differ from the destination's own secrets.
+func UnwrapCredential(passphrase, wrapped string) (string, error) {
+	gcm, err := newGCM(passphrase)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := base64.StdEncoding.DecodeString(wrapped)
+	if err != nil {
+		return "", fmt.Errorf("decode wrapped credential: %w", err)
+	}
+
+	nonceSize := gcm.NonceSize()
+	if len(raw) < nonceSize {
+		return "", fmt.Errorf("wrapped credential too short")
+	}
+	nonce, ciphertext := raw[:nonceSize], raw[nonceSize:]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", fmt.Errorf("decrypt wrapped credential: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+func newGCM(passphrase string) (cipher.AEAD, error) {
+	key := sha256.Sum256([]byte(passphrase))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,166 @@
+// Package cloud defines a provider-agnostic interface over the per-cloud
+// clients in internal/providers, so callers like internal/sync can pick a
+// driver by name (e.g. from a migration job's target_type) instead of
+// hardcoding a type switch across aws/azure/gcp/vmware. Each provider
+// package registers a Factory for itself from an init() function, the
+// same self-registration pattern used by Go's database/sql drivers.
+package cloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+)
+
+// ImageSpec describes a machine image to create from a migrated artifact
+// (an uploaded VHD, a GCS object, an S3-hosted disk, ...).
+type ImageSpec struct {
+	Name        string
+	ArtifactURI string
+	Description string
+	OSType      string
+}
+
+// InstanceSpec describes an instance to launch from a previously created
+// image.
+type InstanceSpec struct {
+	Name          string
+	ImageName     string
+	MachineType   string
+	Network       string
+	Subnet        string
+	SecurityGroup string
+	AdminUsername string
+	AdminPassword string
+	PreserveMAC   bool
+}
+
+// SnapshotSpec describes a point-in-time snapshot of an existing resource
+// (an EBS volume, a managed disk, a running VM, ...).
+type SnapshotSpec struct {
+	ResourceID  string
+	Name        string
+	Description string
+	// Memory and Quiesce only apply to drivers that snapshot a running
+	// VM directly (e.g. vmware); disk/volume-level drivers ignore them.
+	Memory  bool
+	Quiesce bool
+}
+
+// InstanceInfo is a provider-agnostic view of a running instance. Metadata
+// carries whatever provider-specific detail GetInstanceInfo returned, for
+// callers that need more than the common fields.
+type InstanceInfo struct {
+	ID       string
+	Name     string
+	Status   string
+	Metadata map[string]interface{}
+}
+
+// Provider is the common surface every cloud driver implements, wrapping
+// that provider's own client (aws.Client, azure.Client, gcp.Client, ...).
+type Provider interface {
+	// Name is the driver name it was registered under, e.g. "aws".
+	Name() string
+	// CreateImageFromArtifact imports a migrated disk artifact as a
+	// bootable machine image, returning the provider-specific image ID.
+	CreateImageFromArtifact(spec ImageSpec) (string, error)
+	// CreateInstance launches an instance from a previously created
+	// image, returning the provider-specific instance ID.
+	CreateInstance(spec InstanceSpec) (string, error)
+	// Start powers on a stopped instance.
+	Start(instanceID string) error
+	// Stop powers off a running instance.
+	Stop(instanceID string) error
+	// Snapshot creates a point-in-time snapshot of a resource, returning
+	// the provider-specific snapshot ID.
+	Snapshot(spec SnapshotSpec) (string, error)
+	// GetInstanceInfo returns the current state of an instance.
+	GetInstanceInfo(instanceID string) (InstanceInfo, error)
+	// EstimateMachineType suggests a provider-specific machine/instance
+	// type sized for cpuCount and memoryGB.
+	EstimateMachineType(cpuCount int, memoryGB float64) string
+}
+
+// SnapshotDeleter is implemented by Provider drivers that can remove a
+// previously created snapshot. Callers type-assert a Provider for it the
+// same way they do for FlavorAware - not every driver supports snapshot
+// deletion yet (see each provider's cloud_provider.go).
+type SnapshotDeleter interface {
+	// DeleteSnapshot removes snapshotID (the ID Snapshot returned) from
+	// resourceID (the same resource it was taken of).
+	DeleteSnapshot(resourceID, snapshotID string) error
+}
+
+// TargetActions is implemented by Provider drivers that support admin
+// lifecycle operations on an already-launched instance, beyond the plain
+// Start/Stop every Provider exposes. Callers type-assert a Provider for it
+// the same way they do for SnapshotDeleter - not every driver implements it
+// yet (today, only aws does).
+type TargetActions interface {
+	// StartVM powers on a stopped instance.
+	StartVM(ctx context.Context, instanceID string) error
+	// StopVM powers off a running instance. force skips the graceful
+	// in-guest shutdown a plain Stop attempts first.
+	StopVM(ctx context.Context, instanceID string, force bool) error
+	// ResizeVM changes instanceID's machine type, stopping and restarting
+	// it if the driver requires that.
+	ResizeVM(ctx context.Context, instanceID, newInstanceType string) error
+	// ListValidResizeTargets lists the machine types instanceID could be
+	// resized to - e.g. ones available in its current location with a
+	// compatible processor architecture.
+	ListValidResizeTargets(ctx context.Context, instanceID string) ([]string, error)
+}
+
+// Factory builds a Provider from its driver-specific options, typically a
+// YAML/JSON-decoded struct the provider package defines for itself (e.g.
+// gcp.Options).
+type Factory func(options json.RawMessage) (Provider, error)
+
+var (
+	mu        sync.RWMutex
+	factories = make(map[string]Factory)
+)
+
+// Register adds or replaces the factory for driver name. Called from each
+// provider package's init(), mirroring database/sql.Register.
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+	factories[name] = factory
+}
+
+// New builds the Provider registered under name, decoding options with
+// whatever struct that driver's Factory expects. options may be nil for
+// drivers that need no configuration.
+func New(name string, options json.RawMessage) (Provider, error) {
+	mu.RLock()
+	factory, ok := factories[name]
+	mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("cloud: no driver registered for %q", name)
+	}
+	return factory(options)
+}
+
+// Registered reports whether a driver has been registered under name,
+// without constructing it - useful for validating a target_type before
+// committing to a migration job.
+func Registered(name string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	_, ok := factories[name]
+	return ok
+}
+
+// Drivers lists every registered driver name.
+func Drivers() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+	names := make([]string, 0, len(factories))
+	for name := range factories {
+		names = append(names, name)
+	}
+	return names
+}
@@ -0,0 +1,109 @@
+// Package cloudtest provides an in-memory cloud.Provider that registers
+// itself as the "test" driver, so scheduler and API logic that selects a
+// driver by name can be exercised without a real AWS/Azure/GCP/vCenter
+// backend.
+package cloudtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/sp00nznet/octopus/internal/cloud"
+)
+
+func init() {
+	cloud.Register("test", newProvider)
+}
+
+func newProvider(options json.RawMessage) (cloud.Provider, error) {
+	return NewProvider(), nil
+}
+
+// Instance is a fake instance tracked by Provider.
+type Instance struct {
+	ID     string
+	Status string
+}
+
+// Provider is a fake cloud.Provider backed by in-memory maps instead of a
+// real cloud API. Exported so callers that don't want the "test" registry
+// name (e.g. to run two independent fakes side by side) can construct one
+// directly with NewProvider.
+type Provider struct {
+	mu        sync.Mutex
+	images    map[string]cloud.ImageSpec
+	instances map[string]*Instance
+	snapshots map[string]cloud.SnapshotSpec
+	nextID    int
+}
+
+// NewProvider creates an empty fake Provider.
+func NewProvider() *Provider {
+	return &Provider{
+		images:    make(map[string]cloud.ImageSpec),
+		instances: make(map[string]*Instance),
+		snapshots: make(map[string]cloud.SnapshotSpec),
+	}
+}
+
+func (p *Provider) Name() string { return "test" }
+
+func (p *Provider) CreateImageFromArtifact(spec cloud.ImageSpec) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.images[spec.Name] = spec
+	return spec.Name, nil
+}
+
+func (p *Provider) CreateInstance(spec cloud.InstanceSpec) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if _, ok := p.images[spec.ImageName]; !ok {
+		return "", fmt.Errorf("cloudtest: no image named %q", spec.ImageName)
+	}
+	p.nextID++
+	id := fmt.Sprintf("test-instance-%d", p.nextID)
+	p.instances[id] = &Instance{ID: id, Status: "running"}
+	return id, nil
+}
+
+func (p *Provider) Start(instanceID string) error {
+	return p.setStatus(instanceID, "running")
+}
+
+func (p *Provider) Stop(instanceID string) error {
+	return p.setStatus(instanceID, "stopped")
+}
+
+func (p *Provider) setStatus(instanceID, status string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	inst, ok := p.instances[instanceID]
+	if !ok {
+		return fmt.Errorf("cloudtest: no instance %q", instanceID)
+	}
+	inst.Status = status
+	return nil
+}
+
+func (p *Provider) Snapshot(spec cloud.SnapshotSpec) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.snapshots[spec.Name] = spec
+	return spec.Name, nil
+}
+
+func (p *Provider) GetInstanceInfo(instanceID string) (cloud.InstanceInfo, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	inst, ok := p.instances[instanceID]
+	if !ok {
+		return cloud.InstanceInfo{}, fmt.Errorf("cloudtest: no instance %q", instanceID)
+	}
+	return cloud.InstanceInfo{ID: inst.ID, Name: inst.ID, Status: inst.Status}, nil
+}
+
+func (p *Provider) EstimateMachineType(cpuCount int, memoryGB float64) string {
+	return fmt.Sprintf("test-%dcpu-%.0fgb", cpuCount, memoryGB)
+}
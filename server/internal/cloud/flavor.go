@@ -0,0 +1,249 @@
+package cloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FlavorRequest describes the shape a cutover target instance needs to
+// satisfy, typically derived from a migrated VM's current CPU/memory and
+// whatever extra hardware (GPU, local SSD) it depends on.
+type FlavorRequest struct {
+	CPU      int
+	MemoryGB float64
+	GPU      bool
+	LocalSSD bool
+	Arch     string // "x86_64" or "arm64"; empty means "x86_64"
+	Region   string // provider-specific region/zone candidates must be available in
+}
+
+// FlavorCandidate is one provider-specific instance type/flavor, along with
+// enough detail to filter and rank it against a FlavorRequest.
+type FlavorCandidate struct {
+	Name        string
+	CPU         int
+	MemoryGB    float64
+	GPU         bool
+	LocalSSD    bool
+	Arch        string
+	Region      string
+	HourlyCost  float64
+	Unavailable bool // true if the provider's catalog reports it out of stock in Region
+}
+
+// OvercommitPolicy controls how generously ResolveFlavor rounds a
+// FlavorRequest up to a candidate. The zero value is not usable; callers
+// should start from DefaultOvercommitPolicy.
+type OvercommitPolicy struct {
+	// RoundCPUUp requires a candidate to have at least FlavorRequest.CPU
+	// vCPUs - no undersizing on CPU, since that's usually what causes a
+	// migrated workload to regress in production.
+	RoundCPUUp bool
+	// MemoryFactor is the minimum multiple of FlavorRequest.MemoryGB a
+	// candidate's memory must meet (e.g. 1.1 requires 10% headroom).
+	MemoryFactor float64
+}
+
+// DefaultOvercommitPolicy rounds CPU up to the next available size and
+// requires 10% memory headroom over the source VM, matching the sizing
+// guidance openstack-cpi-golang's flavor resolver defaults to.
+var DefaultOvercommitPolicy = OvercommitPolicy{
+	RoundCPUUp:   true,
+	MemoryFactor: 1.1,
+}
+
+// FlavorResolver queries a provider's live instance-type/flavor catalog.
+// Each provider package that supports cutover (gcp today; aws/azure are not
+// implemented yet, see their flavor.go files) implements this against its
+// own client.
+type FlavorResolver interface {
+	// ListCandidates returns every flavor available to req.Region, without
+	// filtering against req's CPU/memory/GPU requirements - that's
+	// FilterCandidates' job, kept separate so callers can inspect or cache
+	// the full catalog.
+	ListCandidates(req FlavorRequest) ([]FlavorCandidate, error)
+}
+
+// FlavorAware is implemented by Provider drivers that support live flavor
+// resolution. Callers type-assert a Provider for it the same way net/http
+// callers assert a ResponseWriter for http.Flusher - most Providers don't
+// implement it yet.
+type FlavorAware interface {
+	FlavorResolver() FlavorResolver
+}
+
+// FilterCandidates returns the candidates from all that satisfy req under
+// policy: available in req.Region, matching Arch/GPU/LocalSSD, CPU at least
+// req.CPU, and memory at least req.MemoryGB*policy.MemoryFactor.
+func FilterCandidates(all []FlavorCandidate, req FlavorRequest, policy OvercommitPolicy) []FlavorCandidate {
+	arch := req.Arch
+	if arch == "" {
+		arch = "x86_64"
+	}
+
+	var out []FlavorCandidate
+	for _, c := range all {
+		if c.Unavailable {
+			continue
+		}
+		if req.Region != "" && c.Region != "" && c.Region != req.Region {
+			continue
+		}
+		if c.Arch != "" && c.Arch != arch {
+			continue
+		}
+		if req.GPU && !c.GPU {
+			continue
+		}
+		if req.LocalSSD && !c.LocalSSD {
+			continue
+		}
+		if policy.RoundCPUUp && c.CPU < req.CPU {
+			continue
+		}
+		if c.MemoryGB < req.MemoryGB*policy.MemoryFactor {
+			continue
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+// RankCandidates orders candidates cheapest-first, breaking ties by whichever
+// candidate overshoots req's CPU and memory by the smallest margin - the
+// closest fit to what was asked for, not just the cheapest shape available.
+func RankCandidates(candidates []FlavorCandidate, req FlavorRequest) []FlavorCandidate {
+	ranked := make([]FlavorCandidate, len(candidates))
+	copy(ranked, candidates)
+
+	overshoot := func(c FlavorCandidate) float64 {
+		cpuOver := float64(c.CPU - req.CPU)
+		memOver := c.MemoryGB - req.MemoryGB
+		return cpuOver + memOver
+	}
+
+	for i := 1; i < len(ranked); i++ {
+		j := i
+		for j > 0 {
+			a, b := ranked[j-1], ranked[j]
+			swap := a.HourlyCost > b.HourlyCost ||
+				(a.HourlyCost == b.HourlyCost && overshoot(a) > overshoot(b))
+			if !swap {
+				break
+			}
+			ranked[j-1], ranked[j] = ranked[j], ranked[j-1]
+			j--
+		}
+	}
+	return ranked
+}
+
+// Rationale formats why a candidate was chosen for req, suitable for
+// recording in activity_logs alongside the migration job it was resolved
+// for.
+func Rationale(c FlavorCandidate, req FlavorRequest, policy OvercommitPolicy) string {
+	return fmt.Sprintf(
+		"picked %s (%d vCPU, %.1fGB RAM, $%.4f/hr) for request of %d vCPU / %.1fGB RAM "+
+			"(overcommit: CPU rounded up=%t, memory factor=%.2fx)",
+		c.Name, c.CPU, c.MemoryGB, c.HourlyCost, req.CPU, req.MemoryGB, policy.RoundCPUUp, policy.MemoryFactor,
+	)
+}
+
+// ResolveFlavor fetches resolver's catalog (through cache, if provided),
+// filters it against req under policy, and returns the cheapest matching
+// candidate along with a human-readable rationale for why it was chosen.
+func ResolveFlavor(resolver FlavorResolver, cache *FlavorCache, req FlavorRequest, policy OvercommitPolicy) (FlavorCandidate, string, error) {
+	var (
+		all []FlavorCandidate
+		err error
+	)
+
+	cacheKey := req.Region
+	if cache != nil {
+		if cached, ok := cache.Get(cacheKey); ok {
+			all = cached
+		}
+	}
+
+	if all == nil {
+		all, err = resolver.ListCandidates(req)
+		if err != nil {
+			return FlavorCandidate{}, "", fmt.Errorf("cloud: list flavor candidates: %w", err)
+		}
+		if cache != nil {
+			if err := cache.Set(cacheKey, all); err != nil {
+				// Cache writes are best-effort - a cold/unwritable cache
+				// dir shouldn't block a flavor decision.
+				fmt.Fprintf(os.Stderr, "cloud: failed to write flavor cache: %v\n", err)
+			}
+		}
+	}
+
+	filtered := FilterCandidates(all, req, policy)
+	if len(filtered) == 0 {
+		return FlavorCandidate{}, "", fmt.Errorf("cloud: no flavor in %q satisfies %d vCPU / %.1fGB RAM", req.Region, req.CPU, req.MemoryGB)
+	}
+
+	ranked := RankCandidates(filtered, req)
+	chosen := ranked[0]
+	return chosen, Rationale(chosen, req, policy), nil
+}
+
+// FlavorCache is an on-disk JSON cache of a provider's flavor catalog, keyed
+// by region, so a dry-run estimate or a real cutover doesn't re-list the
+// provider's entire instance-type catalog on every call.
+type FlavorCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewFlavorCache creates a FlavorCache rooted at dir, with entries expiring
+// after ttl. dir is created on first write if it doesn't already exist.
+func NewFlavorCache(dir string, ttl time.Duration) *FlavorCache {
+	return &FlavorCache{dir: dir, ttl: ttl}
+}
+
+type flavorCacheEntry struct {
+	CachedAt   time.Time         `json:"cached_at"`
+	Candidates []FlavorCandidate `json:"candidates"`
+}
+
+func (fc *FlavorCache) path(key string) string {
+	return filepath.Join(fc.dir, "flavors_"+key+".json")
+}
+
+// Get returns the cached candidates for key, or ok=false if there's no
+// cache entry or it's older than ttl.
+func (fc *FlavorCache) Get(key string) (candidates []FlavorCandidate, ok bool) {
+	data, err := os.ReadFile(fc.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var entry flavorCacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if time.Since(entry.CachedAt) > fc.ttl {
+		return nil, false
+	}
+	return entry.Candidates, true
+}
+
+// Set writes candidates to the cache under key.
+func (fc *FlavorCache) Set(key string, candidates []FlavorCandidate) error {
+	if err := os.MkdirAll(fc.dir, 0o755); err != nil {
+		return fmt.Errorf("cloud: create flavor cache dir: %w", err)
+	}
+	entry := flavorCacheEntry{CachedAt: time.Now(), Candidates: candidates}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("cloud: marshal flavor cache entry: %w", err)
+	}
+	if err := os.WriteFile(fc.path(key), data, 0o644); err != nil {
+		return fmt.Errorf("cloud: write flavor cache entry: %w", err)
+	}
+	return nil
+}
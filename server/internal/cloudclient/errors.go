@@ -0,0 +1,89 @@
+package cloudclient
+
+import (
+	"errors"
+	"strings"
+	"time"
+)
+
+// ErrorClass categorizes a cloud API error so RateLimitedClient.Do knows
+// whether to retry it and, if so, how long to wait.
+type ErrorClass int
+
+const (
+	// ClassFatal errors are never retried (bad request, auth failure, not
+	// found, ...).
+	ClassFatal ErrorClass = iota
+	// ClassTransient errors (connection reset, timeout, 5xx) are retried
+	// with the normal exponential backoff.
+	ClassTransient
+	// ClassThrottling errors are the provider explicitly asking the caller
+	// to slow down, usually with a Retry-After hint (AWS RequestLimitExceeded,
+	// GCP quota exceeded, Azure 429).
+	ClassThrottling
+	// ClassRateLimit is a local rate-limit rejection (this process's own
+	// token bucket is empty) rather than something the provider returned -
+	// distinguished from ClassThrottling so metrics can tell "we backed off
+	// preemptively" apart from "the provider rejected us".
+	ClassRateLimit
+)
+
+// ClassifiedError wraps a cloud SDK error with the ErrorClass Do needs to
+// decide whether and how long to wait before retrying, mirroring the
+// Retry-After-aware throttling errors Azure's vmclient returns from
+// GetThrottlingError.
+type ClassifiedError struct {
+	Class      ErrorClass
+	RetryAfter time.Duration // only meaningful for ClassThrottling
+	Err        error
+}
+
+func (e *ClassifiedError) Error() string { return e.Err.Error() }
+func (e *ClassifiedError) Unwrap() error { return e.Err }
+
+// Classifier decides the ErrorClass of an error returned by a cloud SDK
+// call. Each provider package supplies its own, since AWS/GCP/Azure each
+// surface throttling differently; DefaultClassifier is a conservative
+// string-matching fallback for providers that haven't written one yet.
+type Classifier func(err error) *ClassifiedError
+
+// DefaultClassifier recognizes the throttling/rate-limit vocabulary common
+// to AWS, GCP, and Azure SDK errors by substring match, since none of them
+// share a typed error this package can assert against generically. Provider
+// packages with access to the real SDK error types (e.g. a *smithy.APIError)
+// should classify more precisely than this.
+func DefaultClassifier(err error) *ClassifiedError {
+	if err == nil {
+		return nil
+	}
+
+	msg := strings.ToLower(err.Error())
+	switch {
+	case containsAny(msg, "requestlimitexceeded", "throttl", "slow down", "too many requests", "429", "quota exceeded", "rate exceeded"):
+		return &ClassifiedError{Class: ClassThrottling, RetryAfter: 0, Err: err}
+	case containsAny(msg, "timeout", "timed out", "connection reset", "eof", "temporary failure", "503", "502", "internal error"):
+		return &ClassifiedError{Class: ClassTransient, Err: err}
+	default:
+		return &ClassifiedError{Class: ClassFatal, Err: err}
+	}
+}
+
+func containsAny(s string, substrs ...string) bool {
+	for _, sub := range substrs {
+		if strings.Contains(s, sub) {
+			return true
+		}
+	}
+	return false
+}
+
+// AsClassifiedError unwraps err looking for a *ClassifiedError, so callers
+// that need the class/RetryAfter after Do returns (e.g. for logging) don't
+// have to re-classify.
+func AsClassifiedError(err error) (*ClassifiedError, bool) {
+	var ce *ClassifiedError
+	if errors.As(err, &ce) {
+		return ce, true
+	}
+	return nil, false
+}
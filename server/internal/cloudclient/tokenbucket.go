@@ -0,0 +1,61 @@
+package cloudclient
+
+import (
+	"sync"
+	"time"
+)
+
+// tokenBucket is a simple hand-rolled token-bucket rate limiter: it refills
+// at ratePerSecond, up to burst capacity, and take blocks until a token is
+// available. Separate buckets are used for reads vs. writes since cloud
+// APIs almost always rate-limit writes (CreateInstance, Start/Stop,
+// Snapshot) far more aggressively than reads (DescribeInstances, GetStatus).
+type tokenBucket struct {
+	mu         sync.Mutex
+	rate       float64 // tokens added per second
+	burst      float64 // max tokens held
+	tokens     float64
+	lastRefill time.Time
+}
+
+func newTokenBucket(ratePerSecond, burst float64) *tokenBucket {
+	return &tokenBucket{
+		rate:       ratePerSecond,
+		burst:      burst,
+		tokens:     burst,
+		lastRefill: time.Now(),
+	}
+}
+
+// take blocks until a single token is available. Each pass sleeps for the
+// time the bucket needs to refill to one token, then re-checks, since a
+// concurrent caller may have taken that token in the meantime.
+func (b *tokenBucket) take() {
+	for {
+		b.mu.Lock()
+		b.refillLocked(time.Now())
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mu.Unlock()
+			return
+		}
+		wait := time.Duration(float64(time.Second) * (1 - b.tokens) / b.rate)
+		b.mu.Unlock()
+		if wait <= 0 {
+			wait = time.Millisecond
+		}
+		time.Sleep(wait)
+	}
+}
+
+func (b *tokenBucket) refillLocked(now time.Time) {
+	elapsed := now.Sub(b.lastRefill).Seconds()
+	if elapsed <= 0 {
+		return
+	}
+	b.tokens += elapsed * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.lastRefill = now
+}
@@ -0,0 +1,303 @@
+// Package cloudinit builds NoCloud cloud-init seed ISOs: a minimal
+// ISO9660 image containing just "user-data" and "meta-data", volume
+// labeled CIDATA, which is what cloud-init's NoCloud datasource looks for
+// on any attached CD-ROM. There's no ISO9660-writing dependency anywhere
+// in go.mod, and the format needed here is narrow enough (two flat files,
+// no subdirectories) that hand-rolling it is simpler than adding one.
+package cloudinit
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+const (
+	sectorSize = 2048
+
+	// Fixed sector layout for the small, flat image BuildSeedISO produces.
+	pvdSector        = 16
+	vdstSector       = 17
+	pathTableLSector = 18
+	pathTableMSector = 19
+	rootDirSector    = 20
+)
+
+// BuildSeedISO renders userData and metaData into an ISO9660 image with
+// Rock Ridge name extensions (so their original filenames survive, rather
+// than being truncated to 8.3) and volume identifier "CIDATA", ready to
+// upload to a datastore and attach as a VM's CD-ROM.
+func BuildSeedISO(userData, metaData []byte) ([]byte, error) {
+	files := []isoFile{
+		{name: "user-data", data: userData},
+		{name: "meta-data", data: metaData},
+	}
+
+	// Lay out file data starting right after the root directory extent,
+	// each file padded out to a whole number of sectors.
+	nextSector := rootDirSector + 1
+	for i := range files {
+		files[i].lba = nextSector
+		nextSector += sectorsFor(len(files[i].data))
+	}
+	totalSectors := nextSector
+
+	rootDir, err := buildRootDirectory(files)
+	if err != nil {
+		return nil, fmt.Errorf("build root directory: %w", err)
+	}
+	if len(rootDir) > sectorSize {
+		return nil, fmt.Errorf("root directory extent exceeds one sector (%d files)", len(files))
+	}
+
+	img := make([]byte, totalSectors*sectorSize)
+
+	writeSector(img, pvdSector, buildPVD(totalSectors, len(rootDir)))
+	writeSector(img, vdstSector, buildVDST())
+	writeSector(img, pathTableLSector, buildPathTable(false))
+	writeSector(img, pathTableMSector, buildPathTable(true))
+	writeSector(img, rootDirSector, rootDir)
+
+	for _, f := range files {
+		copy(img[f.lba*sectorSize:], f.data)
+	}
+
+	return img, nil
+}
+
+type isoFile struct {
+	name string
+	data []byte
+	lba  int
+}
+
+func sectorsFor(n int) int {
+	return (n + sectorSize - 1) / sectorSize
+}
+
+func writeSector(img []byte, sector int, data []byte) {
+	copy(img[sector*sectorSize:], data)
+}
+
+func le16(v uint16) []byte { return []byte{byte(v), byte(v >> 8)} }
+func be16(v uint16) []byte { return []byte{byte(v >> 8), byte(v)} }
+func le32(v uint32) []byte {
+	return []byte{byte(v), byte(v >> 8), byte(v >> 16), byte(v >> 24)}
+}
+func be32(v uint32) []byte {
+	return []byte{byte(v >> 24), byte(v >> 16), byte(v >> 8), byte(v)}
+}
+
+// bothEndian16/32 encode a field in the "both-endian" form ECMA-119 uses
+// throughout (little-endian immediately followed by big-endian).
+func bothEndian16(v uint16) []byte {
+	return append(le16(v), be16(v)...)
+}
+func bothEndian32(v uint32) []byte {
+	return append(le32(v), be32(v)...)
+}
+
+func padBytes(b []byte, length int, pad byte) []byte {
+	out := make([]byte, length)
+	for i := range out {
+		out[i] = pad
+	}
+	copy(out, b)
+	return out
+}
+
+// unspecifiedDateTime is the 17-byte "not specified" encoding used for the
+// volume descriptor's date/time fields: 16 ASCII '0' digits plus a zero
+// GMT offset byte.
+func unspecifiedDateTime() []byte {
+	b := bytes.Repeat([]byte("0"), 16)
+	return append(b, 0)
+}
+
+// recordingDateTime is the 7-byte directory record date/time. Zero values
+// are acceptable for a freshly generated, single-use seed image.
+func recordingDateTime() []byte {
+	return make([]byte, 7)
+}
+
+func buildPVD(totalSectors int, rootDirLen int) []byte {
+	b := make([]byte, sectorSize)
+	b[0] = 1 // Volume Descriptor Type: Primary
+	copy(b[1:6], "CD001")
+	b[6] = 1 // Version
+
+	copy(b[8:40], padBytes(nil, 32, ' '))
+	copy(b[40:72], padBytes([]byte("CIDATA"), 32, ' '))
+
+	copy(b[80:88], bothEndian32(uint32(totalSectors)))
+	copy(b[120:124], bothEndian16(1)) // Volume Set Size
+	copy(b[124:128], bothEndian16(1)) // Volume Sequence Number
+	copy(b[128:132], bothEndian16(sectorSize))
+
+	pathTableSize := uint32(10) // one record, no padding needed beyond this
+	copy(b[132:140], bothEndian32(pathTableSize))
+	copy(b[140:144], le32(pathTableLSector))
+	copy(b[148:152], be32(pathTableMSector))
+
+	copy(b[156:190], rootDirRecord(rootDirLen))
+
+	copy(b[190:318], padBytes(nil, 128, ' '))
+	copy(b[318:446], padBytes(nil, 128, ' '))
+	copy(b[446:574], padBytes(nil, 128, ' '))
+	copy(b[574:702], padBytes(nil, 128, ' '))
+	copy(b[702:739], padBytes(nil, 37, ' '))
+	copy(b[739:776], padBytes(nil, 37, ' '))
+	copy(b[776:813], padBytes(nil, 37, ' '))
+
+	copy(b[813:830], unspecifiedDateTime())
+	copy(b[830:847], unspecifiedDateTime())
+	copy(b[847:864], unspecifiedDateTime())
+	copy(b[864:881], unspecifiedDateTime())
+
+	b[881] = 1 // File Structure Version
+
+	return b
+}
+
+func buildVDST() []byte {
+	b := make([]byte, sectorSize)
+	b[0] = 255 // Volume Descriptor Set Terminator
+	copy(b[1:6], "CD001")
+	b[6] = 1
+	return b
+}
+
+// buildPathTable renders the (trivial, root-only) path table. bigEndian
+// selects the M (big-endian) variant; the L variant is little-endian.
+func buildPathTable(bigEndian bool) []byte {
+	enc32, enc16 := le32, le16
+	if bigEndian {
+		enc32, enc16 = be32, be16
+	}
+
+	b := make([]byte, sectorSize)
+	rec := make([]byte, 0, 10)
+	rec = append(rec, 1) // Length of Directory Identifier (root = 1)
+	rec = append(rec, 0) // Extended Attribute Record Length
+	rec = append(rec, enc32(rootDirSector)...)
+	rec = append(rec, enc16(1)...) // Parent Directory Number (root is its own parent)
+	rec = append(rec, 0)           // Directory Identifier (root = single 0x00 byte)
+	rec = append(rec, 0)           // padding to keep the record even-length
+
+	copy(b, rec)
+	return b
+}
+
+// rootDirRecord builds the 34-byte directory record describing the root
+// directory itself, as embedded in the Primary Volume Descriptor.
+func rootDirRecord(dataLen int) []byte {
+	return directoryRecord(directoryRecordSpec{
+		id:        string([]byte{0}),
+		extentLBA: rootDirSector,
+		dataLen:   dataLen,
+		isDir:     true,
+	})
+}
+
+type directoryRecordSpec struct {
+	id          string // "." (0x00), ".." (0x01), or an ISO9660 8.3 identifier
+	longName    string // Rock Ridge NM alternate name; empty to omit
+	rockRidgeSP bool   // emit the Rock Ridge "SP" signature (root "." entry only)
+	extentLBA   int
+	dataLen     int
+	isDir       bool
+}
+
+func directoryRecord(spec directoryRecordSpec) []byte {
+	idBytes := []byte(spec.id)
+	idLen := len(idBytes)
+
+	fixed := 33 + idLen
+	if idLen%2 == 0 {
+		fixed++ // padding byte so the identifier field stays even-sized
+	}
+
+	var systemUse []byte
+	if spec.rockRidgeSP {
+		systemUse = append(systemUse, 'S', 'P', 7, 1, 0xBE, 0xEF, 0)
+	}
+	if spec.longName != "" {
+		name := []byte(spec.longName)
+		systemUse = append(systemUse, 'N', 'M', byte(5+len(name)), 1, 0 /* flags */)
+		systemUse = append(systemUse, name...)
+	}
+
+	total := fixed + len(systemUse)
+	if total%2 != 0 {
+		total++ // directory records are padded to an even length
+	}
+
+	b := make([]byte, total)
+	b[0] = byte(total)
+	// b[1] Extended Attribute Record Length = 0
+	copy(b[2:10], bothEndian32(uint32(spec.extentLBA)))
+	copy(b[10:18], bothEndian32(uint32(spec.dataLen)))
+	copy(b[18:25], recordingDateTime())
+	if spec.isDir {
+		b[25] = 2 // File Flags: directory
+	}
+	// b[26] File Unit Size, b[27] Interleave Gap Size = 0
+	copy(b[28:32], bothEndian16(1)) // Volume Sequence Number
+	b[32] = byte(idLen)
+	copy(b[33:33+idLen], idBytes)
+	copy(b[fixed:], systemUse)
+
+	return b
+}
+
+// buildRootDirectory renders the root directory's extent: "." and ".."
+// self-references followed by one record per file.
+func buildRootDirectory(files []isoFile) ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.Write(directoryRecord(directoryRecordSpec{
+		id:          string([]byte{0}),
+		extentLBA:   rootDirSector,
+		dataLen:     sectorSize,
+		isDir:       true,
+		rockRidgeSP: true,
+	}))
+	buf.Write(directoryRecord(directoryRecordSpec{
+		id:        string([]byte{1}),
+		extentLBA: rootDirSector,
+		dataLen:   sectorSize,
+		isDir:     true,
+	}))
+
+	for _, f := range files {
+		buf.Write(directoryRecord(directoryRecordSpec{
+			id:        shortName(f.name),
+			longName:  f.name,
+			extentLBA: f.lba,
+			dataLen:   len(f.data),
+		}))
+	}
+
+	return buf.Bytes(), nil
+}
+
+// shortName derives a spec-legal ISO9660 Level 1 (8.3, d-characters only)
+// identifier to satisfy strict readers that ignore Rock Ridge: truncated
+// to 8 characters, with anything outside A-Z0-9 mapped to '_'. Readers
+// that understand Rock Ridge use the NM entry's real name instead.
+func shortName(name string) string {
+	trimmed := strings.ToUpper(name)
+	if len(trimmed) > 8 {
+		trimmed = trimmed[:8]
+	}
+
+	var b strings.Builder
+	for _, r := range trimmed {
+		if (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return b.String() + ".;1"
+}
@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 
+	"github.com/sp00nznet/octopus/internal/secretresolver"
 	"gopkg.in/yaml.v3"
 )
 
@@ -17,13 +18,73 @@ type Config struct {
 	ADBindPass string `yaml:"ad_bind_pass"`
 	ADDomain   string `yaml:"ad_domain"`
 
+	// ADURL overrides ADServer with a full LDAP URL (e.g.
+	// "ldaps://dc.corp.example.com:636") so the scheme and port are
+	// configurable. When empty, the legacy ldap://ADServer:389 form is used.
+	ADURL string `yaml:"ad_url"`
+
+	// ADStartTLS upgrades a plaintext ldap:// connection with StartTLS
+	// before binding.
+	ADStartTLS bool `yaml:"ad_start_tls"`
+
+	// ADCACertPath is a PEM CA bundle used to verify the AD server's TLS
+	// certificate for ldaps:// or StartTLS connections. When empty, the
+	// system trust store is used.
+	ADCACertPath string `yaml:"ad_ca_cert_path"`
+
+	// ADInsecureSkipVerify disables TLS certificate verification for AD
+	// connections. Only meant for lab environments.
+	ADInsecureSkipVerify bool `yaml:"ad_insecure_skip_verify"`
+
+	// AuthProviders lists the authentication providers to chain, tried in
+	// order. When empty, the server falls back to a single AD or local
+	// provider based on whether ADServer or ADURL is set.
+	AuthProviders []ProviderConfig `yaml:"auth_providers"`
+
+	// AdminGroups are the AD/OIDC/SAML group names or DNs that grant admin
+	// access. Shared across all providers so group membership is mapped
+	// consistently regardless of which one authenticated the user.
+	AdminGroups []string `yaml:"admin_groups"`
+
+	// OIDC Settings
+	OIDCIssuerURL    string `yaml:"oidc_issuer_url"`
+	OIDCClientID     string `yaml:"oidc_client_id"`
+	OIDCClientSecret string `yaml:"oidc_client_secret"`
+	OIDCRedirectURL  string `yaml:"oidc_redirect_url"`
+
+	// SAML Settings
+	SAMLEntityID       string `yaml:"saml_entity_id"`
+	SAMLACSURL         string `yaml:"saml_acs_url"`
+	SAMLIDPMetadataURL string `yaml:"saml_idp_metadata_url"`
+
 	// JWT Settings
 	JWTSecret     string `yaml:"jwt_secret"`
 	JWTExpiration int    `yaml:"jwt_expiration_hours"`
 
+	// JWTPrivateKeyPath, if set, switches access-token signing from HS256
+	// to RS256/ES256 using the PEM-encoded key at this path, and publishes
+	// the corresponding public key via JWKS.
+	JWTPrivateKeyPath string `yaml:"jwt_private_key_path"`
+
+	// TokenStorePath, if set, persists refresh tokens and revocations to a
+	// BoltDB file at this path instead of keeping them in memory.
+	TokenStorePath string `yaml:"token_store_path"`
+
 	// Session Settings
 	SessionKey string `yaml:"session_key"`
 
+	// BundleEncryptionKey wraps credential fields (e.g. source environment
+	// passwords) inside export/import bundles. The key used to encrypt a
+	// bundle on export must be supplied to decrypt it on import - it does
+	// not need to match the destination's own BundleEncryptionKey.
+	BundleEncryptionKey string `yaml:"bundle_encryption_key"`
+
+	// SecretsKEKURI locates the key-encryption-key used to encrypt secret
+	// fields (e.g. a vmware environment's password) inside environments'
+	// config_json before it's persisted. Supports "file://path" and
+	// "env://VAR_NAME"; see internal/secrets.
+	SecretsKEKURI string `yaml:"secrets_kek_uri"`
+
 	// VMware Settings
 	VMwareDefaults VMwareConfig `yaml:"vmware_defaults"`
 
@@ -31,6 +92,56 @@ type Config struct {
 	AWSDefaults   AWSConfig   `yaml:"aws_defaults"`
 	GCPDefaults   GCPConfig   `yaml:"gcp_defaults"`
 	AzureDefaults AzureConfig `yaml:"azure_defaults"`
+
+	// VaultAddr, VaultRoleID, and VaultSecretID configure the AppRole
+	// backend Resolver uses for "vault://" secret references (in ADBindPass
+	// or JWTSecret; the cloud defaults' credential fields are not resolved).
+	// Leave VaultAddr empty if no config uses vault:// references.
+	VaultAddr     string `yaml:"vault_addr"`
+	VaultRoleID   string `yaml:"vault_role_id"`
+	VaultSecretID string `yaml:"vault_secret_id"`
+
+	// Resolver resolves "vault://", "env://", and "file://" secret
+	// references found in fields like ADBindPass and JWTSecret to their
+	// live value at the point of use. Built by Load; not YAML-serializable.
+	Resolver *secretresolver.Resolver `yaml:"-"`
+
+	// EventsWebhookURL and EventsWebhookSecret configure a CloudEvents
+	// HTTP sink for migration lifecycle events (see internal/events).
+	// Leave EventsWebhookURL empty to skip this sink.
+	EventsWebhookURL    string `yaml:"events_webhook_url"`
+	EventsWebhookSecret string `yaml:"events_webhook_secret"`
+
+	// EventsNATSURL configures a CloudEvents NATS sink. Leave empty to
+	// skip this sink.
+	EventsNATSURL string `yaml:"events_nats_url"`
+
+	// FlavorCacheDir is where estimateFlavor caches a target provider's
+	// instance-type/flavor catalog on disk (see internal/cloud.FlavorCache).
+	FlavorCacheDir string `yaml:"flavor_cache_dir"`
+
+	// SyncTransferWorkers bounds how many per-disk block batches a
+	// SyncManager's TransferPool flushes concurrently.
+	SyncTransferWorkers int `yaml:"sync_transfer_workers"`
+
+	// SyncMaxInFlightBytes bounds the total bytes a SyncManager's
+	// TransferPool may have queued for flush at once, across all workers.
+	SyncMaxInFlightBytes int64 `yaml:"sync_max_inflight_bytes"`
+
+	// PricingCacheDir is where EstimateCost caches provider pricing API
+	// responses on disk (see internal/pricing.Cache).
+	PricingCacheDir string `yaml:"pricing_cache_dir"`
+
+	// DiscoveryIntervalMinutes is how often internal/discovery reconciles
+	// each source environment's VM inventory in the background. Defaults
+	// to 5 when unset.
+	DiscoveryIntervalMinutes int `yaml:"discovery_interval_minutes"`
+}
+
+// ProviderConfig selects one authentication provider in the chain. Type is
+// one of "ldap", "local", "oidc", or "saml".
+type ProviderConfig struct {
+	Type string `yaml:"type"`
 }
 
 // VMwareConfig holds VMware vCenter configuration
@@ -58,25 +169,44 @@ type GCPConfig struct {
 
 // AzureConfig holds Azure configuration
 type AzureConfig struct {
-	SubscriptionID string `yaml:"subscription_id"`
-	ResourceGroup  string `yaml:"resource_group"`
-	TenantID       string `yaml:"tenant_id"`
-	ClientID       string `yaml:"client_id"`
-	ClientSecret   string `yaml:"client_secret"`
+	SubscriptionID       string `yaml:"subscription_id"`
+	ResourceGroup        string `yaml:"resource_group"`
+	TenantID             string `yaml:"tenant_id"`
+	ClientID             string `yaml:"client_id"`
+	ClientSecret         string `yaml:"client_secret"`
+	UseManagedDiskImport bool   `yaml:"use_managed_disk_import"`
+
+	GalleryName     string   `yaml:"gallery_name"`
+	GalleryImageDef string   `yaml:"gallery_image_def"`
+	ReplicaRegions  []string `yaml:"replica_regions"`
+	ReplicaCount    int32    `yaml:"replica_count"`
 }
 
 // Load reads configuration from file or environment
 func Load() (*Config, error) {
 	cfg := &Config{
-		DatabasePath:  getEnv("DATABASE_PATH", "/data/octopus.db"),
-		ADServer:      getEnv("AD_SERVER", ""),
-		ADBaseDN:      getEnv("AD_BASE_DN", ""),
-		ADBindUser:    getEnv("AD_BIND_USER", ""),
-		ADBindPass:    getEnv("AD_BIND_PASS", ""),
-		ADDomain:      getEnv("AD_DOMAIN", ""),
-		JWTSecret:     getEnv("JWT_SECRET", "change-me-in-production"),
-		JWTExpiration: 24,
-		SessionKey:    getEnv("SESSION_KEY", "change-me-in-production-too"),
+		DatabasePath:             getEnv("DATABASE_PATH", "/data/octopus.db"),
+		ADServer:                 getEnv("AD_SERVER", ""),
+		ADBaseDN:                 getEnv("AD_BASE_DN", ""),
+		ADBindUser:               getEnv("AD_BIND_USER", ""),
+		ADBindPass:               getEnv("AD_BIND_PASS", ""),
+		ADDomain:                 getEnv("AD_DOMAIN", ""),
+		JWTSecret:                getEnv("JWT_SECRET", "change-me-in-production"),
+		JWTExpiration:            24,
+		SessionKey:               getEnv("SESSION_KEY", "change-me-in-production-too"),
+		BundleEncryptionKey:      getEnv("BUNDLE_ENCRYPTION_KEY", "change-me-in-production"),
+		SecretsKEKURI:            getEnv("SECRETS_KEK_URI", "env://OCTOPUS_SECRETS_KEK"),
+		VaultAddr:                getEnv("VAULT_ADDR", ""),
+		VaultRoleID:              getEnv("VAULT_ROLE_ID", ""),
+		VaultSecretID:            getEnv("VAULT_SECRET_ID", ""),
+		EventsWebhookURL:         getEnv("EVENTS_WEBHOOK_URL", ""),
+		EventsWebhookSecret:      getEnv("EVENTS_WEBHOOK_SECRET", ""),
+		EventsNATSURL:            getEnv("EVENTS_NATS_URL", ""),
+		FlavorCacheDir:           getEnv("FLAVOR_CACHE_DIR", "/data/flavor-cache"),
+		PricingCacheDir:          getEnv("PRICING_CACHE_DIR", "/data/pricing-cache"),
+		SyncTransferWorkers:      4,
+		SyncMaxInFlightBytes:     256 << 20, // 256 MiB
+		DiscoveryIntervalMinutes: 5,
 	}
 
 	// Try to load from config file if it exists
@@ -91,6 +221,12 @@ func Load() (*Config, error) {
 		}
 	}
 
+	cfg.Resolver = secretresolver.New(secretresolver.VaultConfig{
+		Addr:     cfg.VaultAddr,
+		RoleID:   cfg.VaultRoleID,
+		SecretID: cfg.VaultSecretID,
+	})
+
 	return cfg, nil
 }
 
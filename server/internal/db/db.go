@@ -1,7 +1,9 @@
 package db
 
 import (
+	"bytes"
 	"database/sql"
+	"encoding/gob"
 	"os"
 	"path/filepath"
 	"time"
@@ -35,177 +37,39 @@ func Initialize(path string) (*Database, error) {
 	return &Database{db}, nil
 }
 
-// RunMigrations applies all database migrations
-func RunMigrations(db *Database) error {
-	migrations := []string{
-		// Users table for local user cache
-		`CREATE TABLE IF NOT EXISTS users (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			username TEXT UNIQUE NOT NULL,
-			email TEXT,
-			display_name TEXT,
-			is_admin BOOLEAN DEFAULT FALSE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			last_login TIMESTAMP
-		)`,
-
-		// Environment variables storage (admin portal)
-		`CREATE TABLE IF NOT EXISTS env_variables (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT UNIQUE NOT NULL,
-			value TEXT NOT NULL,
-			description TEXT,
-			is_secret BOOLEAN DEFAULT FALSE,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// Source environments (vCenter clusters, etc.)
-		`CREATE TABLE IF NOT EXISTS source_environments (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL,
-			type TEXT NOT NULL,
-			host TEXT NOT NULL,
-			username TEXT,
-			password TEXT,
-			datacenter TEXT,
-			cluster TEXT,
-			config_json TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// Target environments (vCenter, AWS, GCP, Azure)
-		`CREATE TABLE IF NOT EXISTS target_environments (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			name TEXT NOT NULL,
-			type TEXT NOT NULL CHECK(type IN ('vmware', 'aws', 'gcp', 'azure')),
-			config_json TEXT NOT NULL,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// VMs inventory
-		`CREATE TABLE IF NOT EXISTS vms (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			source_env_id INTEGER REFERENCES source_environments(id),
-			name TEXT NOT NULL,
-			uuid TEXT UNIQUE,
-			cpu_count INTEGER,
-			memory_mb INTEGER,
-			disk_size_gb REAL,
-			guest_os TEXT,
-			power_state TEXT,
-			ip_addresses TEXT,
-			mac_addresses TEXT,
-			port_groups TEXT,
-			hardware_version TEXT,
-			vmware_tools_status TEXT,
-			last_synced TIMESTAMP,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// Migration jobs
-		`CREATE TABLE IF NOT EXISTS migration_jobs (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			vm_id INTEGER REFERENCES vms(id),
-			source_env_id INTEGER REFERENCES source_environments(id),
-			target_env_id INTEGER REFERENCES target_environments(id),
-			status TEXT DEFAULT 'pending' CHECK(status IN ('pending', 'syncing', 'ready', 'cutting_over', 'completed', 'failed', 'cancelled')),
-			progress INTEGER DEFAULT 0,
-			preserve_mac BOOLEAN DEFAULT TRUE,
-			preserve_port_groups BOOLEAN DEFAULT TRUE,
-			sync_interval_minutes INTEGER DEFAULT 60,
-			scheduled_cutover TIMESTAMP,
-			error_message TEXT,
-			created_by TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			started_at TIMESTAMP,
-			completed_at TIMESTAMP
-		)`,
-
-		// Sync history for each migration job
-		`CREATE TABLE IF NOT EXISTS sync_history (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			job_id INTEGER REFERENCES migration_jobs(id),
-			status TEXT CHECK(status IN ('started', 'completed', 'failed')),
-			bytes_transferred INTEGER,
-			duration_seconds INTEGER,
-			error_message TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// Activity logs
-		`CREATE TABLE IF NOT EXISTS activity_logs (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			user_id INTEGER REFERENCES users(id),
-			action TEXT NOT NULL,
-			entity_type TEXT,
-			entity_id INTEGER,
-			details TEXT,
-			ip_address TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// Size estimations
-		`CREATE TABLE IF NOT EXISTS size_estimations (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			vm_id INTEGER REFERENCES vms(id),
-			target_type TEXT NOT NULL,
-			source_size_gb REAL,
-			estimated_size_gb REAL,
-			size_difference_gb REAL,
-			estimation_notes TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-		)`,
-
-		// Scheduled tasks (cutover/failover)
-		`CREATE TABLE IF NOT EXISTS scheduled_tasks (
-			id INTEGER PRIMARY KEY AUTOINCREMENT,
-			job_id INTEGER REFERENCES migration_jobs(id),
-			task_type TEXT NOT NULL CHECK(task_type IN ('cutover', 'failover', 'sync', 'test_failover')),
-			scheduled_time TIMESTAMP NOT NULL,
-			status TEXT DEFAULT 'pending' CHECK(status IN ('pending', 'running', 'completed', 'failed', 'cancelled')),
-			result TEXT,
-			created_by TEXT,
-			created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-			executed_at TIMESTAMP
-		)`,
-
-		// Create indexes for performance
-		`CREATE INDEX IF NOT EXISTS idx_vms_source_env ON vms(source_env_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_migration_jobs_status ON migration_jobs(status)`,
-		`CREATE INDEX IF NOT EXISTS idx_activity_logs_user ON activity_logs(user_id)`,
-		`CREATE INDEX IF NOT EXISTS idx_scheduled_tasks_time ON scheduled_tasks(scheduled_time)`,
-	}
+// VM represents a virtual machine
+type VM struct {
+	ID                int64      `json:"id"`
+	SourceEnvID       int64      `json:"source_env_id"`
+	Name              string     `json:"name"`
+	UUID              string     `json:"uuid"`
+	CPUCount          int        `json:"cpu_count"`
+	MemoryMB          int        `json:"memory_mb"`
+	DiskSizeGB        float64    `json:"disk_size_gb"`
+	GuestOS           string     `json:"guest_os"`
+	PowerState        string     `json:"power_state"`
+	IPAddresses       string     `json:"ip_addresses"`
+	MACAddresses      string     `json:"mac_addresses"`
+	PortGroups        string     `json:"port_groups"`
+	HardwareVersion   string     `json:"hardware_version"`
+	VMwareToolsStatus string     `json:"vmware_tools_status"`
+	LastSynced        time.Time  `json:"last_synced"`
+	DeletedAt         *time.Time `json:"deleted_at,omitempty"`
+	CreatedAt         time.Time  `json:"created_at"`
+}
 
-	for _, migration := range migrations {
-		if _, err := db.Exec(migration); err != nil {
-			return err
-		}
+// MarshalBinary encodes vm for inclusion in a portable export bundle.
+func (vm VM) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(vm); err != nil {
+		return nil, err
 	}
-
-	return nil
+	return buf.Bytes(), nil
 }
 
-// VM represents a virtual machine
-type VM struct {
-	ID                int64     `json:"id"`
-	SourceEnvID       int64     `json:"source_env_id"`
-	Name              string    `json:"name"`
-	UUID              string    `json:"uuid"`
-	CPUCount          int       `json:"cpu_count"`
-	MemoryMB          int       `json:"memory_mb"`
-	DiskSizeGB        float64   `json:"disk_size_gb"`
-	GuestOS           string    `json:"guest_os"`
-	PowerState        string    `json:"power_state"`
-	IPAddresses       string    `json:"ip_addresses"`
-	MACAddresses      string    `json:"mac_addresses"`
-	PortGroups        string    `json:"port_groups"`
-	HardwareVersion   string    `json:"hardware_version"`
-	VMwareToolsStatus string    `json:"vmware_tools_status"`
-	LastSynced        time.Time `json:"last_synced"`
-	CreatedAt         time.Time `json:"created_at"`
+// UnmarshalBinary decodes vm from the format produced by MarshalBinary.
+func (vm *VM) UnmarshalBinary(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(vm)
 }
 
 // MigrationJob represents a VM migration job
@@ -227,6 +91,33 @@ type MigrationJob struct {
 	CompletedAt         *time.Time `json:"completed_at,omitempty"`
 }
 
+// SyncProgress is the live progress of an in-flight sync for a migration
+// job, refreshed roughly every sample interval by performSync. It's
+// overwritten by each new sync rather than accumulated like sync_history.
+type SyncProgress struct {
+	JobID         int64     `json:"job_id"`
+	Phase         string    `json:"phase"`
+	BytesTotal    int64     `json:"bytes_total"`
+	BytesDone     int64     `json:"bytes_done"`
+	ThroughputBPS float64   `json:"throughput_bps"`
+	ETASeconds    int64     `json:"eta_seconds"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+// MarshalBinary encodes job for inclusion in a portable export bundle.
+func (job MigrationJob) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(job); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes job from the format produced by MarshalBinary.
+func (job *MigrationJob) UnmarshalBinary(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(job)
+}
+
 // SourceEnvironment represents a source vCenter cluster
 type SourceEnvironment struct {
 	ID         int64     `json:"id"`
@@ -242,6 +133,23 @@ type SourceEnvironment struct {
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
+// MarshalBinary encodes env for inclusion in a portable export bundle. The
+// Password field is expected to already be wrapped by the caller before
+// marshaling, and unwrapped after unmarshaling - this method itself does no
+// encryption.
+func (env SourceEnvironment) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(env); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes env from the format produced by MarshalBinary.
+func (env *SourceEnvironment) UnmarshalBinary(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(env)
+}
+
 // TargetEnvironment represents a migration target
 type TargetEnvironment struct {
 	ID         int64     `json:"id"`
@@ -252,7 +160,34 @@ type TargetEnvironment struct {
 	UpdatedAt  time.Time `json:"updated_at"`
 }
 
-// ScheduledTask represents a scheduled cutover/failover
+// MarshalBinary encodes env for inclusion in a portable export bundle.
+func (env TargetEnvironment) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(env); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes env from the format produced by MarshalBinary.
+func (env *TargetEnvironment) UnmarshalBinary(data []byte) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(env)
+}
+
+// Environment represents a row in the unified (pre-split) environments
+// table, backing the legacy /environments API.
+type Environment struct {
+	ID         int64     `json:"id"`
+	Name       string    `json:"name"`
+	Type       string    `json:"type"`
+	ConfigJSON string    `json:"config_json"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ScheduledTask represents a scheduled cutover/failover, either a one-shot
+// absolute ScheduledTime or a recurring CronExpression constrained to a
+// MaintenanceWindow.
 type ScheduledTask struct {
 	ID            int64      `json:"id"`
 	JobID         int64      `json:"job_id"`
@@ -263,6 +198,30 @@ type ScheduledTask struct {
 	CreatedBy     string     `json:"created_by"`
 	CreatedAt     time.Time  `json:"created_at"`
 	ExecutedAt    *time.Time `json:"executed_at,omitempty"`
+
+	// CronExpression, when set, makes this a recurring task: NextRun is
+	// recomputed from it after every fire instead of the task settling into
+	// 'completed'.
+	CronExpression string     `json:"cron_expression,omitempty"`
+	NextRun        *time.Time `json:"next_run,omitempty"`
+	// Timezone is the IANA zone CronExpression and MaintenanceWindow are
+	// evaluated in, e.g. "America/Chicago". Defaults to "UTC".
+	Timezone string `json:"timezone,omitempty"`
+	// MaintenanceWindow is JSON-encoded; see processDueTasks/processSyncJobs
+	// in internal/scheduler for how it's enforced.
+	MaintenanceWindow string `json:"maintenance_window,omitempty"`
+
+	// LeaseOwner/LeaseExpiresAt implement claimTask's distributed lock: set
+	// together with Status='running', cleared on settle, and used by
+	// reclaimAbandonedTasks to recover a task a crashed worker never settled.
+	LeaseOwner     string     `json:"lease_owner,omitempty"`
+	LeaseExpiresAt *time.Time `json:"lease_expires_at,omitempty"`
+	// RetryCount/MaxRetries/NextAttemptAt back the scheduler's exponential
+	// backoff retry: a failed task is requeued until RetryCount reaches
+	// MaxRetries, then it settles into 'dead_letter' instead of retrying again.
+	RetryCount    int        `json:"retry_count"`
+	MaxRetries    int        `json:"max_retries"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
 }
 
 // SizeEstimation represents a VM size estimation for a target
@@ -297,5 +256,51 @@ type ActivityLog struct {
 	EntityID   int64     `json:"entity_id"`
 	Details    string    `json:"details"`
 	IPAddress  string    `json:"ip_address"`
+	RequestID  string    `json:"request_id,omitempty"`
 	CreatedAt  time.Time `json:"created_at"`
 }
+
+// WebhookEndpoint represents a registered webhook notification target for
+// migration lifecycle events. Events is a comma-separated filter mask
+// (e.g. "syncing,completed,failed"); SigningSecret is never serialized.
+type WebhookEndpoint struct {
+	ID                  int64     `json:"id"`
+	Name                string    `json:"name"`
+	URL                 string    `json:"url"`
+	Events              string    `json:"events"`
+	AuthToken           string    `json:"-"`
+	SigningSecret       string    `json:"-"`
+	MaxRetries          int       `json:"max_retries"`
+	RetryBackoffSeconds int       `json:"retry_backoff_seconds"`
+	IsActive            bool      `json:"is_active"`
+	CreatedAt           time.Time `json:"created_at"`
+	UpdatedAt           time.Time `json:"updated_at"`
+}
+
+// WebhookDelivery is one attempted delivery of an event to a WebhookEndpoint.
+type WebhookDelivery struct {
+	ID           int64     `json:"id"`
+	EndpointID   int64     `json:"endpoint_id"`
+	Event        string    `json:"event"`
+	PayloadJSON  string    `json:"payload_json"`
+	StatusCode   int       `json:"status_code,omitempty"`
+	Attempt      int       `json:"attempt"`
+	Success      bool      `json:"success"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// UserTask is an actionable item the discovery controller materializes once
+// a source environment's reconciliation failures cross its threshold (see
+// internal/discovery), so an admin sees one card per underlying problem
+// instead of a log line per cycle.
+type UserTask struct {
+	ID                    int64      `json:"id"`
+	SourceEnvID           int64      `json:"source_env_id"`
+	TaskType              string     `json:"task_type"`
+	AffectedResourcesJSON string     `json:"affected_resources_json"`
+	State                 string     `json:"state"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	AcknowledgedAt        *time.Time `json:"acknowledged_at,omitempty"`
+}
@@ -0,0 +1,374 @@
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationsFS embed.FS
+
+// migration is one numbered schema change, loaded from a pair of
+// migrations/NNNN_name.up.sql / migrations/NNNN_name.down.sql files embedded
+// at build time. downSQL is empty for a migration with no .down.sql file -
+// only Rollback needs it.
+type migration struct {
+	version int
+	name    string
+	upSQL   string
+	downSQL string
+}
+
+// RunMigrations brings the database up to the latest embedded migration.
+// A database created before this runner existed (the "users" table already
+// present but schema_migrations empty) is adopted in place rather than
+// re-run - see adoptLegacyDatabase.
+func RunMigrations(db *Database) error {
+	migrations, err := prepareMigrations(db)
+	if err != nil {
+		return err
+	}
+	return migrateTo(db, migrations, latestVersion(migrations))
+}
+
+// MigrateTo brings the database to exactly version, applying pending up
+// migrations or rolling back already-applied ones as needed. Exposed for
+// CLI tooling that wants to move the schema to a specific point, e.g. ahead
+// of a deploy that expects it.
+func MigrateTo(db *Database, version int) error {
+	migrations, err := prepareMigrations(db)
+	if err != nil {
+		return err
+	}
+	return migrateTo(db, migrations, version)
+}
+
+// Rollback undoes the steps most-recently-applied migrations, newest first.
+// Exposed for CLI tooling recovering from a bad deploy.
+func Rollback(db *Database, steps int) error {
+	if steps <= 0 {
+		return nil
+	}
+
+	migrations, err := prepareMigrations(db)
+	if err != nil {
+		return err
+	}
+	byVersion := make(map[int]migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.version] = m
+	}
+
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+	appliedDesc := make([]int, 0, len(applied))
+	for v := range applied {
+		appliedDesc = append(appliedDesc, v)
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(appliedDesc)))
+	if steps > len(appliedDesc) {
+		steps = len(appliedDesc)
+	}
+
+	for _, v := range appliedDesc[:steps] {
+		m, ok := byVersion[v]
+		if !ok {
+			return fmt.Errorf("applied migration %d has no matching embedded migration file", v)
+		}
+		if err := applyDown(db, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prepareMigrations ensures schema_migrations exists, loads the embedded
+// migration set, and adopts a pre-existing legacy database - the three
+// things every entry point (RunMigrations, MigrateTo, Rollback) needs done
+// before it can reason about which versions are pending.
+func prepareMigrations(db *Database) ([]migration, error) {
+	if err := ensureSchemaMigrationsTable(db); err != nil {
+		return nil, err
+	}
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	if err := adoptLegacyDatabase(db, migrations); err != nil {
+		return nil, err
+	}
+	return migrations, nil
+}
+
+// ensureSchemaMigrationsTable creates the table this runner uses to track
+// applied versions. It isn't itself one of the numbered migrations - it has
+// to exist before we can tell which numbered migrations have already run.
+func ensureSchemaMigrationsTable(db *Database) error {
+	_, err := db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			applied_at TIMESTAMP NOT NULL,
+			checksum TEXT NOT NULL
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// adoptLegacyDatabase handles a database created before this runner
+// existed: one where 0001_initial's tables (detected by probing for
+// "users") already exist but schema_migrations has no rows. Rather than
+// re-running 0001's CREATE TABLE IF NOT EXISTS statements (harmless, but
+// pointless), it just records 0001 as applied so later migrations layer on
+// top cleanly.
+func adoptLegacyDatabase(db *Database, migrations []migration) error {
+	var appliedCount int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&appliedCount); err != nil {
+		return fmt.Errorf("count schema_migrations rows: %w", err)
+	}
+	if appliedCount > 0 {
+		return nil
+	}
+
+	var hasUsersTable int
+	err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'`).Scan(&hasUsersTable)
+	if err != nil {
+		return fmt.Errorf("probe for users table: %w", err)
+	}
+	if hasUsersTable == 0 {
+		return nil
+	}
+
+	for _, m := range migrations {
+		if m.version != 1 {
+			continue
+		}
+		_, err := db.Exec(`
+			INSERT INTO schema_migrations (version, applied_at, checksum)
+			VALUES (?, ?, ?)
+		`, m.version, time.Now(), checksum(m.upSQL))
+		if err != nil {
+			return fmt.Errorf("record legacy migration %d as applied: %w", m.version, err)
+		}
+	}
+	return nil
+}
+
+// migrateTo applies or rolls back migrations until target is the highest
+// applied version, first validating every already-applied migration's
+// checksum against its embedded file to detect drift (a migration file
+// edited after it ran against this database).
+func migrateTo(db *Database, migrations []migration, target int) error {
+	applied, err := appliedVersions(db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if sum, ok := applied[m.version]; ok && sum != checksum(m.upSQL) {
+			return fmt.Errorf("migration %d (%s) has changed since it was applied - checksum mismatch", m.version, m.name)
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version > target {
+			break
+		}
+		if _, ok := applied[m.version]; ok {
+			continue
+		}
+		if err := applyUp(db, m); err != nil {
+			return err
+		}
+	}
+
+	for i := len(migrations) - 1; i >= 0; i-- {
+		m := migrations[i]
+		if m.version <= target {
+			break
+		}
+		if _, ok := applied[m.version]; !ok {
+			continue
+		}
+		if err := applyDown(db, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyUp runs m's up.sql inside a transaction and records it as applied.
+func applyUp(db *Database, m migration) error {
+	return withForeignKeysDisabled(db, m.version, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(m.upSQL); err != nil {
+			return fmt.Errorf("apply migration %d (%s): %w", m.version, m.name, err)
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO schema_migrations (version, applied_at, checksum)
+			VALUES (?, ?, ?)
+		`, m.version, time.Now(), checksum(m.upSQL)); err != nil {
+			return fmt.Errorf("record migration %d as applied: %w", m.version, err)
+		}
+		return nil
+	})
+}
+
+// applyDown runs m's down.sql inside a transaction and removes its
+// schema_migrations row.
+func applyDown(db *Database, m migration) error {
+	if m.downSQL == "" {
+		return fmt.Errorf("migration %d (%s) has no .down.sql file", m.version, m.name)
+	}
+	return withForeignKeysDisabled(db, m.version, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(m.downSQL); err != nil {
+			return fmt.Errorf("roll back migration %d (%s): %w", m.version, m.name, err)
+		}
+		if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, m.version); err != nil {
+			return fmt.Errorf("unrecord migration %d: %w", m.version, err)
+		}
+		return nil
+	})
+}
+
+// withForeignKeysDisabled runs fn inside a transaction with PRAGMA
+// foreign_keys temporarily off, restoring whatever it was set to
+// afterwards. sqlite only honors changes to that pragma outside an open
+// transaction, so it has to be toggled before Begin rather than inside fn.
+func withForeignKeysDisabled(db *Database, version int, fn func(tx *sql.Tx) error) error {
+	var fkWasOn bool
+	if err := db.QueryRow(`PRAGMA foreign_keys`).Scan(&fkWasOn); err != nil {
+		return fmt.Errorf("read foreign_keys pragma: %w", err)
+	}
+	if fkWasOn {
+		if _, err := db.Exec(`PRAGMA foreign_keys = OFF`); err != nil {
+			return fmt.Errorf("disable foreign_keys for migration %d: %w", version, err)
+		}
+		defer db.Exec(`PRAGMA foreign_keys = ON`)
+	}
+
+	tx, err := db.Begin()
+	if err != nil {
+		return fmt.Errorf("begin transaction for migration %d: %w", version, err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit migration %d: %w", version, err)
+	}
+	return nil
+}
+
+// appliedVersions returns every version recorded in schema_migrations,
+// keyed by the checksum it was applied with.
+func appliedVersions(db *Database) (map[int]string, error) {
+	rows, err := db.Query(`SELECT version, checksum FROM schema_migrations`)
+	if err != nil {
+		return nil, fmt.Errorf("list applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var sum string
+		if err := rows.Scan(&version, &sum); err != nil {
+			return nil, fmt.Errorf("scan applied migration row: %w", err)
+		}
+		applied[version] = sum
+	}
+	return applied, rows.Err()
+}
+
+// loadMigrations reads every embedded *.up.sql/*.down.sql pair under
+// migrations/ and returns them sorted by version ascending.
+func loadMigrations() ([]migration, error) {
+	entries, err := fs.ReadDir(migrationsFS, "migrations")
+	if err != nil {
+		return nil, fmt.Errorf("read embedded migrations: %w", err)
+	}
+
+	byVersion := make(map[int]*migration)
+	for _, entry := range entries {
+		version, rest, ok := parseMigrationFilename(entry.Name())
+		if !ok {
+			continue
+		}
+
+		content, err := migrationsFS.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", entry.Name(), err)
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &migration{version: version}
+			byVersion[version] = m
+		}
+		switch {
+		case strings.HasSuffix(rest, ".up"):
+			m.name = strings.TrimSuffix(rest, ".up")
+			m.upSQL = string(content)
+		case strings.HasSuffix(rest, ".down"):
+			m.downSQL = string(content)
+		}
+	}
+
+	migrations := make([]migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.upSQL == "" {
+			return nil, fmt.Errorf("migration %d has no .up.sql file", m.version)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+	return migrations, nil
+}
+
+// parseMigrationFilename parses "0001_initial.up.sql" into version 1 and
+// rest "initial.up", or returns ok=false for a filename that doesn't match
+// the "NNNN_name.up|down.sql" pattern.
+func parseMigrationFilename(name string) (version int, rest string, ok bool) {
+	trimmed := strings.TrimSuffix(name, ".sql")
+	if trimmed == name {
+		return 0, "", false
+	}
+	underscore := strings.IndexByte(trimmed, '_')
+	if underscore < 0 {
+		return 0, "", false
+	}
+	version, err := strconv.Atoi(trimmed[:underscore])
+	if err != nil {
+		return 0, "", false
+	}
+	return version, trimmed[underscore+1:], true
+}
+
+// checksum hashes a migration's up.sql so migrateTo can detect a file that
+// changed after it was applied.
+func checksum(sqlText string) string {
+	sum := sha256.Sum256([]byte(sqlText))
+	return hex.EncodeToString(sum[:])
+}
+
+func latestVersion(migrations []migration) int {
+	if len(migrations) == 0 {
+		return 0
+	}
+	return migrations[len(migrations)-1].version
+}
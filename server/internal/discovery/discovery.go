@@ -0,0 +1,305 @@
+// Package discovery runs a background reconciliation loop against every
+// source environment's VM inventory, the same ticker-driven model
+// internal/scheduler uses for scheduled tasks. Where the manual
+// POST /sources/{id}/sync handler aborts the whole sync the first time it
+// can't read a VM, Controller records a per-VM outcome in discovery_status
+// and keeps going, and once the same problem has persisted for a few
+// cycles it materializes a user_tasks row an admin can act on.
+package discovery
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/sp00nznet/octopus/internal/config"
+	"github.com/sp00nznet/octopus/internal/db"
+	"github.com/sp00nznet/octopus/internal/providers/vmware"
+	"github.com/sp00nznet/octopus/internal/secrets"
+)
+
+const defaultInterval = 5 * time.Minute
+
+// failureThreshold is how many consecutive failed reconciliation attempts
+// against the same VM (or the source environment itself) are tolerated
+// before they're materialized as a user_tasks row instead of just being
+// recorded in discovery_status.
+const failureThreshold = 3
+
+// envFailureVMUUID is the discovery_status key used for a failure that
+// happens before any VM could be enumerated (e.g. the vCenter login
+// itself), as opposed to a failure reading one specific VM.
+const envFailureVMUUID = ""
+
+// Controller periodically reconciles every source_environments row's VM
+// inventory.
+type Controller struct {
+	db       *db.Database
+	secrets  *secrets.Protector
+	interval time.Duration
+	stopChan chan struct{}
+}
+
+// New creates a discovery Controller, building its own dependencies from
+// cfg the same way scheduler.New does.
+func New(database *db.Database, cfg *config.Config) *Controller {
+	interval := time.Duration(cfg.DiscoveryIntervalMinutes) * time.Minute
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	return &Controller{
+		db:       database,
+		secrets:  secrets.NewProtector(cfg.SecretsKEKURI),
+		interval: interval,
+		stopChan: make(chan struct{}),
+	}
+}
+
+// Start runs the reconciliation loop until Stop is called. Meant to be
+// invoked with `go`, the same as scheduler.Scheduler.Start.
+func (c *Controller) Start() {
+	log.Println("Discovery controller started")
+
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	c.reconcileAll()
+	for {
+		select {
+		case <-ticker.C:
+			c.reconcileAll()
+		case <-c.stopChan:
+			log.Println("Discovery controller stopped")
+			return
+		}
+	}
+}
+
+// Stop ends the reconciliation loop.
+func (c *Controller) Stop() {
+	close(c.stopChan)
+}
+
+type sourceEnv struct {
+	id                                            int64
+	envType, host, username, password, datacenter string
+}
+
+// reconcileAll walks every source environment. source_environments has no
+// per-row enable/disable flag yet, so every row is reconciled each cycle
+// until one is added.
+func (c *Controller) reconcileAll() {
+	rows, err := c.db.Query(`SELECT id, type, host, username, password, datacenter FROM source_environments`)
+	if err != nil {
+		log.Printf("discovery: failed to list source environments: %v", err)
+		return
+	}
+
+	var envs []sourceEnv
+	for rows.Next() {
+		var e sourceEnv
+		if err := rows.Scan(&e.id, &e.envType, &e.host, &e.username, &e.password, &e.datacenter); err != nil {
+			continue
+		}
+		envs = append(envs, e)
+	}
+	rows.Close()
+
+	for _, e := range envs {
+		c.reconcileEnv(e)
+	}
+}
+
+// reconcileEnv connects to one source environment, enumerates its VMs, and
+// upserts both the vms table (same ON CONFLICT(uuid) shape
+// syncSourceEnvironment already uses) and a discovery_status row per VM -
+// success or failure - then checks whether any failure has crossed
+// failureThreshold.
+func (c *Controller) reconcileEnv(e sourceEnv) {
+	// Only vmware source environments are discoverable today, the same
+	// restriction syncSourceEnvironment's handler has always had.
+	if e.envType != "" && e.envType != "vmware" {
+		return
+	}
+
+	password, err := c.secrets.Decrypt(e.password)
+	if err != nil {
+		c.recordFailure(e.id, envFailureVMUUID, "decrypt_error", err.Error())
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
+	defer cancel()
+
+	client, err := vmware.NewClient(e.host, e.username, password, e.datacenter, true)
+	if err != nil {
+		c.recordFailure(e.id, envFailureVMUUID, classifyError(err), err.Error())
+		return
+	}
+	defer client.Logout(ctx)
+
+	c.recordSuccess(e.id, envFailureVMUUID)
+
+	results, err := client.ListVMsWithErrors(ctx)
+	if err != nil {
+		c.recordFailure(e.id, envFailureVMUUID, classifyError(err), err.Error())
+		return
+	}
+
+	for _, r := range results {
+		if r.Err != nil {
+			c.recordFailure(e.id, vmResultKey(r), classifyError(r.Err), r.Err.Error())
+			continue
+		}
+
+		if err := c.upsertVM(e.id, *r.Info); err != nil {
+			c.recordFailure(e.id, r.Info.UUID, "upsert_error", err.Error())
+			continue
+		}
+		c.recordSuccess(e.id, r.Info.UUID)
+	}
+}
+
+// vmResultKey identifies a VM that failed getVMInfo and so has no UUID of
+// its own - its inventory name is the best identifier available.
+func vmResultKey(r vmware.VMResult) string {
+	if r.Info != nil && r.Info.UUID != "" {
+		return r.Info.UUID
+	}
+	return "name:" + r.Name
+}
+
+// upsertVM mirrors syncSourceEnvironment's per-VM INSERT ... ON CONFLICT.
+func (c *Controller) upsertVM(sourceEnvID int64, vm vmware.VMInfo) error {
+	_, err := c.db.Exec(`
+		INSERT INTO vms (source_env_id, name, uuid, cpu_count, memory_mb, disk_size_gb, guest_os,
+			power_state, ip_addresses, mac_addresses, port_groups, hardware_version, vmware_tools_status, last_synced)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(uuid) DO UPDATE SET
+			name=?, cpu_count=?, memory_mb=?, disk_size_gb=?, guest_os=?,
+			power_state=?, ip_addresses=?, mac_addresses=?, port_groups=?,
+			hardware_version=?, vmware_tools_status=?, last_synced=?
+	`, sourceEnvID, vm.Name, vm.UUID, vm.CPUCount, vm.MemoryMB, vm.DiskSizeGB, vm.GuestOS,
+		vm.PowerState, vm.IPAddresses, vm.MACAddresses, vm.PortGroups, vm.HardwareVersion,
+		vm.VMwareToolsStatus, time.Now(),
+		vm.Name, vm.CPUCount, vm.MemoryMB, vm.DiskSizeGB, vm.GuestOS,
+		vm.PowerState, vm.IPAddresses, vm.MACAddresses, vm.PortGroups,
+		vm.HardwareVersion, vm.VMwareToolsStatus, time.Now())
+	if err != nil {
+		return fmt.Errorf("upsert VM %s: %w", vm.Name, err)
+	}
+	return nil
+}
+
+// recordSuccess clears any standing failure for key, resetting its streak.
+func (c *Controller) recordSuccess(sourceEnvID int64, key string) {
+	now := time.Now()
+	_, err := c.db.Exec(`
+		INSERT INTO discovery_status (source_env_id, vm_uuid, last_attempt, last_success, error_code, error_message, attempt_count)
+		VALUES (?, ?, ?, ?, NULL, NULL, 0)
+		ON CONFLICT(source_env_id, vm_uuid) DO UPDATE SET
+			last_attempt=?, last_success=?, error_code=NULL, error_message=NULL, attempt_count=0
+	`, sourceEnvID, key, now, now, now, now)
+	if err != nil {
+		log.Printf("discovery: failed to record success for env %d %q: %v", sourceEnvID, key, err)
+	}
+}
+
+// recordFailure bumps key's attempt_count and, once it crosses
+// failureThreshold, materializes or merges a user_tasks row for it.
+func (c *Controller) recordFailure(sourceEnvID int64, key, errCode, errMsg string) {
+	now := time.Now()
+	_, err := c.db.Exec(`
+		INSERT INTO discovery_status (source_env_id, vm_uuid, last_attempt, error_code, error_message, attempt_count)
+		VALUES (?, ?, ?, ?, ?, 1)
+		ON CONFLICT(source_env_id, vm_uuid) DO UPDATE SET
+			last_attempt=?, error_code=?, error_message=?, attempt_count=attempt_count+1
+	`, sourceEnvID, key, now, errCode, errMsg, now, errCode, errMsg)
+	if err != nil {
+		log.Printf("discovery: failed to record failure for env %d %q: %v", sourceEnvID, key, err)
+		return
+	}
+
+	var attemptCount int
+	err = c.db.QueryRow(`
+		SELECT attempt_count FROM discovery_status WHERE source_env_id = ? AND vm_uuid = ?
+	`, sourceEnvID, key).Scan(&attemptCount)
+	if err != nil || attemptCount < failureThreshold {
+		return
+	}
+
+	c.materializeTask(sourceEnvID, key, errCode)
+}
+
+// materializeTask upserts a user_tasks row for sourceEnvID+errCode,
+// appending key to its affected_resources_json if it isn't already there -
+// the same failure recurring for another VM updates the existing task
+// instead of spamming a new one every cycle. The human-readable error
+// message for each affected resource lives in discovery_status, keyed by
+// the same (source_env_id, vm_uuid) pair - callers building an admin card
+// join against it rather than duplicating the message here.
+func (c *Controller) materializeTask(sourceEnvID int64, key, errCode string) {
+	dedupKey := errCode
+
+	var existingID int64
+	var affectedJSON string
+	err := c.db.QueryRow(`
+		SELECT id, affected_resources_json FROM user_tasks
+		WHERE source_env_id = ? AND task_type = 'discovery_failure' AND dedup_key = ?
+	`, sourceEnvID, dedupKey).Scan(&existingID, &affectedJSON)
+
+	var affected []string
+	if err == nil {
+		_ = json.Unmarshal([]byte(affectedJSON), &affected)
+	}
+	if !containsString(affected, key) {
+		affected = append(affected, key)
+	}
+	newJSON, marshalErr := json.Marshal(affected)
+	if marshalErr != nil {
+		log.Printf("discovery: failed to marshal affected resources for env %d: %v", sourceEnvID, marshalErr)
+		return
+	}
+
+	now := time.Now()
+	_, execErr := c.db.Exec(`
+		INSERT INTO user_tasks (source_env_id, task_type, dedup_key, affected_resources_json, state, created_at, updated_at)
+		VALUES (?, 'discovery_failure', ?, ?, 'open', ?, ?)
+		ON CONFLICT(source_env_id, task_type, dedup_key) DO UPDATE SET
+			affected_resources_json=?, updated_at=?
+	`, sourceEnvID, dedupKey, string(newJSON), now, now, string(newJSON), now)
+	if execErr != nil {
+		log.Printf("discovery: failed to materialize task for env %d: %v", sourceEnvID, execErr)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyError reduces a govmomi/connection error down to a coarse,
+// stable error_code so recurring failures with the same underlying cause
+// dedup onto the same user_tasks row, even though the full error text
+// (which can include request IDs or object paths) rarely matches verbatim
+// across cycles.
+func classifyError(err error) string {
+	msg := strings.ToLower(err.Error())
+	switch {
+	case strings.Contains(msg, "incorrect user name or password"), strings.Contains(msg, "invalidlogin"):
+		return "auth_error"
+	case strings.Contains(msg, "permission"), strings.Contains(msg, "not authorized"), strings.Contains(msg, "nopermission"):
+		return "permission_denied"
+	case strings.Contains(msg, "no such host"), strings.Contains(msg, "connection refused"), strings.Contains(msg, "timeout"):
+		return "connection_error"
+	default:
+		return "unknown_error"
+	}
+}
@@ -0,0 +1,113 @@
+// Package events publishes CloudEvents v1.0 envelopes for migration
+// lifecycle state changes - env variable CRUD, user admin toggles,
+// instance create/start/stop, snapshot completion, and cutover/failover
+// transitions - so external systems (an ITSM integration, a Slack bot, a
+// dashboard) can subscribe to state changes instead of polling
+// activity_logs. Delivery fans out to one or more pluggable Sinks (an
+// HTTPSink, a NATSSink, or a MemorySink for tests), mirroring the
+// fire-and-forget dispatch internal/webhooks uses for its own endpoint
+// deliveries.
+package events
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sp00nznet/octopus/internal/config"
+)
+
+// SpecVersion is the CloudEvents specification version every event emitted
+// here conforms to.
+const SpecVersion = "1.0"
+
+// CloudEvent is a CloudEvents v1.0 JSON envelope.
+type CloudEvent struct {
+	SpecVersion     string      `json:"specversion"`
+	Type            string      `json:"type"`
+	Source          string      `json:"source"`
+	ID              string      `json:"id"`
+	Subject         string      `json:"subject,omitempty"`
+	Time            time.Time   `json:"time"`
+	DataContentType string      `json:"datacontenttype,omitempty"`
+	Data            interface{} `json:"data,omitempty"`
+}
+
+// Sink delivers a CloudEvent somewhere - a webhook, a message broker, or (in
+// tests) an in-memory slice.
+type Sink interface {
+	Send(event CloudEvent) error
+}
+
+// Publisher builds CloudEvents envelopes and fans them out to every
+// configured Sink.
+type Publisher struct {
+	source string
+	sinks  []Sink
+}
+
+// NewPublisher creates a Publisher that dispatches to sinks. source is
+// stamped as "/octopus/<hostname>" on every event, identifying which
+// Octopus instance emitted it.
+func NewPublisher(sinks ...Sink) *Publisher {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "octopus"
+	}
+	return &Publisher{source: "/octopus/" + host, sinks: sinks}
+}
+
+// NewPublisherFromConfig builds a Publisher from cfg's events sink
+// settings: an HTTPSink when EventsWebhookURL is set, a NATSSink when
+// EventsNATSURL is set. Both are optional; a Publisher with no sinks
+// configured simply drops every event, so the server runs fine without
+// either.
+func NewPublisherFromConfig(cfg *config.Config) *Publisher {
+	var sinks []Sink
+
+	if cfg.EventsWebhookURL != "" {
+		sinks = append(sinks, NewHTTPSink(cfg.EventsWebhookURL, cfg.EventsWebhookSecret, 0, 0))
+	}
+
+	if cfg.EventsNATSURL != "" {
+		sink, err := NewNATSSink(cfg.EventsNATSURL, "octopus.events")
+		if err != nil {
+			log.Printf("events: failed to connect to NATS at %s, skipping sink: %v", cfg.EventsNATSURL, err)
+		} else {
+			sinks = append(sinks, sink)
+		}
+	}
+
+	return NewPublisher(sinks...)
+}
+
+// Publish builds a CloudEvents envelope for eventType/subject/data and
+// dispatches it to every sink on its own goroutine, so a slow or
+// unreachable sink never blocks the scheduler task or API handler that
+// triggered the event. subject is typically the VM or resource ID the event
+// is about.
+func (p *Publisher) Publish(eventType, subject string, data interface{}) {
+	if p == nil || len(p.sinks) == 0 {
+		return
+	}
+
+	event := CloudEvent{
+		SpecVersion:     SpecVersion,
+		Type:            eventType,
+		Source:          p.source,
+		ID:              uuid.NewString(),
+		Subject:         subject,
+		Time:            time.Now().UTC(),
+		DataContentType: "application/json",
+		Data:            data,
+	}
+
+	for _, sink := range p.sinks {
+		go func(sink Sink) {
+			if err := sink.Send(event); err != nil {
+				log.Printf("events: failed to publish %s to sink: %v", eventType, err)
+			}
+		}(sink)
+	}
+}
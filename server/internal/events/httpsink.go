@@ -0,0 +1,97 @@
+package events
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// signatureHeader carries the hex-encoded HMAC-SHA256 of the request body,
+// computed with the sink's signing secret, mirroring internal/webhooks'
+// delivery signature.
+const signatureHeader = "X-Octopus-Signature"
+
+const sendTimeout = 10 * time.Second
+
+// HTTPSink delivers CloudEvents to a webhook URL, signing each payload with
+// HMAC-SHA256 and retrying with exponential backoff.
+type HTTPSink struct {
+	URL        string
+	Secret     string
+	MaxRetries int
+	Backoff    time.Duration
+
+	client *http.Client
+}
+
+// NewHTTPSink creates an HTTPSink. maxRetries and backoff default to 5 and
+// 2s respectively when <= 0.
+func NewHTTPSink(url, secret string, maxRetries int, backoff time.Duration) *HTTPSink {
+	if maxRetries <= 0 {
+		maxRetries = 5
+	}
+	if backoff <= 0 {
+		backoff = 2 * time.Second
+	}
+	return &HTTPSink{
+		URL:        url,
+		Secret:     secret,
+		MaxRetries: maxRetries,
+		Backoff:    backoff,
+		client:     &http.Client{Timeout: sendTimeout},
+	}
+}
+
+// Send POSTs event to the sink's URL, retrying with exponential backoff
+// (Backoff * 2^attempt) up to MaxRetries times.
+func (h *HTTPSink) Send(event CloudEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("events: marshal cloudevent: %w", err)
+	}
+
+	var lastErr error
+	for attempt := 1; attempt <= h.MaxRetries; attempt++ {
+		if err := h.deliver(body); err != nil {
+			lastErr = err
+			if attempt < h.MaxRetries {
+				time.Sleep(h.Backoff * time.Duration(1<<uint(attempt-1)))
+			}
+			continue
+		}
+		return nil
+	}
+	return fmt.Errorf("events: delivery to %s failed after %d attempts: %w", h.URL, h.MaxRetries, lastErr)
+}
+
+func (h *HTTPSink) deliver(body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	req.Header.Set(signatureHeader, sign(h.Secret, body))
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// sign returns the hex-encoded HMAC-SHA256 of body using secret.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
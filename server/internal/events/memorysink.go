@@ -0,0 +1,32 @@
+package events
+
+import "sync"
+
+// MemorySink collects published CloudEvents in memory instead of delivering
+// them anywhere, for use in tests and local development where no webhook
+// receiver or NATS broker is available.
+type MemorySink struct {
+	mu     sync.Mutex
+	events []CloudEvent
+}
+
+// NewMemorySink creates an empty MemorySink.
+func NewMemorySink() *MemorySink {
+	return &MemorySink{}
+}
+
+func (m *MemorySink) Send(event CloudEvent) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.events = append(m.events, event)
+	return nil
+}
+
+// Events returns a copy of every event received so far.
+func (m *MemorySink) Events() []CloudEvent {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]CloudEvent, len(m.events))
+	copy(out, m.events)
+	return out
+}
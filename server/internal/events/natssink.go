@@ -0,0 +1,44 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+)
+
+// NATSSink publishes CloudEvents to NATS, under the subject
+// "<subjectPrefix>.<event.Type>" (e.g. "octopus.events.net.octopus.migration.cutover.completed"),
+// so subscribers can wildcard-match a slice of the lifecycle (e.g.
+// "octopus.events.net.octopus.migration.>").
+type NATSSink struct {
+	conn          *nats.Conn
+	subjectPrefix string
+}
+
+// NewNATSSink connects to the NATS server at url.
+func NewNATSSink(url, subjectPrefix string) (*NATSSink, error) {
+	conn, err := nats.Connect(url)
+	if err != nil {
+		return nil, fmt.Errorf("events: connect to NATS: %w", err)
+	}
+	return &NATSSink{conn: conn, subjectPrefix: subjectPrefix}, nil
+}
+
+func (n *NATSSink) Send(event CloudEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("events: marshal cloudevent: %w", err)
+	}
+
+	subject := n.subjectPrefix + "." + event.Type
+	if err := n.conn.Publish(subject, body); err != nil {
+		return fmt.Errorf("events: publish to NATS subject %s: %w", subject, err)
+	}
+	return nil
+}
+
+// Close closes the underlying NATS connection without draining it first.
+func (n *NATSSink) Close() {
+	n.conn.Close()
+}
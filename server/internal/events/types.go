@@ -0,0 +1,25 @@
+package events
+
+// Event types, namespaced as "net.octopus.<resource>.<action>" per the
+// CloudEvents reverse-DNS type convention.
+const (
+	TypeMigrationSyncStarted      = "net.octopus.migration.sync.started"
+	TypeMigrationSyncCompleted    = "net.octopus.migration.sync.completed"
+	TypeMigrationSyncFailed       = "net.octopus.migration.sync.failed"
+	TypeMigrationCutoverStarted   = "net.octopus.migration.cutover.started"
+	TypeMigrationCutoverCompleted = "net.octopus.migration.cutover.completed"
+	TypeMigrationCutoverFailed    = "net.octopus.migration.cutover.failed"
+	TypeMigrationFailoverStarted  = "net.octopus.migration.failover.started"
+
+	TypeInstanceCreated   = "net.octopus.instance.created"
+	TypeInstanceStarted   = "net.octopus.instance.started"
+	TypeInstanceStopped   = "net.octopus.instance.stopped"
+	TypeSnapshotCompleted = "net.octopus.snapshot.completed"
+
+	TypeEnvVariableCreated = "net.octopus.env_variable.created"
+	TypeEnvVariableUpdated = "net.octopus.env_variable.updated"
+	TypeEnvVariableDeleted = "net.octopus.env_variable.deleted"
+
+	TypeUserAdminGranted = "net.octopus.user.admin_granted"
+	TypeUserAdminRevoked = "net.octopus.user.admin_revoked"
+)
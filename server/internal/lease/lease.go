@@ -0,0 +1,194 @@
+// Package lease coordinates ownership of in-flight migration jobs so that
+// two octopus instances sharing a database (HA setup) don't run the same
+// sync or cutover twice, and a crashed worker doesn't leave a job stuck
+// "syncing" or "cutting_over" forever.
+package lease
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sp00nznet/octopus/internal/db"
+)
+
+// ErrLeaseHeld is returned by Acquire when another worker already holds an
+// unexpired lease on the job.
+var ErrLeaseHeld = errors.New("lease: already held by another worker")
+
+// Lease represents this worker's ownership of a migration job.
+type Lease struct {
+	JobID     int64
+	Token     string
+	ExpiresAt time.Time
+	cancel    context.CancelFunc
+}
+
+// Manager acquires, renews, and reclaims migration_leases rows.
+type Manager struct {
+	db      *db.Database
+	ownerID string
+}
+
+// NewManager creates a new lease Manager. ownerID identifies this process
+// instance to other workers sharing the same database.
+func NewManager(database *db.Database) *Manager {
+	return &Manager{
+		db:      database,
+		ownerID: uuid.NewString(),
+	}
+}
+
+// Acquire takes out a lease on jobID valid for ttl and starts a heartbeat
+// goroutine that renews it at roughly a third of the TTL. It returns a
+// context derived from ctx that is cancelled the moment the lease is lost -
+// either because renewal failed or the caller released it - so callers must
+// observe ctx.Done() and abort their work rather than continuing to mutate a
+// job now owned by someone else (the same caveat operations.Operation.Cancel
+// already documents: cancellation only takes effect if the work in progress
+// checks for it). This is the fix for the Minio-style bug where a lost lock
+// left its context running unbounded.
+//
+// Acquire returns ErrLeaseHeld if another worker already holds an unexpired
+// lease on the job.
+func (m *Manager) Acquire(ctx context.Context, jobID int64, ttl time.Duration) (*Lease, context.Context, error) {
+	token := uuid.NewString()
+	now := time.Now()
+	expiresAt := now.Add(ttl)
+
+	res, err := m.db.Exec(`
+		INSERT INTO migration_leases (job_id, owner_id, token, acquired_at, expires_at)
+		VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT(job_id) DO UPDATE SET
+			owner_id = excluded.owner_id,
+			token = excluded.token,
+			acquired_at = excluded.acquired_at,
+			expires_at = excluded.expires_at
+		WHERE migration_leases.expires_at <= ?
+	`, jobID, m.ownerID, token, now, expiresAt, now)
+	if err != nil {
+		return nil, nil, fmt.Errorf("acquire lease for job %d: %w", jobID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return nil, nil, fmt.Errorf("acquire lease for job %d: %w", jobID, err)
+	}
+	if affected == 0 {
+		return nil, nil, ErrLeaseHeld
+	}
+
+	leaseCtx, cancel := context.WithCancel(ctx)
+	l := &Lease{JobID: jobID, Token: token, ExpiresAt: expiresAt, cancel: cancel}
+
+	go m.heartbeat(leaseCtx, cancel, l, ttl)
+
+	return l, leaseCtx, nil
+}
+
+// heartbeat renews l until leaseCtx is done (the caller released it, or a
+// prior renewal already cancelled it). If a renewal fails - because the
+// lease expired before we got to it, or another worker has since taken it
+// over - cancel is invoked so the caller's context observes the loss.
+func (m *Manager) heartbeat(leaseCtx context.Context, cancel context.CancelFunc, l *Lease, ttl time.Duration) {
+	interval := ttl / 3
+	if interval <= 0 {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-leaseCtx.Done():
+			return
+		case <-ticker.C:
+			if err := m.renew(l, ttl); err != nil {
+				log.Printf("lease: lost lease on job %d: %v", l.JobID, err)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+func (m *Manager) renew(l *Lease, ttl time.Duration) error {
+	now := time.Now()
+	newExpiry := now.Add(ttl)
+
+	res, err := m.db.Exec(`
+		UPDATE migration_leases
+		SET expires_at = ?
+		WHERE job_id = ? AND token = ? AND expires_at > ?
+	`, newExpiry, l.JobID, l.Token, now)
+	if err != nil {
+		return fmt.Errorf("renew lease for job %d: %w", l.JobID, err)
+	}
+
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("renew lease for job %d: %w", l.JobID, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("lease for job %d expired or was reassigned", l.JobID)
+	}
+
+	l.ExpiresAt = newExpiry
+	return nil
+}
+
+// Release deletes l if it's still owned by this token, freeing the job for
+// the next worker immediately instead of waiting out the TTL, and stops its
+// heartbeat goroutine by cancelling the context Acquire returned.
+func (m *Manager) Release(l *Lease) error {
+	if l == nil {
+		return nil
+	}
+	l.cancel()
+	if _, err := m.db.Exec(`DELETE FROM migration_leases WHERE job_id = ? AND token = ?`, l.JobID, l.Token); err != nil {
+		return fmt.Errorf("release lease for job %d: %w", l.JobID, err)
+	}
+	return nil
+}
+
+// Reap deletes expired leases and marks any migration job still mid-flight
+// under one of them as failed, so a crashed worker doesn't leave a job
+// stuck "syncing" or "cutting_over" forever. It's meant to be called
+// periodically by the scheduler.
+func (m *Manager) Reap() {
+	rows, err := m.db.Query(`SELECT job_id FROM migration_leases WHERE expires_at <= ?`, time.Now())
+	if err != nil {
+		log.Printf("lease: error scanning for expired leases: %v", err)
+		return
+	}
+
+	var jobIDs []int64
+	for rows.Next() {
+		var jobID int64
+		if err := rows.Scan(&jobID); err != nil {
+			continue
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+	rows.Close()
+
+	for _, jobID := range jobIDs {
+		m.db.Exec(`DELETE FROM migration_leases WHERE job_id = ?`, jobID)
+
+		res, err := m.db.Exec(`
+			UPDATE migration_jobs
+			SET status = 'failed', error_message = 'lease expired; orphaned by crashed worker'
+			WHERE id = ? AND status IN ('syncing', 'cutting_over')
+		`, jobID)
+		if err != nil {
+			log.Printf("lease: error failing orphaned job %d: %v", jobID, err)
+			continue
+		}
+		if affected, _ := res.RowsAffected(); affected > 0 {
+			log.Printf("lease: reclaimed expired lease on job %d, marked failed", jobID)
+		}
+	}
+}
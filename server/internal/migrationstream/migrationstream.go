@@ -0,0 +1,150 @@
+// Package migrationstream is an in-process pub/sub hub for migration job
+// progress, modeled on the Kubernetes watch pattern: the server pushes typed
+// events (ADDED, MODIFIED, ERROR) to subscribers rather than making clients
+// poll. Each job keeps a bounded ring buffer of its recent events so a
+// reconnecting subscriber can replay what it missed via Last-Event-ID.
+package migrationstream
+
+import (
+	"sync"
+	"time"
+)
+
+// EventType mirrors the Kubernetes watch event types most relevant to a
+// single migration job's lifecycle.
+type EventType string
+
+const (
+	EventAdded    EventType = "ADDED"
+	EventModified EventType = "MODIFIED"
+	EventError    EventType = "ERROR"
+)
+
+// ringBufferSize bounds how many events are retained per job for replay.
+const ringBufferSize = 100
+
+// Event is a single progress update for a migration job. ID is a
+// per-job, monotonically increasing sequence number used as the SSE id
+// field and for Last-Event-ID replay.
+type Event struct {
+	ID        uint64    `json:"id"`
+	Type      EventType `json:"type"`
+	JobID     int64     `json:"job_id"`
+	Status    string    `json:"status"`
+	Progress  int       `json:"progress"`
+	Message   string    `json:"message,omitempty"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// jobStream holds the ring buffer and live subscribers for one migration job.
+type jobStream struct {
+	mu          sync.Mutex
+	nextID      uint64
+	buffer      []Event
+	subscribers map[chan Event]struct{}
+}
+
+// Hub fans out migration progress events to subscribers, keyed by job ID.
+type Hub struct {
+	mu   sync.Mutex
+	jobs map[int64]*jobStream
+}
+
+// NewHub creates an empty Hub.
+func NewHub() *Hub {
+	return &Hub{jobs: make(map[int64]*jobStream)}
+}
+
+func (h *Hub) stream(jobID int64) *jobStream {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	js, ok := h.jobs[jobID]
+	if !ok {
+		js = &jobStream{subscribers: make(map[chan Event]struct{})}
+		h.jobs[jobID] = js
+	}
+	return js
+}
+
+// Publish appends an event to jobID's ring buffer and broadcasts it to
+// current subscribers.
+func (h *Hub) Publish(jobID int64, eventType EventType, status string, progress int, message string) {
+	js := h.stream(jobID)
+
+	js.mu.Lock()
+	js.nextID++
+	event := Event{
+		ID:        js.nextID,
+		Type:      eventType,
+		JobID:     jobID,
+		Status:    status,
+		Progress:  progress,
+		Message:   message,
+		Timestamp: time.Now(),
+	}
+
+	js.buffer = append(js.buffer, event)
+	if len(js.buffer) > ringBufferSize {
+		js.buffer = js.buffer[len(js.buffer)-ringBufferSize:]
+	}
+
+	for ch := range js.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Subscriber too slow to keep up; drop rather than block the
+			// worker that's making progress.
+		}
+	}
+	js.mu.Unlock()
+}
+
+// Subscribe registers a channel that receives every future event for jobID,
+// along with a replay of buffered events with ID greater than lastEventID
+// (pass 0 for no replay). Call the returned function to unsubscribe.
+func (h *Hub) Subscribe(jobID int64, lastEventID uint64) (<-chan Event, []Event, func()) {
+	js := h.stream(jobID)
+	ch := make(chan Event, 32)
+
+	js.mu.Lock()
+	var replay []Event
+	for _, e := range js.buffer {
+		if e.ID > lastEventID {
+			replay = append(replay, e)
+		}
+	}
+	js.subscribers[ch] = struct{}{}
+	js.mu.Unlock()
+
+	unsubscribe := func() {
+		js.mu.Lock()
+		if _, ok := js.subscribers[ch]; ok {
+			delete(js.subscribers, ch)
+			close(ch)
+		}
+		js.mu.Unlock()
+	}
+	return ch, replay, unsubscribe
+}
+
+// OldestBufferedID returns the ID of the oldest event still held in jobID's
+// ring buffer, or 0 if the hub holds nothing for it - either because it's
+// never published an event, or because the process has restarted since.
+// Callers use this to tell a genuine gap (nothing to replay) apart from a
+// reconnect the buffer can already satisfy.
+func (h *Hub) OldestBufferedID(jobID int64) uint64 {
+	h.mu.Lock()
+	js, ok := h.jobs[jobID]
+	h.mu.Unlock()
+	if !ok {
+		return 0
+	}
+
+	js.mu.Lock()
+	defer js.mu.Unlock()
+	if len(js.buffer) == 0 {
+		return 0
+	}
+	return js.buffer[0].ID
+}
@@ -0,0 +1,343 @@
+// Package operations tracks long-running, cancellable jobs (migration
+// syncs, cutovers, source environment discovery) so clients can poll
+// progress, cancel mid-flight, or subscribe to status transitions instead
+// of firing a goroutine and getting back only a status string. The design
+// mirrors LXD's operations subsystem.
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sp00nznet/octopus/internal/db"
+)
+
+// Class distinguishes a plain background task from one a client is
+// expected to stream events from over a WebSocket.
+type Class string
+
+const (
+	ClassTask      Class = "task"
+	ClassWebsocket Class = "websocket"
+)
+
+// Status is the lifecycle state of an Operation.
+type Status string
+
+const (
+	StatusPending   Status = "pending"
+	StatusRunning   Status = "running"
+	StatusSuccess   Status = "success"
+	StatusFailure   Status = "failure"
+	StatusCancelled Status = "cancelled"
+)
+
+// terminal reports whether status is one an Operation can no longer leave.
+func (s Status) terminal() bool {
+	return s == StatusSuccess || s == StatusFailure || s == StatusCancelled
+}
+
+// Update is broadcast to subscribers whenever an Operation's status,
+// progress, or metadata changes.
+type Update struct {
+	Status   Status                 `json:"status"`
+	Progress int                    `json:"progress"`
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+	Err      string                 `json:"error,omitempty"`
+}
+
+// Snapshot is the JSON representation of an Operation returned by the API.
+type Snapshot struct {
+	ID           string                 `json:"id"`
+	Class        Class                  `json:"class"`
+	ResourceType string                 `json:"resource_type"`
+	ResourceID   int64                  `json:"resource_id"`
+	Status       Status                 `json:"status"`
+	Progress     int                    `json:"progress"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+	Err          string                 `json:"error,omitempty"`
+	CreatedAt    time.Time              `json:"created_at"`
+	UpdatedAt    time.Time              `json:"updated_at"`
+}
+
+func (s Snapshot) update() Update {
+	return Update{Status: s.Status, Progress: s.Progress, Metadata: s.Metadata, Err: s.Err}
+}
+
+// Operation tracks a single long-running, cancellable job: a migration
+// sync, a cutover, a source environment discovery scan, and so on.
+// ResourceType/ResourceID identify what the operation is acting on (e.g.
+// "migration_job" and migration_jobs.id) so it can be reconciled against
+// that table's own status column.
+type Operation struct {
+	ID           string
+	Class        Class
+	ResourceType string
+	ResourceID   int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	status      Status
+	progress    int
+	metadata    map[string]interface{}
+	err         string
+	createdAt   time.Time
+	updatedAt   time.Time
+	subscribers map[chan Update]struct{}
+
+	manager *Manager
+}
+
+// Manager tracks all Operations created by this process and persists them
+// to the operations table.
+type Manager struct {
+	db *db.Database
+
+	mu  sync.Mutex
+	ops map[string]*Operation
+}
+
+// NewManager creates an operations Manager backed by database.
+func NewManager(database *db.Database) *Manager {
+	return &Manager{db: database, ops: make(map[string]*Operation)}
+}
+
+// Create registers a new pending Operation against resourceType/resourceID
+// and persists its initial row.
+func (m *Manager) Create(class Class, resourceType string, resourceID int64) *Operation {
+	ctx, cancel := context.WithCancel(context.Background())
+	now := time.Now()
+
+	op := &Operation{
+		ID:           uuid.NewString(),
+		Class:        class,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		ctx:          ctx,
+		cancel:       cancel,
+		status:       StatusPending,
+		metadata:     map[string]interface{}{},
+		createdAt:    now,
+		updatedAt:    now,
+		subscribers:  make(map[chan Update]struct{}),
+		manager:      m,
+	}
+
+	m.mu.Lock()
+	m.ops[op.ID] = op
+	m.mu.Unlock()
+
+	op.persist()
+	return op
+}
+
+// Get returns a tracked Operation by ID.
+func (m *Manager) Get(id string) (*Operation, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	op, ok := m.ops[id]
+	return op, ok
+}
+
+// List returns all Operations tracked by this process, most recently
+// created first.
+func (m *Manager) List() []*Operation {
+	m.mu.Lock()
+	ops := make([]*Operation, 0, len(m.ops))
+	for _, op := range m.ops {
+		ops = append(ops, op)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(ops, func(i, j int) bool { return ops[i].createdAt.After(ops[j].createdAt) })
+	return ops
+}
+
+// Run marks the Operation running and executes fn in a goroutine under its
+// cancellable context, moving it to success/failure/cancelled once fn
+// returns.
+func (op *Operation) Run(fn func(ctx context.Context) error) {
+	op.setStatus(StatusRunning, "")
+
+	go func() {
+		err := fn(op.ctx)
+		switch {
+		case op.ctx.Err() == context.Canceled:
+			op.setStatus(StatusCancelled, "")
+		case err != nil:
+			op.setStatus(StatusFailure, err.Error())
+		default:
+			op.setStatus(StatusSuccess, "")
+		}
+	}()
+}
+
+// Context returns the Operation's cancellable context, for the running job
+// to check for cancellation and thread through to SDK calls.
+func (op *Operation) Context() context.Context {
+	return op.ctx
+}
+
+// SetProgress updates progress and merges metadata, broadcasting the change
+// to subscribers and persisting it.
+func (op *Operation) SetProgress(progress int, metadata map[string]interface{}) {
+	op.mu.Lock()
+	op.progress = progress
+	for k, v := range metadata {
+		op.metadata[k] = v
+	}
+	op.updatedAt = time.Now()
+	op.mu.Unlock()
+
+	op.broadcast()
+	op.persist()
+}
+
+func (op *Operation) setStatus(status Status, errMsg string) {
+	op.mu.Lock()
+	op.status = status
+	op.err = errMsg
+	if status == StatusSuccess {
+		op.progress = 100
+	}
+	op.updatedAt = time.Now()
+	subscribers := op.subscribers
+	op.mu.Unlock()
+
+	op.broadcast()
+	op.persist()
+
+	if status.terminal() {
+		op.mu.Lock()
+		for ch := range subscribers {
+			delete(op.subscribers, ch)
+			close(ch)
+		}
+		op.mu.Unlock()
+	}
+}
+
+// Cancel requests cancellation of the running job via its context. The job
+// itself must observe ctx.Done() for this to take effect.
+func (op *Operation) Cancel() {
+	op.cancel()
+}
+
+// Wait blocks until the Operation reaches a terminal status or timeout
+// elapses, returning whichever status it ends up in.
+func (op *Operation) Wait(timeout time.Duration) Status {
+	if s := op.Snapshot().Status; s.terminal() {
+		return s
+	}
+
+	updates, unsubscribe := op.Subscribe()
+	defer unsubscribe()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case u, ok := <-updates:
+			if !ok {
+				return op.Snapshot().Status
+			}
+			if u.Status.terminal() {
+				return u.Status
+			}
+		case <-timer.C:
+			return op.Snapshot().Status
+		}
+	}
+}
+
+// Subscribe registers a channel that receives an Update every time the
+// Operation's status, progress, or metadata changes. The channel is closed
+// once the Operation reaches a terminal status. Call the returned function
+// to unsubscribe early.
+func (op *Operation) Subscribe() (<-chan Update, func()) {
+	ch := make(chan Update, 8)
+
+	op.mu.Lock()
+	op.subscribers[ch] = struct{}{}
+	op.mu.Unlock()
+
+	unsubscribe := func() {
+		op.mu.Lock()
+		if _, ok := op.subscribers[ch]; ok {
+			delete(op.subscribers, ch)
+			close(ch)
+		}
+		op.mu.Unlock()
+	}
+	return ch, unsubscribe
+}
+
+func (op *Operation) broadcast() {
+	snap := op.Snapshot()
+	update := snap.update()
+
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	for ch := range op.subscribers {
+		select {
+		case ch <- update:
+		default:
+			// Subscriber too slow to keep up; drop the update rather than
+			// block the job that's making progress.
+		}
+	}
+}
+
+// Snapshot returns a point-in-time, JSON-serializable copy of the
+// Operation's state.
+func (op *Operation) Snapshot() Snapshot {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+
+	metadata := make(map[string]interface{}, len(op.metadata))
+	for k, v := range op.metadata {
+		metadata[k] = v
+	}
+
+	return Snapshot{
+		ID:           op.ID,
+		Class:        op.Class,
+		ResourceType: op.ResourceType,
+		ResourceID:   op.ResourceID,
+		Status:       op.status,
+		Progress:     op.progress,
+		Metadata:     metadata,
+		Err:          op.err,
+		CreatedAt:    op.createdAt,
+		UpdatedAt:    op.updatedAt,
+	}
+}
+
+func (op *Operation) persist() {
+	snap := op.Snapshot()
+
+	metadataJSON, err := json.Marshal(snap.Metadata)
+	if err != nil {
+		metadataJSON = []byte("{}")
+	}
+
+	_, err = op.manager.db.Exec(`
+		INSERT INTO operations (id, class, resource_type, resource_id, status, progress, metadata_json, error_message, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(id) DO UPDATE SET
+			status=?, progress=?, metadata_json=?, error_message=?, updated_at=?
+	`, snap.ID, snap.Class, snap.ResourceType, snap.ResourceID, snap.Status, snap.Progress,
+		string(metadataJSON), snap.Err, snap.CreatedAt, snap.UpdatedAt,
+		snap.Status, snap.Progress, string(metadataJSON), snap.Err, snap.UpdatedAt)
+	if err != nil {
+		log.Printf("operations: failed to persist operation %s: %v", snap.ID, err)
+	}
+}
@@ -0,0 +1,111 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/pricing"
+	"github.com/aws/aws-sdk-go-v2/service/pricing/types"
+)
+
+// AWSSource fetches on-demand rates from the AWS Price List Query API
+// (GetProducts against AmazonEC2/AmazonEBS). The API is only published in
+// us-east-1 and ap-south-1 regardless of the region being priced, so the
+// client is always built against us-east-1.
+type AWSSource struct {
+	client *pricing.Client
+}
+
+// NewAWSSource creates an AWSSource using the default AWS credential chain.
+func NewAWSSource(ctx context.Context) (*AWSSource, error) {
+	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion("us-east-1"))
+	if err != nil {
+		return nil, fmt.Errorf("aws pricing: load config: %w", err)
+	}
+	return &AWSSource{client: pricing.NewFromConfig(cfg)}, nil
+}
+
+// awsPriceListTerm is a minimal decode of GetProducts' priceList JSON
+// strings - just enough to pull the USD on-demand hourly rate, not the full
+// term/dimension schema.
+type awsPriceListTerm struct {
+	Terms struct {
+		OnDemand map[string]struct {
+			PriceDimensions map[string]struct {
+				PricePerUnit struct {
+					USD string `json:"USD"`
+				} `json:"pricePerUnit"`
+			} `json:"priceDimensions"`
+		} `json:"OnDemand"`
+	} `json:"terms"`
+}
+
+// FetchPrice implements PriceSource. This is a simplified version: it reads
+// the first OnDemand price dimension off the first matching product and
+// ignores reserved/spot terms, storage IOPS pricing, and multi-AZ deals -
+// good enough for a monthly cost estimate, not for a real invoice
+// reconciliation.
+func (s *AWSSource) FetchPrice(ctx context.Context, q Query) (*Price, error) {
+	computeRate, err := s.onDemandRate(ctx, "AmazonEC2", []types.Filter{
+		{Type: types.FilterTypeTermMatch, Field: aws.String("instanceType"), Value: aws.String(q.InstanceFamily)},
+		{Type: types.FilterTypeTermMatch, Field: aws.String("regionCode"), Value: aws.String(q.Region)},
+		{Type: types.FilterTypeTermMatch, Field: aws.String("tenancy"), Value: aws.String("Shared")},
+		{Type: types.FilterTypeTermMatch, Field: aws.String("operatingSystem"), Value: aws.String("Linux")},
+		{Type: types.FilterTypeTermMatch, Field: aws.String("preInstalledSw"), Value: aws.String("NA")},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws: compute rate: %w", err)
+	}
+
+	storageRate, err := s.onDemandRate(ctx, "AmazonEBS", []types.Filter{
+		{Type: types.FilterTypeTermMatch, Field: aws.String("volumeApiName"), Value: aws.String(q.DiskType)},
+		{Type: types.FilterTypeTermMatch, Field: aws.String("regionCode"), Value: aws.String(q.Region)},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws: storage rate: %w", err)
+	}
+
+	return &Price{
+		ComputeHourly:       computeRate,
+		StorageMonthlyPerGB: storageRate,
+		// Data Transfer Out to internet is tiered; the flat first-tier rate
+		// is the same simplification the old hardcoded table made.
+		NetworkPerGBEgress: 0.09,
+		Timestamp:          time.Now(),
+	}, nil
+}
+
+func (s *AWSSource) onDemandRate(ctx context.Context, serviceCode string, filters []types.Filter) (float64, error) {
+	out, err := s.client.GetProducts(ctx, &pricing.GetProductsInput{
+		ServiceCode: aws.String(serviceCode),
+		Filters:     filters,
+		MaxResults:  aws.Int32(1),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(out.PriceList) == 0 {
+		return 0, fmt.Errorf("no matching products for %s", serviceCode)
+	}
+
+	var parsed awsPriceListTerm
+	if err := json.Unmarshal([]byte(out.PriceList[0]), &parsed); err != nil {
+		return 0, fmt.Errorf("decode price list entry: %w", err)
+	}
+
+	for _, term := range parsed.Terms.OnDemand {
+		for _, dimension := range term.PriceDimensions {
+			var rate float64
+			if _, err := fmt.Sscanf(dimension.PricePerUnit.USD, "%f", &rate); err != nil {
+				continue
+			}
+			return rate, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no OnDemand price dimension found")
+}
@@ -0,0 +1,90 @@
+package pricing
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// retailPricesEndpoint is Azure's public, unauthenticated Retail Prices API -
+// unlike AWS/GCP it needs no SDK or credentials, just a filtered GET.
+const retailPricesEndpoint = "https://prices.azure.com/api/retail/prices"
+
+// AzureSource fetches compute/storage rates from the Azure Retail Prices
+// API for the "Consumption" price type, matching armSkuName against
+// InstanceFamily and a Premium SSD meter against DiskType.
+type AzureSource struct {
+	httpClient *http.Client
+}
+
+// NewAzureSource creates an AzureSource with a bounded request timeout.
+func NewAzureSource() *AzureSource {
+	return &AzureSource{httpClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+type retailPricesResponse struct {
+	Items []struct {
+		RetailPrice   float64 `json:"retailPrice"`
+		UnitOfMeasure string  `json:"unitOfMeasure"`
+		MeterName     string  `json:"meterName"`
+		ProductName   string  `json:"productName"`
+	} `json:"Items"`
+}
+
+// FetchPrice implements PriceSource.
+func (s *AzureSource) FetchPrice(ctx context.Context, q Query) (*Price, error) {
+	computePrice, err := s.queryPrice(ctx, fmt.Sprintf(
+		"armRegionName eq '%s' and armSkuName eq '%s' and priceType eq 'Consumption'",
+		q.Region, q.InstanceFamily))
+	if err != nil {
+		return nil, fmt.Errorf("azure: query compute price: %w", err)
+	}
+
+	storagePrice, err := s.queryPrice(ctx, fmt.Sprintf(
+		"armRegionName eq '%s' and skuName eq '%s' and priceType eq 'Consumption' and contains(meterName, 'Data Stored')",
+		q.Region, q.DiskType))
+	if err != nil {
+		return nil, fmt.Errorf("azure: query storage price: %w", err)
+	}
+
+	return &Price{
+		ComputeHourly:       computePrice,
+		StorageMonthlyPerGB: storagePrice,
+		// Azure bills standard egress per GB past a free tier; approximating
+		// that tier away here is the same simplification the old hardcoded
+		// table made.
+		NetworkPerGBEgress: 0.087,
+		Timestamp:          time.Now(),
+	}, nil
+}
+
+func (s *AzureSource) queryPrice(ctx context.Context, filter string) (float64, error) {
+	reqURL := retailPricesEndpoint + "?$filter=" + url.QueryEscape(filter)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	var parsed retailPricesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return 0, fmt.Errorf("decode response: %w", err)
+	}
+	if len(parsed.Items) == 0 {
+		return 0, fmt.Errorf("no matching price items")
+	}
+
+	return parsed.Items[0].RetailPrice, nil
+}
@@ -0,0 +1,69 @@
+package pricing
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Cache is an on-disk JSON cache of Prices, keyed by
+// (provider, region, instance_family, disk_type), so EstimateCost doesn't
+// re-hit a provider's pricing API on every call.
+type Cache struct {
+	dir string
+	ttl time.Duration
+}
+
+// NewCache creates a Cache rooted at dir, with entries expiring after ttl.
+// dir is created on first write if it doesn't already exist.
+func NewCache(dir string, ttl time.Duration) *Cache {
+	return &Cache{dir: dir, ttl: ttl}
+}
+
+type cacheEntry struct {
+	CachedAt time.Time `json:"cached_at"`
+	Price    Price     `json:"price"`
+}
+
+func cacheKey(q Query) string {
+	return fmt.Sprintf("%s_%s_%s_%s", q.Provider, q.Region, q.InstanceFamily, q.DiskType)
+}
+
+func (c *Cache) path(q Query) string {
+	return filepath.Join(c.dir, "price_"+cacheKey(q)+".json")
+}
+
+// Get returns the cached price for q, or ok=false if there's no cache entry
+// or it's older than ttl.
+func (c *Cache) Get(q Query) (price *Price, ok bool) {
+	data, err := os.ReadFile(c.path(q))
+	if err != nil {
+		return nil, false
+	}
+	var entry cacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, false
+	}
+	if time.Since(entry.CachedAt) > c.ttl {
+		return nil, false
+	}
+	return &entry.Price, true
+}
+
+// Set writes price to the cache under q.
+func (c *Cache) Set(q Query, price *Price) error {
+	if err := os.MkdirAll(c.dir, 0o755); err != nil {
+		return fmt.Errorf("pricing: create cache dir: %w", err)
+	}
+	entry := cacheEntry{CachedAt: time.Now(), Price: *price}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("pricing: marshal cache entry: %w", err)
+	}
+	if err := os.WriteFile(c.path(q), data, 0o644); err != nil {
+		return fmt.Errorf("pricing: write cache entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	billing "cloud.google.com/go/billing/apiv1"
+	billingpb "cloud.google.com/go/billing/apiv1/billingpb"
+	"google.golang.org/api/iterator"
+)
+
+// computeEngineServiceName is the Cloud Billing Catalog API's fixed service
+// ID for Compute Engine (covers both instance and persistent-disk SKUs).
+const computeEngineServiceName = "services/6F81-5844-456A"
+
+// GCPSource fetches on-demand rates from the Cloud Billing Catalog API
+// (ListSkus against the Compute Engine service).
+type GCPSource struct {
+	client *billing.CloudCatalogClient
+}
+
+// NewGCPSource creates a GCPSource using the default Google credential chain.
+func NewGCPSource(ctx context.Context) (*GCPSource, error) {
+	client, err := billing.NewCloudCatalogClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("gcp pricing: create catalog client: %w", err)
+	}
+	return &GCPSource{client: client}, nil
+}
+
+// FetchPrice implements PriceSource. This is a simplified version: it
+// matches SKUs by substring on InstanceFamily/DiskType and region, reads
+// only the first tiered rate (ignoring committed-use and sustained-use
+// discount SKUs), and assumes USD billing - good enough for a monthly
+// estimate, not an exact invoice reconciliation.
+func (s *GCPSource) FetchPrice(ctx context.Context, q Query) (*Price, error) {
+	computeRate, err := s.firstMatchingRate(ctx, q.Region, q.InstanceFamily)
+	if err != nil {
+		return nil, fmt.Errorf("gcp: compute rate: %w", err)
+	}
+
+	storageRate, err := s.firstMatchingRate(ctx, q.Region, q.DiskType)
+	if err != nil {
+		return nil, fmt.Errorf("gcp: storage rate: %w", err)
+	}
+
+	return &Price{
+		ComputeHourly:       computeRate,
+		StorageMonthlyPerGB: storageRate,
+		// GCP egress to internet is also tiered; flattened to the first-tier
+		// rate, matching the old hardcoded table's simplification.
+		NetworkPerGBEgress: 0.12,
+		Timestamp:          time.Now(),
+	}, nil
+}
+
+func (s *GCPSource) firstMatchingRate(ctx context.Context, region, descriptionSubstr string) (float64, error) {
+	it := s.client.ListSkus(ctx, &billingpb.ListSkusRequest{
+		Parent: computeEngineServiceName,
+	})
+
+	for {
+		sku, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		if !strings.Contains(sku.GetDescription(), descriptionSubstr) {
+			continue
+		}
+		if !skuServesRegion(sku, region) {
+			continue
+		}
+
+		for _, info := range sku.GetPricingInfo() {
+			tiers := info.GetPricingExpression().GetTieredRates()
+			if len(tiers) == 0 {
+				continue
+			}
+			unitPrice := tiers[0].GetUnitPrice()
+			return float64(unitPrice.GetUnits()) + float64(unitPrice.GetNanos())/1e9, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no matching SKU for %q in %s", descriptionSubstr, region)
+}
+
+func skuServesRegion(sku *billingpb.Sku, region string) bool {
+	for _, loc := range sku.GetServiceRegions() {
+		if loc == region {
+			return true
+		}
+	}
+	return false
+}
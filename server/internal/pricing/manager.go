@@ -0,0 +1,63 @@
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// DefaultTTL is how long a cached price is trusted before Manager re-queries
+// the provider's pricing API.
+const DefaultTTL = 24 * time.Hour
+
+// Manager resolves Queries against a per-provider PriceSource, caching
+// successful results on disk. It does not itself fall back to a hardcoded
+// table on error - EstimateCost does that, since the fallback rate depends
+// on the VM shape being estimated, not just the Query.
+type Manager struct {
+	sources map[string]PriceSource
+	cache   *Cache
+}
+
+// NewManager creates a Manager backed by cache, dispatching to sources keyed
+// by provider name ("aws", "gcp", "azure").
+func NewManager(cache *Cache, sources map[string]PriceSource) *Manager {
+	return &Manager{sources: sources, cache: cache}
+}
+
+// GetPrice returns q's price, from cache if fresh, otherwise by querying the
+// matching PriceSource and caching the result.
+func (m *Manager) GetPrice(ctx context.Context, q Query) (*Price, error) {
+	if m.cache != nil {
+		if price, ok := m.cache.Get(q); ok {
+			return price, nil
+		}
+	}
+	return m.RefreshPrice(ctx, q)
+}
+
+// RefreshPrice queries q's PriceSource directly, bypassing the cache, and
+// stores the result. Used by GetPrice on a cache miss and by the admin
+// force-refresh endpoint.
+func (m *Manager) RefreshPrice(ctx context.Context, q Query) (*Price, error) {
+	source, ok := m.sources[q.Provider]
+	if !ok {
+		return nil, fmt.Errorf("pricing: no price source registered for provider %q", q.Provider)
+	}
+
+	price, err := source.FetchPrice(ctx, q)
+	if err != nil {
+		return nil, fmt.Errorf("pricing: fetch price for %s/%s: %w", q.Provider, q.Region, err)
+	}
+
+	if m.cache != nil {
+		if err := m.cache.Set(q, price); err != nil {
+			// Cache writes are best-effort - a cold/unwritable cache dir
+			// shouldn't block a price lookup.
+			fmt.Fprintf(os.Stderr, "pricing: failed to write cache entry for %s/%s: %v\n", q.Provider, q.Region, err)
+		}
+	}
+
+	return price, nil
+}
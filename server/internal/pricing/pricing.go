@@ -0,0 +1,34 @@
+// Package pricing resolves a target provider's live on-demand prices for
+// EstimateCost, replacing the hardcoded per-vCPU/per-GB rates that drift out
+// of date. Each provider implements PriceSource against its own pricing API;
+// Manager layers a disk-backed cache on top, and EstimateCost falls back to
+// hardcoded rates when a live lookup fails.
+package pricing
+
+import (
+	"context"
+	"time"
+)
+
+// Query identifies the price Manager.GetPrice should resolve: a provider's
+// per-instance-family compute rate plus its per-disk-type storage rate in a
+// given region.
+type Query struct {
+	Provider       string // "aws", "gcp", or "azure"
+	Region         string
+	InstanceFamily string // e.g. "m5.xlarge", "n2-standard-4", "Standard_D4s_v3"
+	DiskType       string // e.g. "gp3", "pd-ssd", "Premium_LRS"
+}
+
+// Price is one point-in-time quote for a Query.
+type Price struct {
+	ComputeHourly       float64   `json:"compute_hourly"`
+	StorageMonthlyPerGB float64   `json:"storage_monthly_per_gb"`
+	NetworkPerGBEgress  float64   `json:"network_per_gb_egress"`
+	Timestamp           time.Time `json:"timestamp"`
+}
+
+// PriceSource fetches a live Price for q from one provider's pricing API.
+type PriceSource interface {
+	FetchPrice(ctx context.Context, q Query) (*Price, error)
+}
@@ -10,6 +10,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/ec2"
 	"github.com/aws/aws-sdk-go-v2/service/ec2/types"
+	"github.com/sp00nznet/octopus/internal/cloudclient"
 )
 
 // Client wraps the AWS EC2 client for migration operations
@@ -17,6 +18,11 @@ type Client struct {
 	ec2Client *ec2.Client
 	ctx       context.Context
 	region    string
+
+	// rl throttles and retries EC2 calls - AWS rate-limits RunInstances/
+	// StartInstances/StopInstances far more aggressively than the
+	// Describe* read calls, hence separate read/write buckets.
+	rl *cloudclient.RateLimitedClient
 }
 
 // Config holds AWS configuration
@@ -46,6 +52,7 @@ func NewClient(cfg Config) (*Client, error) {
 		ec2Client: ec2.NewFromConfig(awsCfg),
 		ctx:       ctx,
 		region:    cfg.Region,
+		rl:        cloudclient.New(cloudclient.Options{Provider: "aws"}),
 	}, nil
 }
 
@@ -142,7 +149,12 @@ func (c *Client) GetInstanceInfo(instanceID string) (map[string]interface{}, err
 		InstanceIds: []string{instanceID},
 	}
 
-	result, err := c.ec2Client.DescribeInstances(c.ctx, input)
+	var result *ec2.DescribeInstancesOutput
+	err := c.rl.Do("DescribeInstances", false, func() error {
+		var err error
+		result, err = c.ec2Client.DescribeInstances(c.ctx, input)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -154,19 +166,190 @@ func (c *Client) GetInstanceInfo(instanceID string) (map[string]interface{}, err
 	instance := result.Reservations[0].Instances[0]
 
 	info := map[string]interface{}{
-		"instance_id":    *instance.InstanceId,
-		"instance_type":  string(instance.InstanceType),
-		"state":          string(instance.State.Name),
-		"private_ip":     safeString(instance.PrivateIpAddress),
-		"public_ip":      safeString(instance.PublicIpAddress),
-		"vpc_id":         safeString(instance.VpcId),
-		"subnet_id":      safeString(instance.SubnetId),
-		"launch_time":    instance.LaunchTime,
+		"instance_id":   *instance.InstanceId,
+		"instance_type": string(instance.InstanceType),
+		"state":         string(instance.State.Name),
+		"private_ip":    safeString(instance.PrivateIpAddress),
+		"public_ip":     safeString(instance.PublicIpAddress),
+		"vpc_id":        safeString(instance.VpcId),
+		"subnet_id":     safeString(instance.SubnetId),
+		"launch_time":   instance.LaunchTime,
 	}
 
 	return info, nil
 }
 
+// StartInstance powers on a stopped EC2 instance
+func (c *Client) StartInstance(instanceID string) error {
+	return c.rl.Do("StartInstances", true, func() error {
+		_, err := c.ec2Client.StartInstances(c.ctx, &ec2.StartInstancesInput{
+			InstanceIds: []string{instanceID},
+		})
+		return err
+	})
+}
+
+// StopInstance powers off a running EC2 instance
+func (c *Client) StopInstance(instanceID string) error {
+	return c.rl.Do("StopInstances", true, func() error {
+		_, err := c.ec2Client.StopInstances(c.ctx, &ec2.StopInstancesInput{
+			InstanceIds: []string{instanceID},
+		})
+		return err
+	})
+}
+
+// StartVM powers on a stopped EC2 instance. It implements cloud.TargetActions
+// for admin lifecycle calls that need to honor the caller's context, unlike
+// StartInstance above (which predates that and always runs with c.ctx).
+func (c *Client) StartVM(ctx context.Context, instanceID string) error {
+	return c.rl.Do("StartInstances", true, func() error {
+		_, err := c.ec2Client.StartInstances(ctx, &ec2.StartInstancesInput{
+			InstanceIds: []string{instanceID},
+		})
+		return err
+	})
+}
+
+// StopVM powers off a running EC2 instance. force maps to StopInstancesInput's
+// Force flag, which skips a graceful in-guest shutdown.
+func (c *Client) StopVM(ctx context.Context, instanceID string, force bool) error {
+	return c.rl.Do("StopInstances", true, func() error {
+		_, err := c.ec2Client.StopInstances(ctx, &ec2.StopInstancesInput{
+			InstanceIds: []string{instanceID},
+			Force:       aws.Bool(force),
+		})
+		return err
+	})
+}
+
+// ResizeVM changes instanceID's instance type. EC2 only allows
+// ModifyInstanceAttribute's InstanceType change while the instance is
+// stopped, so this stops it, waits for the stop to land, applies the
+// change, and restarts it. newInstanceType is validated against
+// ListValidResizeTargets first so a bad request fails before the instance
+// is ever stopped.
+func (c *Client) ResizeVM(ctx context.Context, instanceID, newInstanceType string) error {
+	valid, err := c.ListValidResizeTargets(ctx, instanceID)
+	if err != nil {
+		return fmt.Errorf("validate resize target for %s: %w", instanceID, err)
+	}
+	ok := false
+	for _, t := range valid {
+		if t == newInstanceType {
+			ok = true
+			break
+		}
+	}
+	if !ok {
+		return fmt.Errorf("instance type %q is not a valid resize target for %s (unavailable in its AZ, or architecture mismatch)", newInstanceType, instanceID)
+	}
+
+	if err := c.StopVM(ctx, instanceID, false); err != nil {
+		return fmt.Errorf("stop instance %s before resize: %w", instanceID, err)
+	}
+	stopWaiter := ec2.NewInstanceStoppedWaiter(c.ec2Client)
+	if err := stopWaiter.Wait(ctx, &ec2.DescribeInstancesInput{InstanceIds: []string{instanceID}}, 5*time.Minute); err != nil {
+		return fmt.Errorf("wait for instance %s to stop before resize: %w", instanceID, err)
+	}
+
+	_, err = c.ec2Client.ModifyInstanceAttribute(ctx, &ec2.ModifyInstanceAttributeInput{
+		InstanceId:   aws.String(instanceID),
+		InstanceType: &types.AttributeValue{Value: aws.String(newInstanceType)},
+	})
+	if err != nil {
+		return fmt.Errorf("modify instance type for %s: %w", instanceID, err)
+	}
+
+	if err := c.StartVM(ctx, instanceID); err != nil {
+		return fmt.Errorf("restart instance %s after resize: %w", instanceID, err)
+	}
+	return nil
+}
+
+// ListValidResizeTargets lists instance types available in instanceID's
+// current availability zone whose processor architecture matches its
+// current instance type - a resize across architectures (e.g. x86_64 to
+// Graviton) isn't something a running OS image survives.
+func (c *Client) ListValidResizeTargets(ctx context.Context, instanceID string) ([]string, error) {
+	descOut, err := c.ec2Client.DescribeInstances(ctx, &ec2.DescribeInstancesInput{
+		InstanceIds: []string{instanceID},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describe instance %s: %w", instanceID, err)
+	}
+	if len(descOut.Reservations) == 0 || len(descOut.Reservations[0].Instances) == 0 {
+		return nil, fmt.Errorf("instance %s not found", instanceID)
+	}
+	instance := descOut.Reservations[0].Instances[0]
+	currentType := instance.InstanceType
+	az := safeString(instance.Placement.AvailabilityZone)
+
+	arch, err := c.instanceTypeArch(ctx, currentType)
+	if err != nil {
+		return nil, err
+	}
+
+	offerings, err := c.ec2Client.DescribeInstanceTypeOfferings(ctx, &ec2.DescribeInstanceTypeOfferingsInput{
+		LocationType: types.LocationTypeAvailabilityZone,
+		Filters: []types.Filter{
+			{Name: aws.String("location"), Values: []string{az}},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describe instance type offerings in %s: %w", az, err)
+	}
+	if len(offerings.InstanceTypeOfferings) == 0 {
+		return nil, nil
+	}
+
+	candidates := make([]types.InstanceType, 0, len(offerings.InstanceTypeOfferings))
+	for _, o := range offerings.InstanceTypeOfferings {
+		candidates = append(candidates, o.InstanceType)
+	}
+
+	typesOut, err := c.ec2Client.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: candidates,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("describe instance types: %w", err)
+	}
+
+	var valid []string
+	for _, it := range typesOut.InstanceTypes {
+		if it.InstanceType == currentType {
+			continue
+		}
+		if it.ProcessorInfo == nil {
+			continue
+		}
+		for _, a := range it.ProcessorInfo.SupportedArchitectures {
+			if a == arch {
+				valid = append(valid, string(it.InstanceType))
+				break
+			}
+		}
+	}
+	return valid, nil
+}
+
+// instanceTypeArch returns instanceType's supported processor architecture
+// (the first one EC2 reports, since today's non-Mac instance types only
+// report one).
+func (c *Client) instanceTypeArch(ctx context.Context, instanceType types.InstanceType) (types.ArchitectureType, error) {
+	out, err := c.ec2Client.DescribeInstanceTypes(ctx, &ec2.DescribeInstanceTypesInput{
+		InstanceTypes: []types.InstanceType{instanceType},
+	})
+	if err != nil {
+		return "", fmt.Errorf("describe instance type %s: %w", instanceType, err)
+	}
+	if len(out.InstanceTypes) == 0 || out.InstanceTypes[0].ProcessorInfo == nil ||
+		len(out.InstanceTypes[0].ProcessorInfo.SupportedArchitectures) == 0 {
+		return "", fmt.Errorf("no architecture info for instance type %s", instanceType)
+	}
+	return out.InstanceTypes[0].ProcessorInfo.SupportedArchitectures[0], nil
+}
+
 // CreateSnapshot creates an EBS snapshot
 func (c *Client) CreateSnapshot(volumeID, description string) (string, error) {
 	input := &ec2.CreateSnapshotInput{
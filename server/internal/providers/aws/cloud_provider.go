@@ -0,0 +1,117 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sp00nznet/octopus/internal/cloud"
+)
+
+// Options configures the "aws" cloud.Provider driver, decoded from the
+// job/environment's driver-specific JSON/YAML options.
+type Options struct {
+	Region          string `json:"region" yaml:"region"`
+	AccessKeyID     string `json:"access_key_id" yaml:"access_key_id"`
+	SecretAccessKey string `json:"secret_access_key" yaml:"secret_access_key"`
+}
+
+func init() {
+	cloud.Register("aws", newProvider)
+}
+
+func newProvider(options json.RawMessage) (cloud.Provider, error) {
+	var opts Options
+	if len(options) > 0 {
+		if err := json.Unmarshal(options, &opts); err != nil {
+			return nil, fmt.Errorf("aws: invalid driver options: %w", err)
+		}
+	}
+
+	client, err := NewClient(Config{
+		Region:          opts.Region,
+		AccessKeyID:     opts.AccessKeyID,
+		SecretAccessKey: opts.SecretAccessKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws: %w", err)
+	}
+
+	return &provider{client: client}, nil
+}
+
+// provider adapts Client to cloud.Provider.
+type provider struct {
+	client *Client
+}
+
+func (p *provider) Name() string { return "aws" }
+
+// CreateImageFromArtifact imports a migrated disk as an AMI. spec.ArtifactURI
+// is an "s3://bucket/key" URI; ImportVMImage only starts the import task and
+// returns its task ID - the caller is expected to poll GetImportStatus
+// itself if it needs the AMI ID before returning, the same async contract
+// ImportVMImage already exposes.
+func (p *provider) CreateImageFromArtifact(spec cloud.ImageSpec) (string, error) {
+	bucket, key, err := splitS3URI(spec.ArtifactURI)
+	if err != nil {
+		return "", err
+	}
+	return p.client.ImportVMImage(bucket, key, spec.Description, spec.OSType)
+}
+
+// splitS3URI parses an "s3://bucket/key" artifact URI into its bucket and
+// key parts.
+func splitS3URI(uri string) (bucket, key string, err error) {
+	trimmed := strings.TrimPrefix(uri, "s3://")
+	parts := strings.SplitN(trimmed, "/", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("aws: invalid artifact URI %q, expected s3://bucket/key", uri)
+	}
+	return parts[0], parts[1], nil
+}
+
+func (p *provider) CreateInstance(spec cloud.InstanceSpec) (string, error) {
+	return p.client.CreateInstanceFromAMI(spec.ImageName, spec.MachineType, spec.Subnet, spec.SecurityGroup, spec.PreserveMAC)
+}
+
+func (p *provider) Start(instanceID string) error { return p.client.StartInstance(instanceID) }
+func (p *provider) Stop(instanceID string) error  { return p.client.StopInstance(instanceID) }
+
+func (p *provider) Snapshot(spec cloud.SnapshotSpec) (string, error) {
+	return p.client.CreateSnapshot(spec.ResourceID, spec.Description)
+}
+
+func (p *provider) GetInstanceInfo(instanceID string) (cloud.InstanceInfo, error) {
+	info, err := p.client.GetInstanceInfo(instanceID)
+	if err != nil {
+		return cloud.InstanceInfo{}, err
+	}
+	status, _ := info["state"].(string)
+	return cloud.InstanceInfo{ID: instanceID, Name: instanceID, Status: status, Metadata: info}, nil
+}
+
+func (p *provider) EstimateMachineType(cpuCount int, memoryGB float64) string {
+	return EstimateInstanceType(cpuCount, memoryGB)
+}
+
+// StartVM implements cloud.TargetActions.
+func (p *provider) StartVM(ctx context.Context, instanceID string) error {
+	return p.client.StartVM(ctx, instanceID)
+}
+
+// StopVM implements cloud.TargetActions.
+func (p *provider) StopVM(ctx context.Context, instanceID string, force bool) error {
+	return p.client.StopVM(ctx, instanceID, force)
+}
+
+// ResizeVM implements cloud.TargetActions.
+func (p *provider) ResizeVM(ctx context.Context, instanceID, newInstanceType string) error {
+	return p.client.ResizeVM(ctx, instanceID, newInstanceType)
+}
+
+// ListValidResizeTargets implements cloud.TargetActions.
+func (p *provider) ListValidResizeTargets(ctx context.Context, instanceID string) ([]string, error) {
+	return p.client.ListValidResizeTargets(ctx, instanceID)
+}
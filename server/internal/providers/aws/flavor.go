@@ -0,0 +1,23 @@
+package aws
+
+import (
+	"errors"
+
+	"github.com/sp00nznet/octopus/internal/cloud"
+)
+
+// FlavorResolver will implement cloud.FlavorResolver against EC2's
+// DescribeInstanceTypes API, mirroring gcp.FlavorResolver. Not implemented
+// yet - EstimateInstanceType's static ladder in client.go is still what
+// backs EstimateMachineType.
+type FlavorResolver struct{}
+
+// NewFlavorResolver returns a FlavorResolver. Its ListCandidates always
+// errors until DescribeInstanceTypes support lands.
+func NewFlavorResolver(*Client) *FlavorResolver {
+	return &FlavorResolver{}
+}
+
+func (r *FlavorResolver) ListCandidates(cloud.FlavorRequest) ([]cloud.FlavorCandidate, error) {
+	return nil, errors.New("aws: live flavor catalog resolution not implemented yet")
+}
@@ -0,0 +1,266 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/mgn"
+	"github.com/aws/aws-sdk-go-v2/service/mgn/types"
+	"github.com/sp00nznet/octopus/internal/cloudclient"
+)
+
+// MGNClient wraps AWS Application Migration Service (MGN) for migration
+// jobs that need continuous block-level replication - sync_interval_minutes,
+// cutting_over, and test_failover - rather than the one-shot ImportVMImage
+// conversion in client.go, which has no notion of "resume replication" or
+// "test without disrupting the source".
+type MGNClient struct {
+	mgnClient *mgn.Client
+	ctx       context.Context
+	region    string
+
+	// rl throttles StartTest/StartCutover/FinalizeCutover, which MGN
+	// rate-limits harder than the Describe* calls, the same reasoning as
+	// Client.rl in client.go.
+	rl *cloudclient.RateLimitedClient
+}
+
+// ReplicationTemplateSpec configures CreateReplicationConfigurationTemplate.
+// It mirrors the handful of fields jobs in this repo actually need to set;
+// MGN's real API accepts many more (see mgn.CreateReplicationConfigurationTemplateInput).
+type ReplicationTemplateSpec struct {
+	StagingAreaSubnetID   string
+	ReplicationServerType string
+	StagingAreaTags       map[string]string
+	UseDedicatedServer    bool
+}
+
+// SourceServer is a provider-agnostic view of one MGN source server,
+// mapping to a row in the vms table via SourceServerID persisted as
+// vms.mgn_source_server_id.
+type SourceServer struct {
+	SourceServerID   string
+	LifeCycleState   string
+	ReplicationState string
+	ReplicationLag   time.Duration
+	LastLaunchResult string
+}
+
+// NewMGNClient creates a new MGN client for region using cfg's credentials.
+func NewMGNClient(cfg Config) (*MGNClient, error) {
+	ctx := context.Background()
+
+	awsCfg, err := config.LoadDefaultConfig(ctx,
+		config.WithRegion(cfg.Region),
+		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
+			cfg.AccessKeyID,
+			cfg.SecretAccessKey,
+			"",
+		)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+
+	return &MGNClient{
+		mgnClient: mgn.NewFromConfig(awsCfg),
+		ctx:       ctx,
+		region:    cfg.Region,
+		rl:        cloudclient.New(cloudclient.Options{Provider: "aws"}),
+	}, nil
+}
+
+// InitializeService activates MGN in this account/region. It's idempotent -
+// MGN returns success if the service is already initialized - so callers
+// can call it unconditionally before the first CreateReplicationConfigurationTemplate.
+func (c *MGNClient) InitializeService() error {
+	_, err := c.mgnClient.InitializeService(c.ctx, &mgn.InitializeServiceInput{})
+	if err != nil {
+		return fmt.Errorf("initialize MGN service: %w", err)
+	}
+	return nil
+}
+
+// CreateReplicationConfigurationTemplate creates the account-level template
+// new source servers inherit when MGN's replication agent first registers
+// them, returning the template ID.
+func (c *MGNClient) CreateReplicationConfigurationTemplate(spec ReplicationTemplateSpec) (string, error) {
+	input := &mgn.CreateReplicationConfigurationTemplateInput{
+		AssociateDefaultSecurityGroup:       aws.Bool(true),
+		BandwidthThrottling:                 0,
+		CreatePublicIP:                      aws.Bool(false),
+		DataPlaneRouting:                    types.ReplicationConfigurationDataPlaneRoutingPrivateIp,
+		DefaultLargeStagingDiskType:         types.ReplicationConfigurationDefaultLargeStagingDiskTypeGp3,
+		EbsEncryption:                       types.ReplicationConfigurationEbsEncryptionDefault,
+		ReplicationServerInstanceType:       aws.String(spec.ReplicationServerType),
+		ReplicationServersSecurityGroupsIDs: []string{},
+		StagingAreaSubnetId:                 aws.String(spec.StagingAreaSubnetID),
+		StagingAreaTags:                     spec.StagingAreaTags,
+		UseDedicatedReplicationServer:       aws.Bool(spec.UseDedicatedServer),
+	}
+
+	result, err := c.mgnClient.CreateReplicationConfigurationTemplate(c.ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("create replication configuration template: %w", err)
+	}
+	return aws.ToString(result.ReplicationConfigurationTemplateID), nil
+}
+
+// DescribeSourceServers lists every source server MGN's replication agent
+// has registered in this account/region. Unlike DescribeInstances elsewhere
+// in this package, it pages through NewDescribeSourceServersPaginator since
+// an account migrating hundreds of VMs can exceed a single page.
+func (c *MGNClient) DescribeSourceServers() ([]SourceServer, error) {
+	paginator := mgn.NewDescribeSourceServersPaginator(c.mgnClient, &mgn.DescribeSourceServersInput{})
+
+	var servers []SourceServer
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(c.ctx)
+		if err != nil {
+			return nil, fmt.Errorf("describe source servers: %w", err)
+		}
+		for _, item := range page.Items {
+			servers = append(servers, toSourceServer(item))
+		}
+	}
+	return servers, nil
+}
+
+// GetSourceServer describes a single source server by ID, for periodic
+// replication-lag polling where fetching the whole account's list would be
+// wasteful.
+func (c *MGNClient) GetSourceServer(sourceServerID string) (SourceServer, error) {
+	result, err := c.mgnClient.DescribeSourceServers(c.ctx, &mgn.DescribeSourceServersInput{
+		Filters: &types.DescribeSourceServersRequestFilters{
+			SourceServerIDs: []string{sourceServerID},
+		},
+	})
+	if err != nil {
+		return SourceServer{}, fmt.Errorf("describe source server %s: %w", sourceServerID, err)
+	}
+	if len(result.Items) == 0 {
+		return SourceServer{}, fmt.Errorf("source server %s not found", sourceServerID)
+	}
+	return toSourceServer(result.Items[0]), nil
+}
+
+// StartTest launches a non-destructive test instance from sourceServerID's
+// latest recovery point, without touching the source VM, returning the MGN
+// job ID so callers can poll it if needed.
+func (c *MGNClient) StartTest(sourceServerID string) (string, error) {
+	var jobID string
+	err := c.rl.Do("StartTest", true, func() error {
+		result, err := c.mgnClient.StartTest(c.ctx, &mgn.StartTestInput{
+			SourceServerIDs: []string{sourceServerID},
+		})
+		if err != nil {
+			return err
+		}
+		if result.Job != nil {
+			jobID = aws.ToString(result.Job.JobID)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("start test for source server %s: %w", sourceServerID, err)
+	}
+	return jobID, nil
+}
+
+// StartCutover launches the final cutover instance from sourceServerID's
+// latest recovery point. Unlike StartTest, the instance it creates is meant
+// to become the production target - FinalizeCutover marks replication for
+// it complete once the caller has verified it.
+func (c *MGNClient) StartCutover(sourceServerID string) (string, error) {
+	var jobID string
+	err := c.rl.Do("StartCutover", true, func() error {
+		result, err := c.mgnClient.StartCutover(c.ctx, &mgn.StartCutoverInput{
+			SourceServerIDs: []string{sourceServerID},
+		})
+		if err != nil {
+			return err
+		}
+		if result.Job != nil {
+			jobID = aws.ToString(result.Job.JobID)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("start cutover for source server %s: %w", sourceServerID, err)
+	}
+	return jobID, nil
+}
+
+// FinalizeCutover tells MGN the cutover instance for sourceServerID is now
+// the production server, stopping replication to it permanently.
+func (c *MGNClient) FinalizeCutover(sourceServerID string) error {
+	return c.rl.Do("FinalizeCutover", true, func() error {
+		_, err := c.mgnClient.FinalizeCutover(c.ctx, &mgn.FinalizeCutoverInput{
+			SourceServerID: aws.String(sourceServerID),
+		})
+		return err
+	})
+}
+
+// TerminateTestInstances tears down the test instance StartTest launched
+// for sourceServerID, once a test_failover task has finished verifying it.
+func (c *MGNClient) TerminateTestInstances(sourceServerID string) error {
+	return c.rl.Do("TerminateTargetInstances", true, func() error {
+		_, err := c.mgnClient.TerminateTargetInstances(c.ctx, &mgn.TerminateTargetInstancesInput{
+			SourceServerIDs: []string{sourceServerID},
+		})
+		return err
+	})
+}
+
+func toSourceServer(item types.SourceServer) SourceServer {
+	s := SourceServer{
+		SourceServerID: aws.ToString(item.SourceServerID),
+	}
+	if item.LifeCycle != nil {
+		s.LifeCycleState = string(item.LifeCycle.State)
+		// LifeCycleLastTest has no single "result" field - it's
+		// Initiated/Finalized/Reverted sub-structs that fill in as the
+		// test progresses - so report the furthest stage reached.
+		if lastTest := item.LifeCycle.LastTest; lastTest != nil {
+			switch {
+			case lastTest.Reverted != nil:
+				s.LastLaunchResult = "reverted"
+			case lastTest.Finalized != nil:
+				s.LastLaunchResult = "finalized"
+			case lastTest.Initiated != nil:
+				s.LastLaunchResult = "initiated"
+			}
+		}
+	}
+	if item.DataReplicationInfo != nil {
+		s.ReplicationState = string(item.DataReplicationInfo.DataReplicationState)
+		if item.DataReplicationInfo.LagDuration != nil {
+			// LagDuration comes back as an ISO-8601-ish "PT<seconds>S"
+			// string; parseLagDuration below only handles that simple
+			// whole-seconds form, which is what MGN actually emits.
+			s.ReplicationLag = parseLagDuration(aws.ToString(item.DataReplicationInfo.LagDuration))
+		}
+	}
+	return s
+}
+
+// parseLagDuration parses MGN's "PT<N>S" lag duration format into a
+// time.Duration, returning 0 for anything it doesn't recognize rather than
+// failing the whole DescribeSourceServers call over a single malformed
+// field.
+func parseLagDuration(raw string) time.Duration {
+	if len(raw) < 3 || raw[0] != 'P' || raw[1] != 'T' || raw[len(raw)-1] != 'S' {
+		return 0
+	}
+	seconds, err := strconv.Atoi(raw[2 : len(raw)-1])
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
@@ -2,22 +2,60 @@ package azure
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore/to"
 	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
 	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/compute/armcompute"
+	"github.com/Azure/azure-sdk-for-go/sdk/resourcemanager/network/armnetwork"
+	"github.com/sp00nznet/octopus/internal/cloudclient"
 )
 
+// ownerTag is set on every network resource Octopus creates so dependents
+// can be discovered later for cleanup, regardless of naming convention.
+const ownerTag = "octopus-owner-vm"
+
 // Client wraps the Azure Compute client for migration operations
 type Client struct {
-	vmClient       *armcompute.VirtualMachinesClient
-	disksClient    *armcompute.DisksClient
-	imagesClient   *armcompute.ImagesClient
-	ctx            context.Context
-	subscriptionID string
-	resourceGroup  string
-	location       string
+	vmClient              *armcompute.VirtualMachinesClient
+	disksClient           *armcompute.DisksClient
+	imagesClient          *armcompute.ImagesClient
+	networkClient         *networkClient
+	galleriesClient       *armcompute.GalleriesClient
+	galleryImagesClient   *armcompute.GalleryImagesClient
+	galleryVersionsClient *armcompute.GalleryImageVersionsClient
+	ctx                   context.Context
+	subscriptionID        string
+	resourceGroup         string
+	location              string
+
+	useManagedDiskImport bool
+
+	galleryName     string
+	galleryImageDef string
+	replicaRegions  []string
+	replicaCount    int32
+
+	// rl throttles and retries ARM calls - BeginStart/BeginDeallocate hit
+	// Azure's compute write quota far harder than Get, hence separate
+	// read/write buckets.
+	rl *cloudclient.RateLimitedClient
+}
+
+// networkClient groups the ARM clients needed to provision VM networking
+type networkClient struct {
+	interfaces     *armnetwork.InterfacesClient
+	publicIPs      *armnetwork.PublicIPAddressesClient
+	securityGroups *armnetwork.SecurityGroupsClient
+}
+
+// NICOptions controls how EnsureNIC provisions a network interface
+type NICOptions struct {
+	PublicIPName       string
+	NSGName            string
+	EnableIPForwarding bool
 }
 
 // Config holds Azure configuration
@@ -28,6 +66,19 @@ type Config struct {
 	ClientID       string
 	ClientSecret   string
 	Location       string
+
+	// UseManagedDiskImport routes CreateImageFromVHD through the managed-disk
+	// import path instead of the direct blob-URI path, for subscriptions that
+	// don't have a storage account to hold the source blob.
+	UseManagedDiskImport bool
+
+	// Shared Image Gallery settings used by PublishMigratedImage. GalleryName
+	// and GalleryImageDef select the gallery/image definition to publish
+	// into; ReplicaRegions and ReplicaCount control replication breadth.
+	GalleryName     string
+	GalleryImageDef string
+	ReplicaRegions  []string
+	ReplicaCount    int32
 }
 
 // NewClient creates a new Azure client
@@ -54,19 +105,85 @@ func NewClient(cfg Config) (*Client, error) {
 		return nil, fmt.Errorf("failed to create images client: %w", err)
 	}
 
+	interfacesClient, err := armnetwork.NewInterfacesClient(cfg.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create interfaces client: %w", err)
+	}
+
+	publicIPsClient, err := armnetwork.NewPublicIPAddressesClient(cfg.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create public IP client: %w", err)
+	}
+
+	securityGroupsClient, err := armnetwork.NewSecurityGroupsClient(cfg.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create security groups client: %w", err)
+	}
+
+	galleriesClient, err := armcompute.NewGalleriesClient(cfg.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create galleries client: %w", err)
+	}
+
+	galleryImagesClient, err := armcompute.NewGalleryImagesClient(cfg.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gallery images client: %w", err)
+	}
+
+	galleryVersionsClient, err := armcompute.NewGalleryImageVersionsClient(cfg.SubscriptionID, cred, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gallery image versions client: %w", err)
+	}
+
 	return &Client{
-		vmClient:       vmClient,
-		disksClient:    disksClient,
-		imagesClient:   imagesClient,
-		ctx:            ctx,
-		subscriptionID: cfg.SubscriptionID,
-		resourceGroup:  cfg.ResourceGroup,
-		location:       cfg.Location,
+		vmClient:     vmClient,
+		disksClient:  disksClient,
+		imagesClient: imagesClient,
+		networkClient: &networkClient{
+			interfaces:     interfacesClient,
+			publicIPs:      publicIPsClient,
+			securityGroups: securityGroupsClient,
+		},
+		galleriesClient:       galleriesClient,
+		galleryImagesClient:   galleryImagesClient,
+		galleryVersionsClient: galleryVersionsClient,
+		ctx:                   ctx,
+		subscriptionID:        cfg.SubscriptionID,
+		resourceGroup:         cfg.ResourceGroup,
+		location:              cfg.Location,
+		useManagedDiskImport:  cfg.UseManagedDiskImport,
+		galleryName:           cfg.GalleryName,
+		galleryImageDef:       cfg.GalleryImageDef,
+		replicaRegions:        cfg.ReplicaRegions,
+		replicaCount:          cfg.ReplicaCount,
+		rl:                    cloudclient.New(cloudclient.Options{Provider: "azure"}),
 	}, nil
 }
 
-// CreateImageFromVHD creates an Azure managed image from a VHD in blob storage
+// isNotFound reports whether err is an Azure 404 response
+func isNotFound(err error) bool {
+	var respErr *azcore.ResponseError
+	if errors.As(err, &respErr) {
+		return respErr.StatusCode == 404
+	}
+	return false
+}
+
+// CreateImageFromVHD creates an Azure managed image from a VHD in blob
+// storage. If the client was configured with UseManagedDiskImport, it
+// instead imports the VHD into a managed disk first and captures the image
+// from that disk, for subscriptions that don't have a storage account to
+// host the blob-URI path below.
 func (c *Client) CreateImageFromVHD(imageName, vhdURI, osType string) error {
+	if c.useManagedDiskImport {
+		diskName := imageName + "-import"
+		diskID, err := c.ImportDiskFromVHD(vhdURI, diskName, 0, osType)
+		if err != nil {
+			return fmt.Errorf("failed to import VHD to managed disk: %w", err)
+		}
+		return c.CreateImageFromManagedDisk(imageName, diskID, osType)
+	}
+
 	var osTypeEnum armcompute.OperatingSystemTypes
 	if osType == "windows" {
 		osTypeEnum = armcompute.OperatingSystemTypesWindows
@@ -100,7 +217,85 @@ func (c *Client) CreateImageFromVHD(imageName, vhdURI, osType string) error {
 	return nil
 }
 
-// CreateVMFromImage creates an Azure VM from a managed image
+// ImportDiskFromVHD creates a managed disk of sizeGB (or the source VHD's
+// own size when sizeGB is 0) by importing the blob at vhdURI, mirroring the
+// Packer azure-chroot builder's disk-import step. It returns the new disk's
+// resource ID.
+func (c *Client) ImportDiskFromVHD(vhdURI, diskName string, sizeGB int32, osType string) (string, error) {
+	var osTypeEnum armcompute.OperatingSystemTypes
+	if osType == "windows" {
+		osTypeEnum = armcompute.OperatingSystemTypesWindows
+	} else {
+		osTypeEnum = armcompute.OperatingSystemTypesLinux
+	}
+
+	disk := armcompute.Disk{
+		Location: to.Ptr(c.location),
+		Properties: &armcompute.DiskProperties{
+			OSType: to.Ptr(osTypeEnum),
+			CreationData: &armcompute.CreationData{
+				CreateOption: to.Ptr(armcompute.DiskCreateOptionImport),
+				SourceURI:    to.Ptr(vhdURI),
+			},
+		},
+	}
+	if sizeGB > 0 {
+		disk.Properties.DiskSizeGB = to.Ptr(sizeGB)
+	}
+
+	poller, err := c.disksClient.BeginCreateOrUpdate(c.ctx, c.resourceGroup, diskName, disk, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to import disk: %w", err)
+	}
+
+	result, err := poller.PollUntilDone(c.ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed waiting for disk import: %w", err)
+	}
+
+	return *result.ID, nil
+}
+
+// CreateImageFromManagedDisk captures a managed image from an existing
+// managed disk, giving callers a disk-based image build path as an
+// alternative to the direct blob-URI path in CreateImageFromVHD.
+func (c *Client) CreateImageFromManagedDisk(imageName, diskID, osType string) error {
+	var osTypeEnum armcompute.OperatingSystemTypes
+	if osType == "windows" {
+		osTypeEnum = armcompute.OperatingSystemTypesWindows
+	} else {
+		osTypeEnum = armcompute.OperatingSystemTypesLinux
+	}
+
+	image := armcompute.Image{
+		Location: to.Ptr(c.location),
+		Properties: &armcompute.ImageProperties{
+			StorageProfile: &armcompute.ImageStorageProfile{
+				OSDisk: &armcompute.ImageOSDisk{
+					OSType:      to.Ptr(osTypeEnum),
+					ManagedDisk: &armcompute.SubResource{ID: to.Ptr(diskID)},
+					OSState:     to.Ptr(armcompute.OperatingSystemStateTypesGeneralized),
+				},
+			},
+		},
+	}
+
+	poller, err := c.imagesClient.BeginCreateOrUpdate(c.ctx, c.resourceGroup, imageName, image, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create image from managed disk: %w", err)
+	}
+
+	_, err = poller.PollUntilDone(c.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed waiting for image creation: %w", err)
+	}
+
+	return nil
+}
+
+// CreateVMFromImage creates an Azure VM from a managed image, provisioning
+// its public IP, NSG, and NIC first (idempotent: safe to call repeatedly for
+// the same vmName).
 func (c *Client) CreateVMFromImage(vmName, imageName, vmSize, vnetName, subnetName, adminUsername, adminPassword string) error {
 	imageID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/images/%s",
 		c.subscriptionID, c.resourceGroup, imageName)
@@ -108,10 +303,23 @@ func (c *Client) CreateVMFromImage(vmName, imageName, vmSize, vnetName, subnetNa
 	subnetID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/virtualNetworks/%s/subnets/%s",
 		c.subscriptionID, c.resourceGroup, vnetName, subnetName)
 
-	// Create NIC first
-	nicName := vmName + "-nic"
-	nicID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/networkInterfaces/%s",
-		c.subscriptionID, c.resourceGroup, nicName)
+	pipName := vmName + "-pip"
+	if _, err := c.EnsurePublicIP(vmName, pipName); err != nil {
+		return fmt.Errorf("failed to ensure public IP: %w", err)
+	}
+
+	nsgName := vmName + "-nsg"
+	if _, err := c.EnsureNSG(vmName, nsgName); err != nil {
+		return fmt.Errorf("failed to ensure NSG: %w", err)
+	}
+
+	nicID, err := c.EnsureNIC(vmName, subnetID, NICOptions{
+		PublicIPName: pipName,
+		NSGName:      nsgName,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to ensure NIC: %w", err)
+	}
 
 	vm := armcompute.VirtualMachine{
 		Location: to.Ptr(c.location),
@@ -145,9 +353,6 @@ func (c *Client) CreateVMFromImage(vmName, imageName, vmSize, vnetName, subnetNa
 		},
 	}
 
-	// Note: In a real implementation, you'd create the NIC first
-	_ = subnetID // Would be used for NIC creation
-
 	poller, err := c.vmClient.BeginCreateOrUpdate(c.ctx, c.resourceGroup, vmName, vm, nil)
 	if err != nil {
 		return fmt.Errorf("failed to create VM: %w", err)
@@ -161,9 +366,202 @@ func (c *Client) CreateVMFromImage(vmName, imageName, vmSize, vnetName, subnetNa
 	return nil
 }
 
+// EnsurePublicIP returns the ID of the public IP address named pipName,
+// creating it (tagged with the owning VM name) if it doesn't already exist.
+func (c *Client) EnsurePublicIP(vmName, pipName string) (string, error) {
+	existing, err := c.networkClient.publicIPs.Get(c.ctx, c.resourceGroup, pipName, nil)
+	if err == nil {
+		return *existing.ID, nil
+	}
+	if !isNotFound(err) {
+		return "", fmt.Errorf("failed to get public IP: %w", err)
+	}
+
+	pip := armnetwork.PublicIPAddress{
+		Location: to.Ptr(c.location),
+		Tags:     map[string]*string{ownerTag: to.Ptr(vmName)},
+		Properties: &armnetwork.PublicIPAddressPropertiesFormat{
+			PublicIPAllocationMethod: to.Ptr(armnetwork.IPAllocationMethodStatic),
+		},
+	}
+
+	poller, err := c.networkClient.publicIPs.BeginCreateOrUpdate(c.ctx, c.resourceGroup, pipName, pip, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create public IP: %w", err)
+	}
+
+	result, err := poller.PollUntilDone(c.ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed waiting for public IP creation: %w", err)
+	}
+
+	return *result.ID, nil
+}
+
+// EnsureNSG returns the ID of the network security group named nsgName,
+// creating it (tagged with the owning VM name) if it doesn't already exist.
+func (c *Client) EnsureNSG(vmName, nsgName string) (string, error) {
+	existing, err := c.networkClient.securityGroups.Get(c.ctx, c.resourceGroup, nsgName, nil)
+	if err == nil {
+		return *existing.ID, nil
+	}
+	if !isNotFound(err) {
+		return "", fmt.Errorf("failed to get NSG: %w", err)
+	}
+
+	nsg := armnetwork.SecurityGroup{
+		Location:   to.Ptr(c.location),
+		Tags:       map[string]*string{ownerTag: to.Ptr(vmName)},
+		Properties: &armnetwork.SecurityGroupPropertiesFormat{},
+	}
+
+	poller, err := c.networkClient.securityGroups.BeginCreateOrUpdate(c.ctx, c.resourceGroup, nsgName, nsg, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create NSG: %w", err)
+	}
+
+	result, err := poller.PollUntilDone(c.ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed waiting for NSG creation: %w", err)
+	}
+
+	return *result.ID, nil
+}
+
+// EnsureNIC returns the ID of the network interface for vmName attached to
+// subnetID, creating it (tagged with the owning VM name) if it doesn't
+// already exist. opts.PublicIPName and opts.NSGName, if set, must already
+// have been created via EnsurePublicIP/EnsureNSG.
+func (c *Client) EnsureNIC(vmName, subnetID string, opts NICOptions) (string, error) {
+	nicName := vmName + "-nic"
+
+	existing, err := c.networkClient.interfaces.Get(c.ctx, c.resourceGroup, nicName, nil)
+	if err == nil {
+		return *existing.ID, nil
+	}
+	if !isNotFound(err) {
+		return "", fmt.Errorf("failed to get NIC: %w", err)
+	}
+
+	ipConfig := &armnetwork.InterfaceIPConfigurationPropertiesFormat{
+		Subnet:                    &armnetwork.Subnet{ID: to.Ptr(subnetID)},
+		PrivateIPAllocationMethod: to.Ptr(armnetwork.IPAllocationMethodDynamic),
+	}
+
+	if opts.PublicIPName != "" {
+		pipID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/publicIPAddresses/%s",
+			c.subscriptionID, c.resourceGroup, opts.PublicIPName)
+		ipConfig.PublicIPAddress = &armnetwork.PublicIPAddress{ID: to.Ptr(pipID)}
+	}
+
+	nic := armnetwork.Interface{
+		Location: to.Ptr(c.location),
+		Tags:     map[string]*string{ownerTag: to.Ptr(vmName)},
+		Properties: &armnetwork.InterfacePropertiesFormat{
+			IPConfigurations: []*armnetwork.InterfaceIPConfiguration{
+				{
+					Name:       to.Ptr("ipconfig1"),
+					Properties: ipConfig,
+				},
+			},
+			EnableIPForwarding: to.Ptr(opts.EnableIPForwarding),
+		},
+	}
+
+	if opts.NSGName != "" {
+		nsgID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Network/networkSecurityGroups/%s",
+			c.subscriptionID, c.resourceGroup, opts.NSGName)
+		nic.Properties.NetworkSecurityGroup = &armnetwork.SecurityGroup{ID: to.Ptr(nsgID)}
+	}
+
+	poller, err := c.networkClient.interfaces.BeginCreateOrUpdate(c.ctx, c.resourceGroup, nicName, nic, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create NIC: %w", err)
+	}
+
+	result, err := poller.PollUntilDone(c.ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed waiting for NIC creation: %w", err)
+	}
+
+	return *result.ID, nil
+}
+
+// DeleteVMAndDependents deletes vmName along with its OS disk and the NIC,
+// public IP and NSG named after it (vmName-nic, -pip, -nsg, the names
+// CreateVMFromImage gives them), mirroring the dangling-resource cleanup
+// used by the Arvados Azure driver. Resources are found by those names, not
+// by their ownerTag.
+func (c *Client) DeleteVMAndDependents(vmName string) error {
+	vm, err := c.vmClient.Get(c.ctx, c.resourceGroup, vmName, nil)
+	var osDiskName string
+	if err == nil && vm.Properties != nil && vm.Properties.StorageProfile != nil &&
+		vm.Properties.StorageProfile.OSDisk != nil && vm.Properties.StorageProfile.OSDisk.Name != nil {
+		osDiskName = *vm.Properties.StorageProfile.OSDisk.Name
+	} else if err != nil && !isNotFound(err) {
+		return fmt.Errorf("failed to get VM before delete: %w", err)
+	}
+
+	if err == nil {
+		vmPoller, err := c.vmClient.BeginDelete(c.ctx, c.resourceGroup, vmName, nil)
+		if err != nil {
+			return fmt.Errorf("failed to delete VM: %w", err)
+		}
+		if _, err := vmPoller.PollUntilDone(c.ctx, nil); err != nil {
+			return fmt.Errorf("failed waiting for VM deletion: %w", err)
+		}
+	}
+
+	if osDiskName != "" {
+		diskPoller, err := c.disksClient.BeginDelete(c.ctx, c.resourceGroup, osDiskName, nil)
+		if err != nil && !isNotFound(err) {
+			return fmt.Errorf("failed to delete OS disk: %w", err)
+		}
+		if diskPoller != nil {
+			if _, err := diskPoller.PollUntilDone(c.ctx, nil); err != nil {
+				return fmt.Errorf("failed waiting for OS disk deletion: %w", err)
+			}
+		}
+	}
+
+	nicName := vmName + "-nic"
+	if nicPoller, err := c.networkClient.interfaces.BeginDelete(c.ctx, c.resourceGroup, nicName, nil); err != nil {
+		if !isNotFound(err) {
+			return fmt.Errorf("failed to delete NIC: %w", err)
+		}
+	} else if _, err := nicPoller.PollUntilDone(c.ctx, nil); err != nil {
+		return fmt.Errorf("failed waiting for NIC deletion: %w", err)
+	}
+
+	pipName := vmName + "-pip"
+	if pipPoller, err := c.networkClient.publicIPs.BeginDelete(c.ctx, c.resourceGroup, pipName, nil); err != nil {
+		if !isNotFound(err) {
+			return fmt.Errorf("failed to delete public IP: %w", err)
+		}
+	} else if _, err := pipPoller.PollUntilDone(c.ctx, nil); err != nil {
+		return fmt.Errorf("failed waiting for public IP deletion: %w", err)
+	}
+
+	nsgName := vmName + "-nsg"
+	if nsgPoller, err := c.networkClient.securityGroups.BeginDelete(c.ctx, c.resourceGroup, nsgName, nil); err != nil {
+		if !isNotFound(err) {
+			return fmt.Errorf("failed to delete NSG: %w", err)
+		}
+	} else if _, err := nsgPoller.PollUntilDone(c.ctx, nil); err != nil {
+		return fmt.Errorf("failed waiting for NSG deletion: %w", err)
+	}
+
+	return nil
+}
+
 // GetVMInfo returns details about an Azure VM
 func (c *Client) GetVMInfo(vmName string) (map[string]interface{}, error) {
-	vm, err := c.vmClient.Get(c.ctx, c.resourceGroup, vmName, nil)
+	var vm armcompute.VirtualMachinesClientGetResponse
+	err := c.rl.Do("GetVM", false, func() error {
+		var err error
+		vm, err = c.vmClient.Get(c.ctx, c.resourceGroup, vmName, nil)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -184,22 +582,26 @@ func (c *Client) GetVMInfo(vmName string) (map[string]interface{}, error) {
 
 // StartVM starts a stopped Azure VM
 func (c *Client) StartVM(vmName string) error {
-	poller, err := c.vmClient.BeginStart(c.ctx, c.resourceGroup, vmName, nil)
-	if err != nil {
+	return c.rl.Do("BeginStart", true, func() error {
+		poller, err := c.vmClient.BeginStart(c.ctx, c.resourceGroup, vmName, nil)
+		if err != nil {
+			return err
+		}
+		_, err = poller.PollUntilDone(c.ctx, nil)
 		return err
-	}
-	_, err = poller.PollUntilDone(c.ctx, nil)
-	return err
+	})
 }
 
 // StopVM deallocates an Azure VM
 func (c *Client) StopVM(vmName string) error {
-	poller, err := c.vmClient.BeginDeallocate(c.ctx, c.resourceGroup, vmName, nil)
-	if err != nil {
+	return c.rl.Do("BeginDeallocate", true, func() error {
+		poller, err := c.vmClient.BeginDeallocate(c.ctx, c.resourceGroup, vmName, nil)
+		if err != nil {
+			return err
+		}
+		_, err = poller.PollUntilDone(c.ctx, nil)
 		return err
-	}
-	_, err = poller.PollUntilDone(c.ctx, nil)
-	return err
+	})
 }
 
 // CreateSnapshot creates a snapshot of a managed disk
@@ -234,6 +636,146 @@ func (c *Client) CreateSnapshot(diskName, snapshotName string) error {
 	return err
 }
 
+// EnsureGallery creates a Shared Image Gallery named name if it doesn't
+// already exist.
+func (c *Client) EnsureGallery(name string) error {
+	_, err := c.galleriesClient.Get(c.ctx, c.resourceGroup, name, nil)
+	if err == nil {
+		return nil
+	}
+	if !isNotFound(err) {
+		return fmt.Errorf("failed to get gallery: %w", err)
+	}
+
+	gallery := armcompute.Gallery{
+		Location:   to.Ptr(c.location),
+		Properties: &armcompute.GalleryProperties{},
+	}
+
+	poller, err := c.galleriesClient.BeginCreateOrUpdate(c.ctx, c.resourceGroup, name, gallery, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create gallery: %w", err)
+	}
+
+	_, err = poller.PollUntilDone(c.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed waiting for gallery creation: %w", err)
+	}
+
+	return nil
+}
+
+// EnsureImageDefinition creates an image definition named defName within
+// gallery if it doesn't already exist.
+func (c *Client) EnsureImageDefinition(gallery, defName, osType, publisher, offer, sku string) error {
+	_, err := c.galleryImagesClient.Get(c.ctx, c.resourceGroup, gallery, defName, nil)
+	if err == nil {
+		return nil
+	}
+	if !isNotFound(err) {
+		return fmt.Errorf("failed to get gallery image definition: %w", err)
+	}
+
+	var osTypeEnum armcompute.OperatingSystemTypes
+	if osType == "windows" {
+		osTypeEnum = armcompute.OperatingSystemTypesWindows
+	} else {
+		osTypeEnum = armcompute.OperatingSystemTypesLinux
+	}
+
+	imageDef := armcompute.GalleryImage{
+		Location: to.Ptr(c.location),
+		Properties: &armcompute.GalleryImageProperties{
+			OSType:  to.Ptr(osTypeEnum),
+			OSState: to.Ptr(armcompute.OperatingSystemStateTypesGeneralized),
+			Identifier: &armcompute.GalleryImageIdentifier{
+				Publisher: to.Ptr(publisher),
+				Offer:     to.Ptr(offer),
+				SKU:       to.Ptr(sku),
+			},
+		},
+	}
+
+	poller, err := c.galleryImagesClient.BeginCreateOrUpdate(c.ctx, c.resourceGroup, gallery, defName, imageDef, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create gallery image definition: %w", err)
+	}
+
+	_, err = poller.PollUntilDone(c.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed waiting for gallery image definition creation: %w", err)
+	}
+
+	return nil
+}
+
+// PublishImageVersion publishes sourceImageID as version of the gallery
+// image definition defName, replicating it into targetRegions.
+func (c *Client) PublishImageVersion(gallery, defName, version, sourceImageID string, targetRegions []string) error {
+	var replicas []*armcompute.TargetRegion
+	for _, region := range targetRegions {
+		replicas = append(replicas, &armcompute.TargetRegion{Name: to.Ptr(region)})
+	}
+	if len(replicas) == 0 {
+		replicas = append(replicas, &armcompute.TargetRegion{Name: to.Ptr(c.location)})
+	}
+
+	imageVersion := armcompute.GalleryImageVersion{
+		Location: to.Ptr(c.location),
+		Properties: &armcompute.GalleryImageVersionProperties{
+			StorageProfile: &armcompute.GalleryImageVersionStorageProfile{
+				Source: &armcompute.GalleryArtifactVersionSource{ID: to.Ptr(sourceImageID)},
+			},
+			PublishingProfile: &armcompute.GalleryImageVersionPublishingProfile{
+				TargetRegions: replicas,
+			},
+		},
+	}
+
+	poller, err := c.galleryVersionsClient.BeginCreateOrUpdate(c.ctx, c.resourceGroup, gallery, defName, version, imageVersion, nil)
+	if err != nil {
+		return fmt.Errorf("failed to publish gallery image version: %w", err)
+	}
+
+	_, err = poller.PollUntilDone(c.ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed waiting for gallery image version publish: %w", err)
+	}
+
+	return nil
+}
+
+// PublishMigratedImage creates imageName from vhdURI via CreateImageFromVHD
+// and, if the client was configured with GalleryName/GalleryImageDef,
+// replicates it into the Shared Image Gallery so migrated golden images are
+// versioned and available across ReplicaRegions.
+func (c *Client) PublishMigratedImage(imageName, vhdURI, osType, publisher, offer, sku, version string) error {
+	if err := c.CreateImageFromVHD(imageName, vhdURI, osType); err != nil {
+		return fmt.Errorf("failed to create image: %w", err)
+	}
+
+	if c.galleryName == "" || c.galleryImageDef == "" {
+		return nil
+	}
+
+	if err := c.EnsureGallery(c.galleryName); err != nil {
+		return fmt.Errorf("failed to ensure gallery: %w", err)
+	}
+
+	if err := c.EnsureImageDefinition(c.galleryName, c.galleryImageDef, osType, publisher, offer, sku); err != nil {
+		return fmt.Errorf("failed to ensure gallery image definition: %w", err)
+	}
+
+	imageID := fmt.Sprintf("/subscriptions/%s/resourceGroups/%s/providers/Microsoft.Compute/images/%s",
+		c.subscriptionID, c.resourceGroup, imageName)
+
+	if err := c.PublishImageVersion(c.galleryName, c.galleryImageDef, version, imageID, c.replicaRegions); err != nil {
+		return fmt.Errorf("failed to publish gallery image version: %w", err)
+	}
+
+	return nil
+}
+
 // EstimateVMSize suggests an appropriate Azure VM size based on VM specs
 func EstimateVMSize(cpuCount int, memoryGB float64) string {
 	// Using D-series for general purpose
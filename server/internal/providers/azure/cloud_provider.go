@@ -0,0 +1,90 @@
+package azure
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/sp00nznet/octopus/internal/cloud"
+)
+
+// Options configures the "azure" cloud.Provider driver, decoded from the
+// job/environment's driver-specific JSON/YAML options.
+type Options struct {
+	SubscriptionID string `json:"subscription_id" yaml:"subscription_id"`
+	ResourceGroup  string `json:"resource_group" yaml:"resource_group"`
+	TenantID       string `json:"tenant_id" yaml:"tenant_id"`
+	ClientID       string `json:"client_id" yaml:"client_id"`
+	ClientSecret   string `json:"client_secret" yaml:"client_secret"`
+	Location       string `json:"location" yaml:"location"`
+}
+
+func init() {
+	cloud.Register("azure", newProvider)
+}
+
+func newProvider(options json.RawMessage) (cloud.Provider, error) {
+	var opts Options
+	if len(options) > 0 {
+		if err := json.Unmarshal(options, &opts); err != nil {
+			return nil, fmt.Errorf("azure: invalid driver options: %w", err)
+		}
+	}
+
+	client, err := NewClient(Config{
+		SubscriptionID: opts.SubscriptionID,
+		ResourceGroup:  opts.ResourceGroup,
+		TenantID:       opts.TenantID,
+		ClientID:       opts.ClientID,
+		ClientSecret:   opts.ClientSecret,
+		Location:       opts.Location,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("azure: %w", err)
+	}
+
+	return &provider{client: client}, nil
+}
+
+// provider adapts Client to cloud.Provider.
+type provider struct {
+	client *Client
+}
+
+func (p *provider) Name() string { return "azure" }
+
+func (p *provider) CreateImageFromArtifact(spec cloud.ImageSpec) (string, error) {
+	if err := p.client.CreateImageFromVHD(spec.Name, spec.ArtifactURI, spec.OSType); err != nil {
+		return "", err
+	}
+	return spec.Name, nil
+}
+
+func (p *provider) CreateInstance(spec cloud.InstanceSpec) (string, error) {
+	if err := p.client.CreateVMFromImage(spec.Name, spec.ImageName, spec.MachineType, spec.Network, spec.Subnet, spec.AdminUsername, spec.AdminPassword); err != nil {
+		return "", err
+	}
+	return spec.Name, nil
+}
+
+func (p *provider) Start(instanceID string) error { return p.client.StartVM(instanceID) }
+func (p *provider) Stop(instanceID string) error  { return p.client.StopVM(instanceID) }
+
+func (p *provider) Snapshot(spec cloud.SnapshotSpec) (string, error) {
+	if err := p.client.CreateSnapshot(spec.ResourceID, spec.Name); err != nil {
+		return "", err
+	}
+	return spec.Name, nil
+}
+
+func (p *provider) GetInstanceInfo(instanceID string) (cloud.InstanceInfo, error) {
+	info, err := p.client.GetVMInfo(instanceID)
+	if err != nil {
+		return cloud.InstanceInfo{}, err
+	}
+	status, _ := info["provisioning_state"].(string)
+	return cloud.InstanceInfo{ID: instanceID, Name: instanceID, Status: status, Metadata: info}, nil
+}
+
+func (p *provider) EstimateMachineType(cpuCount int, memoryGB float64) string {
+	return EstimateVMSize(cpuCount, memoryGB)
+}
@@ -0,0 +1,23 @@
+package azure
+
+import (
+	"errors"
+
+	"github.com/sp00nznet/octopus/internal/cloud"
+)
+
+// FlavorResolver will implement cloud.FlavorResolver against the
+// VirtualMachineSizes.List API, mirroring gcp.FlavorResolver. Not
+// implemented yet - EstimateVMSize's static ladder in client.go is still
+// what backs EstimateMachineType.
+type FlavorResolver struct{}
+
+// NewFlavorResolver returns a FlavorResolver. Its ListCandidates always
+// errors until VirtualMachineSizes.List support lands.
+func NewFlavorResolver(*Client) *FlavorResolver {
+	return &FlavorResolver{}
+}
+
+func (r *FlavorResolver) ListCandidates(cloud.FlavorRequest) ([]cloud.FlavorCandidate, error) {
+	return nil, errors.New("azure: live flavor catalog resolution not implemented yet")
+}
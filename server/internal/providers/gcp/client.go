@@ -2,23 +2,55 @@ package gcp
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"math/rand"
 	"time"
 
 	compute "cloud.google.com/go/compute/apiv1"
 	computepb "cloud.google.com/go/compute/apiv1/computepb"
 	"google.golang.org/api/option"
 	"google.golang.org/protobuf/proto"
+
+	"github.com/sp00nznet/octopus/internal/cloudclient"
+)
+
+// ErrOperationTimeout is returned by waitForOperation when an operation's
+// deadline (imageOperationTimeout or instanceOperationTimeout) elapses
+// before GCE reports it done.
+var ErrOperationTimeout = errors.New("gcp: operation timed out")
+
+// ErrOperationCanceled is returned by waitForOperation when the caller's
+// context is canceled while an operation is still in flight.
+var ErrOperationCanceled = errors.New("gcp: operation canceled")
+
+const (
+	// imageOperationTimeout bounds how long an image import (the slowest
+	// GCE operation we drive) is allowed to run before we give up and
+	// free the goroutine waiting on it.
+	imageOperationTimeout = 30 * time.Minute
+	// instanceOperationTimeout bounds instance create/start/stop/snapshot
+	// operations, which normally complete in well under a minute.
+	instanceOperationTimeout = 10 * time.Minute
+
+	minBackoff = 2 * time.Second
+	maxBackoff = 60 * time.Second
 )
 
 // Client wraps the GCP Compute client for migration operations
 type Client struct {
-	instancesClient *compute.InstancesClient
-	imagesClient    *compute.ImagesClient
-	disksClient     *compute.DisksClient
-	ctx             context.Context
-	projectID       string
-	zone            string
+	instancesClient    *compute.InstancesClient
+	imagesClient       *compute.ImagesClient
+	disksClient        *compute.DisksClient
+	machineTypesClient *compute.MachineTypesClient
+	ctx                context.Context
+	projectID          string
+	zone               string
+
+	// rl throttles and retries Compute Engine calls - Start/Stop/CreateSnapshot
+	// are subject to GCE's per-minute write-operation quota, which is far
+	// tighter than the quota for Get, hence separate read/write buckets.
+	rl *cloudclient.RateLimitedClient
 }
 
 // Config holds GCP configuration
@@ -52,13 +84,20 @@ func NewClient(cfg Config) (*Client, error) {
 		return nil, fmt.Errorf("failed to create disks client: %w", err)
 	}
 
+	machineTypesClient, err := compute.NewMachineTypesRESTClient(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create machine types client: %w", err)
+	}
+
 	return &Client{
-		instancesClient: instancesClient,
-		imagesClient:    imagesClient,
-		disksClient:     disksClient,
-		ctx:             ctx,
-		projectID:       cfg.ProjectID,
-		zone:            cfg.Zone,
+		instancesClient:    instancesClient,
+		imagesClient:       imagesClient,
+		disksClient:        disksClient,
+		machineTypesClient: machineTypesClient,
+		ctx:                ctx,
+		projectID:          cfg.ProjectID,
+		zone:               cfg.Zone,
+		rl:                 cloudclient.New(cloudclient.Options{Provider: "gcp"}),
 	}, nil
 }
 
@@ -67,10 +106,15 @@ func (c *Client) Close() {
 	c.instancesClient.Close()
 	c.imagesClient.Close()
 	c.disksClient.Close()
+	c.machineTypesClient.Close()
 }
 
-// CreateImageFromGCS creates a GCE image from a file in GCS
-func (c *Client) CreateImageFromGCS(imageName, gcsURI, description string) error {
+// CreateImageFromGCS creates a GCE image from a file in GCS. ctx is
+// propagated from the API/scheduler request that triggered the import, so
+// canceling it (e.g. the request's own deadline, or an operator abort)
+// stops us waiting on the operation instead of blocking a scheduler
+// goroutine forever.
+func (c *Client) CreateImageFromGCS(ctx context.Context, imageName, gcsURI, description string) error {
 	req := &computepb.InsertImageRequest{
 		Project: c.projectID,
 		ImageResource: &computepb.Image{
@@ -82,17 +126,16 @@ func (c *Client) CreateImageFromGCS(imageName, gcsURI, description string) error
 		},
 	}
 
-	op, err := c.imagesClient.Insert(c.ctx, req)
+	op, err := c.imagesClient.Insert(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to create image: %w", err)
 	}
 
-	// Wait for operation to complete
-	return c.waitForOperation(op, "image creation")
+	return c.waitForOperation(ctx, op, "image creation", imageOperationTimeout)
 }
 
 // CreateInstanceFromImage creates a GCE instance from an image
-func (c *Client) CreateInstanceFromImage(instanceName, imageName, machineType, network, subnet string) error {
+func (c *Client) CreateInstanceFromImage(ctx context.Context, instanceName, imageName, machineType, network, subnet string) error {
 	imageURL := fmt.Sprintf("projects/%s/global/images/%s", c.projectID, imageName)
 	machineTypeURL := fmt.Sprintf("zones/%s/machineTypes/%s", c.zone, machineType)
 	networkURL := fmt.Sprintf("projects/%s/global/networks/%s", c.projectID, network)
@@ -130,12 +173,12 @@ func (c *Client) CreateInstanceFromImage(instanceName, imageName, machineType, n
 		},
 	}
 
-	op, err := c.instancesClient.Insert(c.ctx, req)
+	op, err := c.instancesClient.Insert(ctx, req)
 	if err != nil {
 		return fmt.Errorf("failed to create instance: %w", err)
 	}
 
-	return c.waitForOperation(op, "instance creation")
+	return c.waitForOperation(ctx, op, "instance creation", instanceOperationTimeout)
 }
 
 // GetInstanceInfo returns details about a GCE instance
@@ -146,7 +189,12 @@ func (c *Client) GetInstanceInfo(instanceName string) (map[string]interface{}, e
 		Instance: instanceName,
 	}
 
-	instance, err := c.instancesClient.Get(c.ctx, req)
+	var instance *computepb.Instance
+	err := c.rl.Do("GetInstance", false, func() error {
+		var err error
+		instance, err = c.instancesClient.Get(c.ctx, req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -171,39 +219,41 @@ func (c *Client) GetInstanceInfo(instanceName string) (map[string]interface{}, e
 }
 
 // StartInstance starts a stopped GCE instance
-func (c *Client) StartInstance(instanceName string) error {
+func (c *Client) StartInstance(ctx context.Context, instanceName string) error {
 	req := &computepb.StartInstanceRequest{
 		Project:  c.projectID,
 		Zone:     c.zone,
 		Instance: instanceName,
 	}
 
-	op, err := c.instancesClient.Start(c.ctx, req)
-	if err != nil {
-		return err
-	}
-
-	return c.waitForOperation(op, "instance start")
+	return c.rl.Do("Start", true, func() error {
+		op, err := c.instancesClient.Start(ctx, req)
+		if err != nil {
+			return err
+		}
+		return c.waitForOperation(ctx, op, "instance start", instanceOperationTimeout)
+	})
 }
 
 // StopInstance stops a running GCE instance
-func (c *Client) StopInstance(instanceName string) error {
+func (c *Client) StopInstance(ctx context.Context, instanceName string) error {
 	req := &computepb.StopInstanceRequest{
 		Project:  c.projectID,
 		Zone:     c.zone,
 		Instance: instanceName,
 	}
 
-	op, err := c.instancesClient.Stop(c.ctx, req)
-	if err != nil {
-		return err
-	}
-
-	return c.waitForOperation(op, "instance stop")
+	return c.rl.Do("Stop", true, func() error {
+		op, err := c.instancesClient.Stop(ctx, req)
+		if err != nil {
+			return err
+		}
+		return c.waitForOperation(ctx, op, "instance stop", instanceOperationTimeout)
+	})
 }
 
 // CreateSnapshot creates a snapshot of a disk
-func (c *Client) CreateSnapshot(diskName, snapshotName string) error {
+func (c *Client) CreateSnapshot(ctx context.Context, diskName, snapshotName string) error {
 	req := &computepb.CreateSnapshotDiskRequest{
 		Project: c.projectID,
 		Zone:    c.zone,
@@ -213,12 +263,12 @@ func (c *Client) CreateSnapshot(diskName, snapshotName string) error {
 		},
 	}
 
-	op, err := c.disksClient.CreateSnapshot(c.ctx, req)
+	op, err := c.disksClient.CreateSnapshot(ctx, req)
 	if err != nil {
 		return err
 	}
 
-	return c.waitForOperation(op, "snapshot creation")
+	return c.waitForOperation(ctx, op, "snapshot creation", instanceOperationTimeout)
 }
 
 // EstimateMachineType suggests an appropriate GCE machine type based on VM specs
@@ -244,8 +294,17 @@ func EstimateMachineType(cpuCount int, memoryGB float64) string {
 	}
 }
 
-// waitForOperation waits for a GCE operation to complete
-func (c *Client) waitForOperation(op *compute.Operation, operationName string) error {
+// waitForOperation polls a GCE long-running operation through its own
+// Poll method (rather than re-fetching it by hand) until it
+// completes, ctx is canceled, or timeout elapses. Polls back off
+// exponentially from minBackoff to maxBackoff with jitter, so a slow
+// operation doesn't hammer the Operations API while a fast one doesn't sit
+// idle for the full initial interval either.
+func (c *Client) waitForOperation(ctx context.Context, op *compute.Operation, operationName string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := minBackoff
 	for {
 		if op.Done() {
 			if op.Proto().GetError() != nil {
@@ -253,8 +312,34 @@ func (c *Client) waitForOperation(op *compute.Operation, operationName string) e
 			}
 			return nil
 		}
-		time.Sleep(5 * time.Second)
+
+		select {
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%s: %w", operationName, ErrOperationTimeout)
+			}
+			return fmt.Errorf("%s: %w", operationName, ErrOperationCanceled)
+		case <-time.After(backoff):
+		}
+
+		if err := op.Poll(ctx); err != nil {
+			return fmt.Errorf("%s: poll operation status: %w", operationName, err)
+		}
+
+		backoff = nextBackoff(backoff)
+	}
+}
+
+// nextBackoff doubles cur, caps it at maxBackoff, and returns a random
+// duration between half and all of that value, so concurrent operations
+// polling the same API don't synchronize.
+func nextBackoff(cur time.Duration) time.Duration {
+	next := cur * 2
+	if next > maxBackoff {
+		next = maxBackoff
 	}
+	jitter := time.Duration(rand.Int63n(int64(next) / 2))
+	return next/2 + jitter
 }
 
 func getRegionFromZone(zone string) string {
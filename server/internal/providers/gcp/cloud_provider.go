@@ -0,0 +1,101 @@
+package gcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sp00nznet/octopus/internal/cloud"
+)
+
+// Options configures the "gcp" cloud.Provider driver, decoded from the
+// job/environment's driver-specific JSON/YAML options.
+type Options struct {
+	ProjectID       string `json:"project_id" yaml:"project_id"`
+	Zone            string `json:"zone" yaml:"zone"`
+	CredentialsFile string `json:"credentials_file" yaml:"credentials_file"`
+}
+
+func init() {
+	cloud.Register("gcp", newProvider)
+}
+
+func newProvider(options json.RawMessage) (cloud.Provider, error) {
+	var opts Options
+	if len(options) > 0 {
+		if err := json.Unmarshal(options, &opts); err != nil {
+			return nil, fmt.Errorf("gcp: invalid driver options: %w", err)
+		}
+	}
+
+	client, err := NewClient(Config{
+		ProjectID:       opts.ProjectID,
+		Zone:            opts.Zone,
+		CredentialsFile: opts.CredentialsFile,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("gcp: %w", err)
+	}
+
+	return &provider{client: client}, nil
+}
+
+// provider adapts Client to cloud.Provider.
+type provider struct {
+	client *Client
+}
+
+func (p *provider) Name() string { return "gcp" }
+
+// cloud.Provider doesn't carry a context, so these calls use
+// context.Background() - Client's operation waits still honor their own
+// per-operation timeout (imageOperationTimeout/instanceOperationTimeout),
+// they just can't be canceled early by the caller's own request context.
+func (p *provider) CreateImageFromArtifact(spec cloud.ImageSpec) (string, error) {
+	if err := p.client.CreateImageFromGCS(context.Background(), spec.Name, spec.ArtifactURI, spec.Description); err != nil {
+		return "", err
+	}
+	return spec.Name, nil
+}
+
+func (p *provider) CreateInstance(spec cloud.InstanceSpec) (string, error) {
+	if err := p.client.CreateInstanceFromImage(context.Background(), spec.Name, spec.ImageName, spec.MachineType, spec.Network, spec.Subnet); err != nil {
+		return "", err
+	}
+	return spec.Name, nil
+}
+
+func (p *provider) Start(instanceID string) error {
+	return p.client.StartInstance(context.Background(), instanceID)
+}
+func (p *provider) Stop(instanceID string) error {
+	return p.client.StopInstance(context.Background(), instanceID)
+}
+
+func (p *provider) Snapshot(spec cloud.SnapshotSpec) (string, error) {
+	if err := p.client.CreateSnapshot(context.Background(), spec.ResourceID, spec.Name); err != nil {
+		return "", err
+	}
+	return spec.Name, nil
+}
+
+func (p *provider) GetInstanceInfo(instanceID string) (cloud.InstanceInfo, error) {
+	info, err := p.client.GetInstanceInfo(instanceID)
+	if err != nil {
+		return cloud.InstanceInfo{}, err
+	}
+	name, _ := info["name"].(string)
+	status, _ := info["status"].(string)
+	return cloud.InstanceInfo{ID: instanceID, Name: name, Status: status, Metadata: info}, nil
+}
+
+func (p *provider) EstimateMachineType(cpuCount int, memoryGB float64) string {
+	return EstimateMachineType(cpuCount, memoryGB)
+}
+
+// FlavorResolver implements cloud.FlavorAware, giving callers access to the
+// live GCE machine-type catalog instead of EstimateMachineType's static
+// ladder.
+func (p *provider) FlavorResolver() cloud.FlavorResolver {
+	return NewFlavorResolver(p.client)
+}
@@ -0,0 +1,73 @@
+package gcp
+
+import (
+	"fmt"
+
+	computepb "cloud.google.com/go/compute/apiv1/computepb"
+	"google.golang.org/api/iterator"
+
+	"github.com/sp00nznet/octopus/internal/cloud"
+)
+
+// gceHourlyCostPerVCPU is a rough, region-agnostic us-central1 on-demand
+// n2-standard list price used to rank candidates until real Cloud Billing
+// Catalog pricing is wired in. This is a simplified version - it's close
+// enough to order candidates cheapest-first but should not be quoted to a
+// user as an actual bill estimate.
+const gceHourlyCostPerVCPU = 0.0315
+
+// FlavorResolver implements cloud.FlavorResolver against a live GCE project,
+// listing real machine types via Client's MachineTypesClient rather than the
+// static if/else ladder EstimateMachineType uses.
+type FlavorResolver struct {
+	client *Client
+}
+
+// NewFlavorResolver wraps client as a cloud.FlavorResolver.
+func NewFlavorResolver(client *Client) *FlavorResolver {
+	return &FlavorResolver{client: client}
+}
+
+// ListCandidates lists every machine type available in req.Region (a GCE
+// zone, e.g. "us-central1-a"), falling back to the client's configured zone
+// if req.Region is empty.
+func (r *FlavorResolver) ListCandidates(req cloud.FlavorRequest) ([]cloud.FlavorCandidate, error) {
+	zone := req.Region
+	if zone == "" {
+		zone = r.client.zone
+	}
+
+	ctx := r.client.ctx
+	it := r.client.machineTypesClient.List(ctx, &computepb.ListMachineTypesRequest{
+		Project: r.client.projectID,
+		Zone:    zone,
+	})
+
+	var candidates []cloud.FlavorCandidate
+	for {
+		mt, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("gcp: list machine types in %s: %w", zone, err)
+		}
+
+		cpu := int(mt.GetGuestCpus())
+		memoryGB := float64(mt.GetMemoryMb()) / 1024
+
+		candidates = append(candidates, cloud.FlavorCandidate{
+			Name:        mt.GetName(),
+			CPU:         cpu,
+			MemoryGB:    memoryGB,
+			GPU:         false, // accelerator-attached types are listed separately by GCE and aren't handled yet
+			LocalSSD:    !mt.GetIsSharedCpu() && cpu >= 32,
+			Arch:        "x86_64",
+			Region:      zone,
+			HourlyCost:  float64(cpu) * gceHourlyCostPerVCPU,
+			Unavailable: mt.GetDeprecated() != nil,
+		})
+	}
+
+	return candidates, nil
+}
@@ -0,0 +1,115 @@
+package vmware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sp00nznet/octopus/internal/cloud"
+)
+
+// Options configures the "vmware" cloud.Provider driver, decoded from the
+// job/environment's driver-specific JSON/YAML options.
+type Options struct {
+	Host       string `json:"host" yaml:"host"`
+	Username   string `json:"username" yaml:"username"`
+	Password   string `json:"password" yaml:"password"`
+	Datacenter string `json:"datacenter" yaml:"datacenter"`
+	Insecure   bool   `json:"insecure" yaml:"insecure"`
+}
+
+func init() {
+	cloud.Register("vmware", newProvider)
+}
+
+func newProvider(options json.RawMessage) (cloud.Provider, error) {
+	var opts Options
+	if len(options) > 0 {
+		if err := json.Unmarshal(options, &opts); err != nil {
+			return nil, fmt.Errorf("vmware: invalid driver options: %w", err)
+		}
+	}
+
+	client, err := NewClient(opts.Host, opts.Username, opts.Password, opts.Datacenter, opts.Insecure)
+	if err != nil {
+		return nil, fmt.Errorf("vmware: %w", err)
+	}
+
+	return &provider{client: client}, nil
+}
+
+// provider adapts Client to cloud.Provider. Unlike the public-cloud
+// drivers, vCenter has no separate "image" artifact or fixed machine-type
+// catalog, so CreateImageFromArtifact and EstimateMachineType are
+// reinterpreted in vSphere terms: exporting a VM and describing its raw
+// CPU/memory shape, respectively.
+type provider struct {
+	client *Client
+}
+
+func (p *provider) Name() string { return "vmware" }
+
+// CreateImageFromArtifact exports spec.Name to spec.ArtifactURI, treating
+// it as a filesystem path ExportVM can write an OVF/VMDK bundle to.
+func (p *provider) CreateImageFromArtifact(spec cloud.ImageSpec) (string, error) {
+	if _, err := p.client.ExportVM(context.Background(), spec.Name, spec.ArtifactURI, ExportOptions{}); err != nil {
+		return "", err
+	}
+	return spec.Name, nil
+}
+
+// CreateInstance clones spec.ImageName into spec.Name, leaving placement
+// (folder/host/datastore) to vCenter's defaults.
+func (p *provider) CreateInstance(spec cloud.InstanceSpec) (string, error) {
+	// cloud.InstanceSpec has no hostname/DNS/static-IP fields yet, so
+	// there's nothing to build a CustomizationOptions from here; callers
+	// that need guest customization use Client.CloneVM directly until the
+	// generic InstanceSpec grows those fields.
+	if err := p.client.CloneVM(context.Background(), spec.ImageName, spec.Name, "", "", "", "", spec.PreserveMAC, nil); err != nil {
+		return "", err
+	}
+	return spec.Name, nil
+}
+
+func (p *provider) Start(instanceID string) error {
+	return p.client.PowerOn(context.Background(), instanceID)
+}
+func (p *provider) Stop(instanceID string) error {
+	return p.client.PowerOff(context.Background(), instanceID)
+}
+
+func (p *provider) Snapshot(spec cloud.SnapshotSpec) (string, error) {
+	if err := p.client.CreateSnapshot(context.Background(), spec.ResourceID, spec.Name, spec.Description, spec.Memory, spec.Quiesce); err != nil {
+		return "", err
+	}
+	return spec.Name, nil
+}
+
+// DeleteSnapshot implements cloud.SnapshotDeleter.
+func (p *provider) DeleteSnapshot(resourceID, snapshotID string) error {
+	return p.client.DeleteSnapshot(context.Background(), resourceID, snapshotID)
+}
+
+func (p *provider) GetInstanceInfo(instanceID string) (cloud.InstanceInfo, error) {
+	info, err := p.client.GetVM(context.Background(), instanceID)
+	if err != nil {
+		return cloud.InstanceInfo{}, err
+	}
+	return cloud.InstanceInfo{
+		ID:     info.UUID,
+		Name:   info.Name,
+		Status: info.PowerState,
+		Metadata: map[string]interface{}{
+			"cpu_count":    info.CPUCount,
+			"memory_mb":    info.MemoryMB,
+			"disk_size_gb": info.DiskSizeGB,
+			"guest_os":     info.GuestOS,
+		},
+	}, nil
+}
+
+// EstimateMachineType has no vSphere equivalent of a fixed instance-type
+// catalog, so it just describes the requested shape directly.
+func (p *provider) EstimateMachineType(cpuCount int, memoryGB float64) string {
+	return fmt.Sprintf("%d vCPU / %.0fGB RAM", cpuCount, memoryGB)
+}
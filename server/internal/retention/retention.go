@@ -0,0 +1,220 @@
+// Package retention persists each migration job's sync.SnapshotPolicy and the
+// sync.SnapshotRecords it has tagged, and reaps snapshots past their
+// retention window. It sits between the sync package (which defines the
+// policy/record types but has no database access of its own, mirroring how
+// SyncManager reports progress through callbacks instead of writing to the
+// database directly) and the scheduler (which owns the reaper ticker and
+// resolves the cloud.Provider needed to actually delete a snapshot).
+package retention
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/sp00nznet/octopus/internal/db"
+	"github.com/sp00nznet/octopus/internal/sync"
+)
+
+// Manager reads and writes snapshot_policies and job_snapshots.
+type Manager struct {
+	db *db.Database
+}
+
+// NewManager creates a new retention Manager.
+func NewManager(database *db.Database) *Manager {
+	return &Manager{db: database}
+}
+
+// SetPolicy upserts jobID's snapshot retention policy.
+func (m *Manager) SetPolicy(jobID int64, policy sync.SnapshotPolicy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+
+	_, err := m.db.Exec(`
+		INSERT INTO snapshot_policies (job_id, min_retention_seconds, max_retention_seconds, mode, keep_last_n, bypass_retention_role, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(job_id) DO UPDATE SET
+			min_retention_seconds = excluded.min_retention_seconds,
+			max_retention_seconds = excluded.max_retention_seconds,
+			mode = excluded.mode,
+			keep_last_n = excluded.keep_last_n,
+			bypass_retention_role = excluded.bypass_retention_role,
+			updated_at = excluded.updated_at
+	`, jobID, seconds(policy.MinRetention), seconds(policy.MaxRetention), policy.Mode, policy.KeepLastN, nullString(policy.BypassRetentionRole), time.Now())
+	if err != nil {
+		return fmt.Errorf("set snapshot policy for job %d: %w", jobID, err)
+	}
+	return nil
+}
+
+// Policy returns jobID's snapshot policy, falling back to
+// sync.DefaultSnapshotPolicy if none has been set for the job.
+func (m *Manager) Policy(jobID int64) (sync.SnapshotPolicy, error) {
+	var minSec, maxSec int64
+	var mode string
+	var keepLastN int
+	var bypassRole sql.NullString
+
+	err := m.db.QueryRow(`
+		SELECT min_retention_seconds, max_retention_seconds, mode, keep_last_n, bypass_retention_role
+		FROM snapshot_policies WHERE job_id = ?
+	`, jobID).Scan(&minSec, &maxSec, &mode, &keepLastN, &bypassRole)
+	if err == sql.ErrNoRows {
+		return sync.DefaultSnapshotPolicy, nil
+	}
+	if err != nil {
+		return sync.SnapshotPolicy{}, fmt.Errorf("load snapshot policy for job %d: %w", jobID, err)
+	}
+
+	return sync.SnapshotPolicy{
+		MinRetention:        time.Duration(minSec) * time.Second,
+		MaxRetention:        time.Duration(maxSec) * time.Second,
+		Mode:                mode,
+		KeepLastN:           keepLastN,
+		BypassRetentionRole: bypassRole.String,
+	}, nil
+}
+
+// RecordSnapshot persists rec, tagging it with the policy that was active
+// when it was taken so later reaping evaluates it consistently even if the
+// job's policy has since changed.
+func (m *Manager) RecordSnapshot(rec sync.SnapshotRecord) error {
+	_, err := m.db.Exec(`
+		INSERT INTO job_snapshots (job_id, resource_id, snapshot_id, min_retention_seconds, max_retention_seconds, mode, keep_last_n, bypass_retention_role, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, rec.JobID, rec.ResourceID, rec.SnapshotID, seconds(rec.Policy.MinRetention), seconds(rec.Policy.MaxRetention), rec.Policy.Mode, rec.Policy.KeepLastN, nullString(rec.Policy.BypassRetentionRole), rec.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("record snapshot %s for job %d: %w", rec.SnapshotID, rec.JobID, err)
+	}
+	return nil
+}
+
+// BaseSnapshot returns the most recently created, not-yet-deleted snapshot
+// recorded for jobID - the one cutover and rollback should treat as the
+// current CBT base. It returns sql.ErrNoRows if the job has no surviving
+// snapshot.
+func (m *Manager) BaseSnapshot(jobID int64) (sync.SnapshotRecord, error) {
+	row := m.db.QueryRow(`
+		SELECT job_id, resource_id, snapshot_id, min_retention_seconds, max_retention_seconds, mode, keep_last_n, bypass_retention_role, created_at
+		FROM job_snapshots
+		WHERE job_id = ? AND deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT 1
+	`, jobID)
+	return scanSnapshotRow(row.Scan)
+}
+
+// Delete marks snapshotID for jobID as deleted and invokes deleteFn to
+// actually remove it from the provider, refusing when rec is still within
+// MinRetention and bypassRole doesn't satisfy the policy (see
+// sync.SnapshotRecord.CanDelete). deleteFn is only called once the retention
+// check passes, and the row is only marked deleted if deleteFn succeeds.
+func (m *Manager) Delete(jobID int64, snapshotID, bypassRole string, deleteFn func(sync.SnapshotRecord) error) error {
+	row := m.db.QueryRow(`
+		SELECT job_id, resource_id, snapshot_id, min_retention_seconds, max_retention_seconds, mode, keep_last_n, bypass_retention_role, created_at
+		FROM job_snapshots
+		WHERE job_id = ? AND snapshot_id = ? AND deleted_at IS NULL
+	`, jobID, snapshotID)
+	rec, err := scanSnapshotRow(row.Scan)
+	if err != nil {
+		return fmt.Errorf("find snapshot %s for job %d: %w", snapshotID, jobID, err)
+	}
+
+	if !rec.CanDelete(time.Now(), bypassRole) {
+		return fmt.Errorf("snapshot %s for job %d is still within its retention window", snapshotID, jobID)
+	}
+
+	if err := deleteFn(rec); err != nil {
+		return fmt.Errorf("delete snapshot %s for job %d: %w", snapshotID, jobID, err)
+	}
+
+	if _, err := m.db.Exec(`UPDATE job_snapshots SET deleted_at = ? WHERE job_id = ? AND snapshot_id = ?`, time.Now(), jobID, snapshotID); err != nil {
+		return fmt.Errorf("mark snapshot %s for job %d deleted: %w", snapshotID, jobID, err)
+	}
+	return nil
+}
+
+// Reap scans every job's surviving snapshots and deletes whichever are
+// eligible: past MaxRetention outright, or past MinRetention and beyond
+// KeepLastN most-recent-per-job. It's meant to be called periodically by
+// the scheduler, mirroring lease.Manager.Reap. deleteFn is invoked once per
+// snapshot Reap decides to remove; a deleteFn error is logged and that
+// snapshot is left for the next pass rather than aborting the whole run.
+func (m *Manager) Reap(deleteFn func(sync.SnapshotRecord) error) {
+	rows, err := m.db.Query(`
+		SELECT job_id, resource_id, snapshot_id, min_retention_seconds, max_retention_seconds, mode, keep_last_n, bypass_retention_role, created_at
+		FROM job_snapshots
+		WHERE deleted_at IS NULL
+		ORDER BY job_id, created_at DESC
+	`)
+	if err != nil {
+		log.Printf("retention: error scanning snapshots: %v", err)
+		return
+	}
+
+	byJob := make(map[int64][]sync.SnapshotRecord)
+	for rows.Next() {
+		rec, err := scanSnapshotRow(rows.Scan)
+		if err != nil {
+			continue
+		}
+		byJob[rec.JobID] = append(byJob[rec.JobID], rec)
+	}
+	rows.Close()
+
+	now := time.Now()
+	for jobID, records := range byJob {
+		// records is ordered newest-first (see the query above), so the
+		// first KeepLastN entries are always protected from MinRetention-
+		// based reaping, but not from MaxRetention - a policy change or an
+		// unusually long-lived job shouldn't let a snapshot live forever
+		// just because it's recent.
+		for i, rec := range records {
+			expired := rec.IsExpired(now)
+			keep := i < rec.Policy.KeepLastN
+			if !expired && (keep || !rec.CanDelete(now, "")) {
+				continue
+			}
+
+			if err := deleteFn(rec); err != nil {
+				log.Printf("retention: error deleting snapshot %s for job %d: %v", rec.SnapshotID, jobID, err)
+				continue
+			}
+			if _, err := m.db.Exec(`UPDATE job_snapshots SET deleted_at = ? WHERE job_id = ? AND snapshot_id = ?`, now, rec.JobID, rec.SnapshotID); err != nil {
+				log.Printf("retention: error marking snapshot %s for job %d deleted: %v", rec.SnapshotID, jobID, err)
+			}
+		}
+	}
+}
+
+func scanSnapshotRow(scan func(dest ...interface{}) error) (sync.SnapshotRecord, error) {
+	var rec sync.SnapshotRecord
+	var minSec, maxSec int64
+	var mode string
+	var keepLastN int
+	var bypassRole sql.NullString
+
+	if err := scan(&rec.JobID, &rec.ResourceID, &rec.SnapshotID, &minSec, &maxSec, &mode, &keepLastN, &bypassRole, &rec.CreatedAt); err != nil {
+		return sync.SnapshotRecord{}, err
+	}
+
+	rec.Policy = sync.SnapshotPolicy{
+		MinRetention:        time.Duration(minSec) * time.Second,
+		MaxRetention:        time.Duration(maxSec) * time.Second,
+		Mode:                mode,
+		KeepLastN:           keepLastN,
+		BypassRetentionRole: bypassRole.String,
+	}
+	return rec, nil
+}
+
+func seconds(d time.Duration) int64 {
+	return int64(d.Seconds())
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
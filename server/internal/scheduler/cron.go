@@ -0,0 +1,123 @@
+package scheduler
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/cronexpr"
+)
+
+// MaintenanceWindow restricts a recurring scheduled_tasks row to a daily
+// time-of-day range, optionally limited to specific weekdays, e.g. "sync
+// every 15 min but only during 22:00-04:00 UTC on weekdays". Stored as
+// scheduled_tasks.maintenance_window JSON; a nil window is always open.
+type MaintenanceWindow struct {
+	Start string   `json:"start"` // "HH:MM", in the task's Timezone
+	End   string   `json:"end"`   // "HH:MM"; if End <= Start the window wraps past midnight
+	Days  []string `json:"days"`  // lowercase weekday abbreviations ("mon".."sun"); empty means every day
+}
+
+// parseMaintenanceWindow decodes raw (scheduled_tasks.maintenance_window).
+// An empty raw is a nil window, meaning always open.
+func parseMaintenanceWindow(raw string) (*MaintenanceWindow, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var w MaintenanceWindow
+	if err := json.Unmarshal([]byte(raw), &w); err != nil {
+		return nil, fmt.Errorf("scheduler: invalid maintenance_window: %w", err)
+	}
+	return &w, nil
+}
+
+var weekdayAbbrev = map[time.Weekday]string{
+	time.Sunday: "sun", time.Monday: "mon", time.Tuesday: "tue", time.Wednesday: "wed",
+	time.Thursday: "thu", time.Friday: "fri", time.Saturday: "sat",
+}
+
+// isOpen reports whether t (already in the task's timezone) falls inside w.
+// A nil w is always open; a window with unparsable Start/End fails open
+// rather than permanently blocking the task.
+func (w *MaintenanceWindow) isOpen(t time.Time) bool {
+	if w == nil {
+		return true
+	}
+
+	if len(w.Days) > 0 {
+		today := weekdayAbbrev[t.Weekday()]
+		open := false
+		for _, d := range w.Days {
+			if d == today {
+				open = true
+				break
+			}
+		}
+		if !open {
+			return false
+		}
+	}
+
+	start, errStart := time.Parse("15:04", w.Start)
+	end, errEnd := time.Parse("15:04", w.End)
+	if errStart != nil || errEnd != nil {
+		return true
+	}
+
+	minutesNow := t.Hour()*60 + t.Minute()
+	minutesStart := start.Hour()*60 + start.Minute()
+	minutesEnd := end.Hour()*60 + end.Minute()
+
+	if minutesStart < minutesEnd {
+		return minutesNow >= minutesStart && minutesNow < minutesEnd
+	}
+	// Window wraps past midnight, e.g. 22:00-04:00.
+	return minutesNow >= minutesStart || minutesNow < minutesEnd
+}
+
+// loadZone resolves tz to a *time.Location, defaulting to UTC.
+func loadZone(tz string) (*time.Location, error) {
+	if tz == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return nil, fmt.Errorf("scheduler: invalid timezone %q: %w", tz, err)
+	}
+	return loc, nil
+}
+
+// nextFireTime returns cronExpr's next fire time strictly after `after`,
+// evaluated in tz and returned in UTC (scheduled_time/next_run are stored as
+// UTC timestamps).
+func nextFireTime(cronExpr, tz string, after time.Time) (time.Time, error) {
+	expr, err := cronexpr.Parse(cronExpr)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("scheduler: invalid cron_expression %q: %w", cronExpr, err)
+	}
+	loc, err := loadZone(tz)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return expr.Next(after.In(loc)).UTC(), nil
+}
+
+// nextFireTimes returns up to n of cronExpr's upcoming fire times after
+// `after`, for the schedules preview API.
+func nextFireTimes(cronExpr, tz string, after time.Time, n int) ([]time.Time, error) {
+	expr, err := cronexpr.Parse(cronExpr)
+	if err != nil {
+		return nil, fmt.Errorf("scheduler: invalid cron_expression %q: %w", cronExpr, err)
+	}
+	loc, err := loadZone(tz)
+	if err != nil {
+		return nil, err
+	}
+
+	fireTimes := expr.NextN(after.In(loc), uint(n))
+	out := make([]time.Time, len(fireTimes))
+	for i, t := range fireTimes {
+		out[i] = t.UTC()
+	}
+	return out, nil
+}
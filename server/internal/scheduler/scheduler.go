@@ -1,28 +1,174 @@
 package scheduler
 
 import (
+	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"strconv"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/sp00nznet/octopus/internal/cloud"
+	"github.com/sp00nznet/octopus/internal/config"
 	"github.com/sp00nznet/octopus/internal/db"
+	"github.com/sp00nznet/octopus/internal/events"
+	"github.com/sp00nznet/octopus/internal/lease"
+	"github.com/sp00nznet/octopus/internal/metrics"
+	"github.com/sp00nznet/octopus/internal/migrationstream"
+	"github.com/sp00nznet/octopus/internal/providers/aws"
+	"github.com/sp00nznet/octopus/internal/retention"
+	"github.com/sp00nznet/octopus/internal/secrets"
 	"github.com/sp00nznet/octopus/internal/sync"
+	"github.com/sp00nznet/octopus/internal/webhooks"
 )
 
+// MRF (multi-retry failover) backoff bounds: a failed sync is retried with
+// exponential backoff starting at mrfBaseBackoff and capped at
+// mrfMaxBackoff, Minio-replication-style, so a chronically broken job
+// doesn't hammer the target every tick forever.
+const (
+	mrfBaseBackoff = 30 * time.Second
+	mrfMaxBackoff  = 30 * time.Minute
+)
+
+// taskLeaseTTL bounds how long a scheduled_tasks row claimed by claimTask
+// can go without a heartbeat before reclaimAbandonedTasks treats its worker
+// as crashed and returns it to 'pending' for another worker to pick up.
+const taskLeaseTTL = 2 * time.Minute
+
+// Scheduled-task retry backoff bounds: a failed one-shot task is requeued
+// with exponential backoff starting at taskRetryBaseBackoff and capped at
+// taskRetryMaxBackoff, same doubling shape as mrfBaseBackoff/mrfMaxBackoff
+// but a separate pair of constants since scheduled_tasks retries are
+// unrelated to the sync_retry_queue MRF mechanism.
+const (
+	taskRetryBaseBackoff = 1 * time.Minute
+	taskRetryMaxBackoff  = 1 * time.Hour
+)
+
+// migrationLeaseTTL bounds how long a sync or cutover driven from the
+// scheduler's own dispatch paths (processSyncJobs, the taskHandlers below)
+// may run before its lease needs renewing - mirrors migrationLeaseTTL in
+// internal/api/handlers.go, which guards the same jobs when triggered over
+// the API instead.
+const migrationLeaseTTL = 30 * time.Second
+
+// taskHandlers dispatches a due scheduled_tasks row by task_type. Registered
+// here instead of a switch in runTask so adding a new task_type doesn't
+// require touching the claim/retry/lease machinery around it. cutover/
+// failover/sync acquire jobID's migration lease before dispatching - the
+// same lease the API handlers take - so a scheduled_tasks row never races a
+// concurrently running API-triggered sync/cutover, or another instance's
+// own scheduler, onto the same job.
+var taskHandlers = map[string]func(s *Scheduler, jobID int64) error{
+	"cutover":       func(s *Scheduler, jobID int64) error { return s.withMigrationLease(jobID, s.TriggerCutover) },
+	"failover":      func(s *Scheduler, jobID int64) error { return s.withMigrationLease(jobID, s.TriggerCutover) }, // Failover uses same logic
+	"sync":          func(s *Scheduler, jobID int64) error { return s.withMigrationLease(jobID, s.TriggerSync) },
+	"test_failover": func(s *Scheduler, jobID int64) error { return s.performTestFailover(jobID) },
+}
+
+// withMigrationLease acquires jobID's migration lease, runs fn with a
+// context that's cancelled if the lease is lost mid-run, and releases the
+// lease afterward. Returns lease.ErrLeaseHeld unchanged if another worker
+// (another instance, or an API-triggered sync/cutover still in flight)
+// already holds it, so callers can treat that as "skip this tick" rather
+// than a real failure.
+func (s *Scheduler) withMigrationLease(jobID int64, fn func(ctx context.Context, jobID int64) error) error {
+	lse, leaseCtx, err := s.leases.Acquire(context.Background(), jobID, migrationLeaseTTL)
+	if err != nil {
+		return err
+	}
+	defer s.leases.Release(lse)
+	return fn(leaseCtx, jobID)
+}
+
+// dispatchSync acquires jobID's migration lease and, if acquired, spawns
+// performSync in the background with a context tied to that lease. Used by
+// processSyncJobs and processMRFQueue, which scan many jobs per tick and
+// can't block on withMigrationLease's synchronous fn for each one. Silently
+// skips jobID if the lease is already held - another instance, or an
+// API-triggered sync, owns it this tick - logging only on a real Acquire
+// failure.
+func (s *Scheduler) dispatchSync(jobID int64, vmName string, preserveMAC, preservePortGroups bool) {
+	lse, leaseCtx, err := s.leases.Acquire(context.Background(), jobID, migrationLeaseTTL)
+	if err != nil {
+		if !errors.Is(err, lease.ErrLeaseHeld) {
+			log.Printf("migration job %d: failed to acquire sync lease: %v", jobID, err)
+		}
+		return
+	}
+	go func() {
+		defer s.leases.Release(lse)
+		s.performSync(leaseCtx, jobID, vmName, preserveMAC, preservePortGroups)
+	}()
+}
+
 // Scheduler manages scheduled tasks for migrations
 type Scheduler struct {
-	db       *db.Database
-	stopChan chan struct{}
+	db        *db.Database
+	webhooks  *webhooks.Manager
+	events    *events.Publisher
+	stream    *migrationstream.Hub
+	leases    *lease.Manager
+	snapshots *retention.Manager
+	secrets   *secrets.Protector
+	stopChan  chan struct{}
+
+	// workerID identifies this scheduler instance in scheduled_tasks'
+	// lease_owner column, the same way lease.Manager tags migration_leases -
+	// so two replicas sharing a database never both claim the same task.
+	workerID string
+
+	syncTransferWorkers  int
+	syncMaxInFlightBytes int64
 }
 
 // New creates a new scheduler
-func New(database *db.Database) *Scheduler {
+func New(database *db.Database, cfg *config.Config) *Scheduler {
 	return &Scheduler{
-		db:       database,
-		stopChan: make(chan struct{}),
+		db:                   database,
+		webhooks:             webhooks.NewManager(database),
+		events:               events.NewPublisherFromConfig(cfg),
+		stream:               migrationstream.NewHub(),
+		leases:               lease.NewManager(database),
+		snapshots:            retention.NewManager(database),
+		secrets:              secrets.NewProtector(cfg.SecretsKEKURI),
+		stopChan:             make(chan struct{}),
+		workerID:             uuid.NewString(),
+		syncTransferWorkers:  cfg.SyncTransferWorkers,
+		syncMaxInFlightBytes: cfg.SyncMaxInFlightBytes,
 	}
 }
 
+// Stream returns the scheduler's migration progress hub so the API layer can
+// subscribe clients to it.
+func (s *Scheduler) Stream() *migrationstream.Hub {
+	return s.stream
+}
+
+// Leases returns the scheduler's lease manager so the API layer can guard
+// sync/cutover handlers against concurrent execution.
+func (s *Scheduler) Leases() *lease.Manager {
+	return s.leases
+}
+
+// Snapshots returns the scheduler's retention manager so the API layer can
+// read/write a job's snapshot policy and locate its base snapshot.
+func (s *Scheduler) Snapshots() *retention.Manager {
+	return s.snapshots
+}
+
+// PreviewFireTimes returns up to n of cronExpr's upcoming fire times (in tz,
+// returned as UTC), for the admin "preview next N runs" API - it doesn't
+// touch scheduled_tasks, so it's safe to call against an expression that
+// hasn't been saved yet.
+func (s *Scheduler) PreviewFireTimes(cronExpr, tz string, n int) ([]time.Time, error) {
+	return nextFireTimes(cronExpr, tz, time.Now(), n)
+}
+
 // Start begins the scheduler loop
 func (s *Scheduler) Start() {
 	log.Println("Scheduler started")
@@ -35,12 +181,44 @@ func (s *Scheduler) Start() {
 	syncTicker := time.NewTicker(5 * time.Minute)
 	defer syncTicker.Stop()
 
+	// Reclaim leases abandoned by crashed workers
+	reapTicker := time.NewTicker(30 * time.Second)
+	defer reapTicker.Stop()
+
+	// Retry failed syncs queued in sync_retry_queue (MRF)
+	mrfTicker := time.NewTicker(30 * time.Second)
+	defer mrfTicker.Stop()
+
+	// Refresh the gauge metrics that aren't naturally updated by an event
+	// (pending/failed job counts, MRF backlog size)
+	metricsTicker := time.NewTicker(30 * time.Second)
+	defer metricsTicker.Stop()
+
+	// Garbage-collect CBT reference snapshots past their retention window
+	snapshotReapTicker := time.NewTicker(30 * time.Second)
+	defer snapshotReapTicker.Stop()
+
+	// Reclaim scheduled_tasks rows whose lease expired without being
+	// settled - a worker claimed them and then crashed mid-run.
+	taskReapTicker := time.NewTicker(30 * time.Second)
+	defer taskReapTicker.Stop()
+
 	for {
 		select {
 		case <-ticker.C:
 			s.processDueTasks()
 		case <-syncTicker.C:
 			s.processSyncJobs()
+		case <-reapTicker.C:
+			s.leases.Reap()
+		case <-mrfTicker.C:
+			s.processMRFQueue()
+		case <-metricsTicker.C:
+			s.refreshGaugeMetrics()
+		case <-snapshotReapTicker.C:
+			s.reapSnapshots()
+		case <-taskReapTicker.C:
+			s.reclaimAbandonedTasks()
 		case <-s.stopChan:
 			log.Println("Scheduler stopped")
 			return
@@ -53,48 +231,164 @@ func (s *Scheduler) Stop() {
 	close(s.stopChan)
 }
 
-// processDueTasks finds and executes scheduled tasks that are due
+// processDueTasks finds and executes scheduled tasks that are due: one-shot
+// tasks whose absolute scheduled_time has arrived, and recurring (cron_expression
+// set) tasks whose next_run has arrived and whose maintenance_window (if any)
+// is currently open.
 func (s *Scheduler) processDueTasks() {
+	now := time.Now()
 	rows, err := s.db.Query(`
-		SELECT id, job_id, task_type, scheduled_time
+		SELECT id, job_id, task_type, scheduled_time, cron_expression, timezone, maintenance_window
 		FROM scheduled_tasks
-		WHERE status = 'pending' AND scheduled_time <= ?
-	`, time.Now())
+		WHERE status = 'pending' AND (
+			(cron_expression IS NULL AND scheduled_time <= ?) OR
+			(cron_expression IS NOT NULL AND next_run <= ?)
+		)
+	`, now, now)
 	if err != nil {
 		log.Printf("Error fetching due tasks: %v", err)
 		return
 	}
 	defer rows.Close()
 
+	type dueTask struct {
+		ID                int64
+		JobID             int64
+		TaskType          string
+		ScheduledTime     time.Time
+		CronExpression    sql.NullString
+		Timezone          sql.NullString
+		MaintenanceWindow sql.NullString
+	}
+	var tasks []dueTask
+
 	for rows.Next() {
-		var task struct {
-			ID            int64
-			JobID         int64
-			TaskType      string
-			ScheduledTime time.Time
+		var task dueTask
+		if err := rows.Scan(&task.ID, &task.JobID, &task.TaskType, &task.ScheduledTime,
+			&task.CronExpression, &task.Timezone, &task.MaintenanceWindow); err != nil {
+			continue
 		}
+		tasks = append(tasks, task)
+	}
 
-		if err := rows.Scan(&task.ID, &task.JobID, &task.TaskType, &task.ScheduledTime); err != nil {
+	for _, task := range tasks {
+		if !task.CronExpression.Valid {
+			// One-shot task: claimTask atomically marks it running and
+			// takes out our lease; runTask settles it into completed/failed
+			// (or back to pending for a retry, or dead_letter).
+			if !s.claimTask(task.ID) {
+				continue
+			}
+			go s.runTask(task.ID, task.JobID, task.TaskType, false)
+			continue
+		}
+
+		window, err := parseMaintenanceWindow(task.MaintenanceWindow.String)
+		if err != nil {
+			log.Printf("scheduled task %d: %v", task.ID, err)
+			continue
+		}
+		loc, err := loadZone(task.Timezone.String)
+		if err != nil {
+			log.Printf("scheduled task %d: %v", task.ID, err)
 			continue
 		}
+		if !window.isOpen(now.In(loc)) {
+			// Window hasn't opened yet - leave next_run alone and recheck
+			// next tick rather than firing early.
+			continue
+		}
+
+		next, err := nextFireTime(task.CronExpression.String, task.Timezone.String, now)
+		if err != nil {
+			log.Printf("scheduled task %d: %v", task.ID, err)
+			continue
+		}
+		if !s.claimTask(task.ID) {
+			continue
+		}
+		s.db.Exec(`UPDATE scheduled_tasks SET scheduled_time = ?, next_run = ? WHERE id = ?`, next, next, task.ID)
+		go s.runTask(task.ID, task.JobID, task.TaskType, true)
+	}
+}
 
-		// Mark as running
-		s.db.Exec(`UPDATE scheduled_tasks SET status = 'running' WHERE id = ?`, task.ID)
+// claimTask atomically marks a pending scheduled_tasks row 'running' and
+// takes out a lease on it for taskLeaseTTL, so two replicas polling the
+// same due row never both dispatch it - the same UPDATE ... WHERE
+// status='pending' pattern lease.Manager.Acquire uses for migration_leases,
+// applied directly to the row instead of a separate lease table since the
+// request asked for lease columns on scheduled_tasks itself.
+func (s *Scheduler) claimTask(taskID int64) bool {
+	res, err := s.db.Exec(`
+		UPDATE scheduled_tasks
+		SET status = 'running', lease_owner = ?, lease_expires_at = ?
+		WHERE id = ? AND status = 'pending'
+	`, s.workerID, time.Now().Add(taskLeaseTTL), taskID)
+	if err != nil {
+		log.Printf("scheduled task %d: claim failed: %v", taskID, err)
+		return false
+	}
+	affected, err := res.RowsAffected()
+	if err != nil || affected == 0 {
+		return false
+	}
+	return true
+}
 
-		// Execute task
-		go s.executeTask(task.ID, task.JobID, task.TaskType)
+// reclaimAbandonedTasks returns any 'running' scheduled_tasks row whose
+// lease has expired - left behind by a worker that claimed it and then
+// crashed before runTask settled it - back to 'pending' so the next poll
+// picks it up fresh.
+func (s *Scheduler) reclaimAbandonedTasks() {
+	rows, err := s.db.Query(`SELECT id FROM scheduled_tasks WHERE status = 'running' AND lease_expires_at <= ?`, time.Now())
+	if err != nil {
+		log.Printf("scheduler: error scanning for abandoned tasks: %v", err)
+		return
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+
+	for _, id := range ids {
+		res, err := s.db.Exec(`
+			UPDATE scheduled_tasks
+			SET status = 'pending', lease_owner = NULL, lease_expires_at = NULL
+			WHERE id = ? AND status = 'running'
+		`, id)
+		if err != nil {
+			log.Printf("scheduler: error reclaiming abandoned task %d: %v", id, err)
+			continue
+		}
+		if affected, _ := res.RowsAffected(); affected > 0 {
+			log.Printf("scheduler: reclaimed abandoned task %d (lease expired)", id)
+			s.logTaskActivity(id, "reclaimed", "lease expired; orphaned by crashed worker")
+		}
 	}
 }
 
-// processSyncJobs finds migration jobs that need syncing
+// processSyncJobs finds migration jobs that need syncing. A job skips its
+// interval-based sync while a pending recurring scheduled_tasks row of
+// task_type 'sync' for the same job has a maintenance_window that isn't
+// currently open - letting an operator confine interval syncs to a window
+// (e.g. "only 22:00-04:00 UTC on weekdays") without having to drive every
+// sync purely off that scheduled_tasks row's own cron.
 func (s *Scheduler) processSyncJobs() {
 	rows, err := s.db.Query(`
 		SELECT m.id, m.vm_id, m.source_env_id, m.target_env_id, m.sync_interval_minutes,
 			m.preserve_mac, m.preserve_port_groups, v.name as vm_name,
-			COALESCE(MAX(sh.created_at), m.created_at) as last_sync
+			COALESCE(MAX(sh.created_at), m.created_at) as last_sync,
+			st.maintenance_window, st.timezone
 		FROM migration_jobs m
 		JOIN vms v ON m.vm_id = v.id
 		LEFT JOIN sync_history sh ON m.id = sh.job_id AND sh.status = 'completed'
+		LEFT JOIN scheduled_tasks st ON st.job_id = m.id AND st.task_type = 'sync'
+			AND st.status = 'pending' AND st.cron_expression IS NOT NULL
 		WHERE m.status IN ('syncing', 'ready')
 		GROUP BY m.id
 	`)
@@ -115,54 +409,210 @@ func (s *Scheduler) processSyncJobs() {
 			PreservePortGroups  bool
 			VMName              string
 			LastSync            time.Time
+			MaintenanceWindow   sql.NullString
+			Timezone            sql.NullString
 		}
 
 		if err := rows.Scan(&job.ID, &job.VMID, &job.SourceEnvID, &job.TargetEnvID,
 			&job.SyncIntervalMinutes, &job.PreserveMAC, &job.PreservePortGroups,
-			&job.VMName, &job.LastSync); err != nil {
+			&job.VMName, &job.LastSync, &job.MaintenanceWindow, &job.Timezone); err != nil {
+			continue
+		}
+
+		window, err := parseMaintenanceWindow(job.MaintenanceWindow.String)
+		if err != nil {
+			log.Printf("migration job %d: %v", job.ID, err)
+			continue
+		}
+		loc, err := loadZone(job.Timezone.String)
+		if err != nil {
+			log.Printf("migration job %d: %v", job.ID, err)
+			continue
+		}
+		if !window.isOpen(time.Now().In(loc)) {
 			continue
 		}
 
 		// Check if sync is due
 		nextSync := job.LastSync.Add(time.Duration(job.SyncIntervalMinutes) * time.Minute)
 		if time.Now().After(nextSync) {
-			go s.performSync(job.ID, job.VMName, job.PreserveMAC, job.PreservePortGroups)
+			s.dispatchSync(job.ID, job.VMName, job.PreserveMAC, job.PreservePortGroups)
 		}
 	}
 }
 
-// executeTask executes a scheduled task
-func (s *Scheduler) executeTask(taskID, jobID int64, taskType string) {
+// runTask dispatches a claimed scheduled_tasks row to its task_type's
+// registered handler, heartbeating the lease claimTask took out while the
+// handler runs, and settles the row afterwards. A recurring task (its
+// next_run/scheduled_time already advanced by processDueTasks) always
+// returns to 'pending' for its next fire, regardless of outcome - it isn't
+// subject to retry/dead_letter since the next scheduled fire supersedes a
+// retry anyway. A one-shot task either completes, or falls through to
+// settleFailedTask to retry with backoff or dead-letter.
+func (s *Scheduler) runTask(taskID, jobID int64, taskType string, recurring bool) {
 	startTime := time.Now()
-	var result string
-	var err error
 
-	switch taskType {
-	case "cutover":
-		err = s.TriggerCutover(jobID)
-	case "failover":
-		err = s.TriggerCutover(jobID) // Failover uses same logic
-	case "sync":
-		s.TriggerSync(jobID)
-	case "test_failover":
-		err = s.performTestFailover(jobID)
+	heartbeatDone := make(chan struct{})
+	go s.heartbeatTaskLease(taskID, heartbeatDone)
+	defer close(heartbeatDone)
+
+	handler, ok := taskHandlers[taskType]
+	var err error
+	if !ok {
+		err = fmt.Errorf("no handler registered for task_type %q", taskType)
+	} else {
+		err = handler(s, jobID)
 	}
 
-	status := "completed"
+	var result string
 	if err != nil {
-		status = "failed"
 		result = err.Error()
 	}
 
+	if recurring {
+		s.db.Exec(`
+			UPDATE scheduled_tasks
+			SET status = 'pending', result = ?, executed_at = ?, lease_owner = NULL, lease_expires_at = NULL
+			WHERE id = ?
+		`, result, startTime, taskID)
+		s.logTaskActivity(taskID, "executed", result)
+		return
+	}
+
+	if err == nil {
+		s.db.Exec(`
+			UPDATE scheduled_tasks
+			SET status = 'completed', result = ?, executed_at = ?, lease_owner = NULL, lease_expires_at = NULL
+			WHERE id = ?
+		`, result, startTime, taskID)
+		s.logTaskActivity(taskID, "completed", result)
+		return
+	}
+
+	s.settleFailedTask(taskID, startTime, result)
+}
+
+// heartbeatTaskLease renews taskID's lease_expires_at at roughly a third of
+// taskLeaseTTL until done is closed, mirroring lease.Manager's heartbeat, so
+// a handler that legitimately runs longer than the TTL isn't reclaimed out
+// from under the worker still running it.
+func (s *Scheduler) heartbeatTaskLease(taskID int64, done <-chan struct{}) {
+	ticker := time.NewTicker(taskLeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			res, err := s.db.Exec(`
+				UPDATE scheduled_tasks
+				SET lease_expires_at = ?
+				WHERE id = ? AND lease_owner = ?
+			`, time.Now().Add(taskLeaseTTL), taskID, s.workerID)
+			if err != nil {
+				log.Printf("scheduled task %d: lease renewal failed: %v", taskID, err)
+				continue
+			}
+			if affected, _ := res.RowsAffected(); affected == 0 {
+				log.Printf("scheduled task %d: lease lost to another worker mid-run", taskID)
+				return
+			}
+		}
+	}
+}
+
+// settleFailedTask requeues taskID for a backoff retry, or moves it to
+// 'dead_letter' once retry_count would exceed max_retries.
+func (s *Scheduler) settleFailedTask(taskID int64, startTime time.Time, result string) {
+	var retryCount, maxRetries int
+	if err := s.db.QueryRow(`SELECT retry_count, max_retries FROM scheduled_tasks WHERE id = ?`, taskID).
+		Scan(&retryCount, &maxRetries); err != nil {
+		log.Printf("scheduled task %d: failed to read retry state: %v", taskID, err)
+		return
+	}
+	retryCount++
+
+	if retryCount > maxRetries {
+		s.db.Exec(`
+			UPDATE scheduled_tasks
+			SET status = 'dead_letter', result = ?, executed_at = ?, retry_count = ?, lease_owner = NULL, lease_expires_at = NULL
+			WHERE id = ?
+		`, result, startTime, retryCount, taskID)
+		s.logTaskActivity(taskID, "dead_letter", result)
+		return
+	}
+
+	next := time.Now().Add(taskRetryBackoff(retryCount))
 	s.db.Exec(`
 		UPDATE scheduled_tasks
-		SET status = ?, result = ?, executed_at = ?
+		SET status = 'pending', result = ?, executed_at = ?, retry_count = ?, next_attempt_at = ?, scheduled_time = ?, lease_owner = NULL, lease_expires_at = NULL
 		WHERE id = ?
-	`, status, result, startTime, taskID)
+	`, result, startTime, retryCount, next, next, taskID)
+	s.logTaskActivity(taskID, "retry_scheduled", result)
+}
+
+// taskRetryBackoff returns the capped exponential backoff before retrying a
+// failed scheduled task, doubling from taskRetryBaseBackoff each attempt -
+// the same doubling-loop shape as mrfBackoff, kept as a separate function
+// since it backs off on a different pair of constants.
+func taskRetryBackoff(retryCount int) time.Duration {
+	if retryCount < 1 {
+		retryCount = 1
+	}
+	delay := taskRetryBaseBackoff
+	for i := 1; i < retryCount && delay < taskRetryMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > taskRetryMaxBackoff {
+		delay = taskRetryMaxBackoff
+	}
+	return delay
+}
+
+// logTaskActivity records a scheduled task's state transition into
+// activity_logs so GET /schedules/{id}/events can return a timeline.
+// user_id is 0, the same sentinel logActivity falls back to when a username
+// doesn't resolve to a row - there's no user to attribute a scheduler-driven
+// transition to.
+func (s *Scheduler) logTaskActivity(taskID int64, action, details string) {
+	s.db.Exec(`
+		INSERT INTO activity_logs (user_id, action, entity_type, entity_id, details, ip_address, request_id)
+		VALUES (0, ?, 'scheduled_task', ?, ?, '', '')
+	`, action, taskID, details)
+}
+
+// RetryTask manually resets a failed, dead_letter, or cancelled scheduled
+// task back to pending for immediate re-execution, clearing its lease and
+// retry_count - the operator is asserting the underlying problem is fixed,
+// so it shouldn't immediately re-exhaust max_retries on the next failure.
+// Backs POST /schedules/{id}/retry.
+func (s *Scheduler) RetryTask(taskID int64) error {
+	res, err := s.db.Exec(`
+		UPDATE scheduled_tasks
+		SET status = 'pending', retry_count = 0, next_attempt_at = NULL, scheduled_time = ?, lease_owner = NULL, lease_expires_at = NULL
+		WHERE id = ? AND status IN ('failed', 'dead_letter', 'cancelled')
+	`, time.Now(), taskID)
+	if err != nil {
+		return fmt.Errorf("retry scheduled task %d: %w", taskID, err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("retry scheduled task %d: %w", taskID, err)
+	}
+	if affected == 0 {
+		return fmt.Errorf("scheduled task %d is not in a retryable state", taskID)
+	}
+	s.logTaskActivity(taskID, "manual_retry", "retried by operator")
+	return nil
 }
 
-// TriggerSync triggers a sync operation for a migration job
-func (s *Scheduler) TriggerSync(jobID int64) {
+// TriggerSync triggers a sync operation for a migration job. ctx is
+// cancelled when the caller loses the migration lease (see
+// handlers.triggerSync); performSync and everything it drives check ctx
+// between steps so a lost lease actually aborts the in-flight sync instead
+// of leaving it running unsupervised.
+func (s *Scheduler) TriggerSync(ctx context.Context, jobID int64) error {
 	// Get job details
 	var job struct {
 		ID                 int64
@@ -190,14 +640,15 @@ func (s *Scheduler) TriggerSync(jobID int64) {
 		&job.SourceType, &job.TargetType)
 	if err != nil {
 		log.Printf("Error getting job %d: %v", jobID, err)
-		return
+		return err
 	}
 
-	s.performSync(jobID, job.VMName, job.PreserveMAC, job.PreservePortGroups)
+	s.performSync(ctx, jobID, job.VMName, job.PreserveMAC, job.PreservePortGroups)
+	return nil
 }
 
 // performSync performs the actual sync operation
-func (s *Scheduler) performSync(jobID int64, vmName string, preserveMAC, preservePortGroups bool) {
+func (s *Scheduler) performSync(ctx context.Context, jobID int64, vmName string, preserveMAC, preservePortGroups bool) {
 	startTime := time.Now()
 
 	// Record sync start
@@ -208,6 +659,9 @@ func (s *Scheduler) performSync(jobID int64, vmName string, preserveMAC, preserv
 
 	// Update job status
 	s.db.Exec(`UPDATE migration_jobs SET status = 'syncing' WHERE id = ?`, jobID)
+	s.webhooks.Dispatch(webhooks.EventSyncing, "migration_job", jobID, "syncing", "")
+	s.stream.Publish(jobID, migrationstream.EventAdded, "syncing", 0, "sync started")
+	s.events.Publish(events.TypeMigrationSyncStarted, vmName, map[string]interface{}{"job_id": jobID})
 
 	// Get source and target configs
 	var sourceType, targetType string
@@ -221,9 +675,32 @@ func (s *Scheduler) performSync(jobID int64, vmName string, preserveMAC, preserv
 		WHERE m.id = ?
 	`, jobID).Scan(&sourceType, &sourceConfig, &targetType, &targetConfig)
 
+	if targetType == "aws" {
+		s.performMGNSync(ctx, jobID, vmName, s.decodeConfig(targetConfig), startTime)
+		return
+	}
+
 	// Create sync manager and perform sync
-	syncMgr := sync.NewSyncManager(jobID, sourceType, targetType, nil, nil)
-	result, err := syncMgr.PerformSync(vmName, preserveMAC, preservePortGroups)
+	syncMgr := sync.NewSyncManager(jobID, sourceType, targetType, s.decodeConfig(sourceConfig), s.decodeConfig(targetConfig))
+	syncMgr.SetEventsPublisher(s.events)
+	syncMgr.SetTransferOptions(s.syncTransferWorkers, s.syncMaxInFlightBytes)
+	syncMgr.SetProgressFunc(func(percent int, message string) {
+		s.stream.Publish(jobID, migrationstream.EventModified, "syncing", percent, message)
+	})
+	syncMgr.SetByteProgressFunc(func(p sync.Progress) {
+		s.updateSyncProgress(jobID, p)
+	})
+	if policy, err := s.snapshots.Policy(jobID); err != nil {
+		log.Printf("scheduler: failed to load snapshot policy for job %d, using default: %v", jobID, err)
+	} else {
+		syncMgr.SetSnapshotPolicy(policy)
+	}
+	syncMgr.SetSnapshotCreatedFunc(func(rec sync.SnapshotRecord) {
+		if err := s.snapshots.RecordSnapshot(rec); err != nil {
+			log.Printf("scheduler: failed to record snapshot %s for job %d: %v", rec.SnapshotID, jobID, err)
+		}
+	})
+	result, err := syncMgr.PerformSync(ctx, vmName, preserveMAC, preservePortGroups)
 
 	// Record result
 	status := "completed"
@@ -233,55 +710,444 @@ func (s *Scheduler) performSync(jobID int64, vmName string, preserveMAC, preserv
 		errorMsg = err.Error()
 	}
 
-	duration := int(time.Since(startTime).Seconds())
+	duration := time.Since(startTime)
 	bytesTransferred := int64(0)
 	if result != nil {
 		bytesTransferred = result.BytesTransferred
 	}
+	metrics.SyncDurationSeconds.Observe(duration.Seconds())
+	metrics.SyncBytesTransferredTotal.WithLabelValues(jobLabel(jobID), sourceType, targetType).Add(float64(bytesTransferred))
 
 	s.db.Exec(`
 		INSERT INTO sync_history (job_id, status, bytes_transferred, duration_seconds, error_message)
 		VALUES (?, ?, ?, ?, ?)
-	`, jobID, status, bytesTransferred, duration, errorMsg)
+	`, jobID, status, bytesTransferred, int(duration.Seconds()), errorMsg)
 
 	// Update job status
 	if err != nil {
 		s.db.Exec(`UPDATE migration_jobs SET status = 'failed', error_message = ? WHERE id = ?`, errorMsg, jobID)
+		s.webhooks.Dispatch(webhooks.EventFailed, "migration_job", jobID, "failed", errorMsg)
+		s.stream.Publish(jobID, migrationstream.EventError, "failed", 0, errorMsg)
+		s.events.Publish(events.TypeMigrationSyncFailed, vmName, map[string]interface{}{"job_id": jobID, "error": errorMsg})
+		s.enqueueSyncRetry(jobID, errorMsg)
 	} else {
 		s.db.Exec(`UPDATE migration_jobs SET status = 'ready' WHERE id = ?`, jobID)
+		s.webhooks.Dispatch(webhooks.Event("ready"), "migration_job", jobID, "ready", "")
+		s.events.Publish(events.TypeMigrationSyncCompleted, vmName, map[string]interface{}{"job_id": jobID, "bytes_transferred": bytesTransferred})
+		s.stream.Publish(jobID, migrationstream.EventModified, "ready", 100, "sync finished")
+		s.db.Exec(`DELETE FROM sync_retry_queue WHERE job_id = ?`, jobID)
+	}
+}
+
+// performMGNSync polls AWS Application Migration Service (MGN) for
+// vmName's replication health instead of driving the generic CBT sync.SyncManager
+// path - an "aws" target job replicates continuously in the background via
+// MGN's own agent, so "sync" here means resuming/checking on that
+// replication rather than copying bytes ourselves. startTime is the value
+// performSync captured before recording the 'started' sync_history row, so
+// duration_seconds covers the whole call, not just the polling.
+func (s *Scheduler) performMGNSync(ctx context.Context, jobID int64, vmName string, targetConfig map[string]interface{}, startTime time.Time) {
+	status, lagSeconds, errorMsg := s.pollMGNReplication(ctx, jobID, targetConfig)
+	duration := time.Since(startTime)
+	metrics.SyncDurationSeconds.Observe(duration.Seconds())
+
+	s.db.Exec(`
+		INSERT INTO sync_history (job_id, status, replication_lag_seconds, duration_seconds, error_message)
+		VALUES (?, ?, ?, ?, ?)
+	`, jobID, status, lagSeconds, int(duration.Seconds()), errorMsg)
+
+	if status == "failed" {
+		s.db.Exec(`UPDATE migration_jobs SET status = 'failed', error_message = ? WHERE id = ?`, errorMsg, jobID)
+		s.webhooks.Dispatch(webhooks.EventFailed, "migration_job", jobID, "failed", errorMsg)
+		s.stream.Publish(jobID, migrationstream.EventError, "failed", 0, errorMsg)
+		s.events.Publish(events.TypeMigrationSyncFailed, vmName, map[string]interface{}{"job_id": jobID, "error": errorMsg})
+		s.enqueueSyncRetry(jobID, errorMsg)
+		return
+	}
+
+	s.db.Exec(`UPDATE migration_jobs SET status = 'ready' WHERE id = ?`, jobID)
+	s.webhooks.Dispatch(webhooks.Event("ready"), "migration_job", jobID, "ready", "")
+	s.events.Publish(events.TypeMigrationSyncCompleted, vmName, map[string]interface{}{"job_id": jobID, "replication_lag_seconds": lagSeconds})
+	s.stream.Publish(jobID, migrationstream.EventModified, "ready", 100, fmt.Sprintf("replication lag %ds", lagSeconds))
+	s.db.Exec(`DELETE FROM sync_retry_queue WHERE job_id = ?`, jobID)
+}
+
+// pollMGNReplication looks up jobID's MGN source server and reports whether
+// its replication is healthy enough to call the job "ready", along with its
+// current lag so callers can record it in place of bytes_transferred.
+func (s *Scheduler) pollMGNReplication(ctx context.Context, jobID int64, targetConfig map[string]interface{}) (status string, lagSeconds int64, errorMsg string) {
+	if err := ctx.Err(); err != nil {
+		return "failed", 0, err.Error()
+	}
+
+	sourceServerID, err := s.mgnSourceServerID(jobID)
+	if err != nil {
+		return "failed", 0, err.Error()
+	}
+
+	client, err := newMGNClient(targetConfig)
+	if err != nil {
+		return "failed", 0, err.Error()
+	}
+
+	server, err := client.GetSourceServer(sourceServerID)
+	if err != nil {
+		return "failed", 0, err.Error()
+	}
+
+	lagSeconds = int64(server.ReplicationLag.Seconds())
+	if !mgnReplicationHealthy(server.ReplicationState) {
+		return "failed", lagSeconds, fmt.Sprintf("MGN replication state for source server %s is %s", sourceServerID, server.ReplicationState)
+	}
+	return "completed", lagSeconds, ""
+}
+
+// mgnReplicationHealthy reports whether state represents MGN actively
+// replicating data rather than stalled, disconnected, or not yet started -
+// see the MGN SDK's types.DataReplicationState for the full state list.
+func mgnReplicationHealthy(state string) bool {
+	switch state {
+	case "CONTINUOUS", "BACKLOG", "RESCAN":
+		return true
+	default:
+		return false
+	}
+}
+
+// performMGNCutover launches and finalizes an MGN cutover instance for
+// jobID's source server, replacing syncMgr.PerformCutover for "aws" target
+// jobs.
+func (s *Scheduler) performMGNCutover(ctx context.Context, jobID int64, targetConfig map[string]interface{}) error {
+	sourceServerID, err := s.mgnSourceServerID(jobID)
+	if err != nil {
+		return err
+	}
+
+	client, err := newMGNClient(targetConfig)
+	if err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("cutover aborted before starting MGN cutover for source server %s: %w", sourceServerID, err)
+	}
+	if _, err := client.StartCutover(sourceServerID); err != nil {
+		return fmt.Errorf("start MGN cutover for source server %s: %w", sourceServerID, err)
+	}
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("cutover aborted before finalizing MGN cutover for source server %s: %w", sourceServerID, err)
+	}
+	if err := client.FinalizeCutover(sourceServerID); err != nil {
+		return fmt.Errorf("finalize MGN cutover for source server %s: %w", sourceServerID, err)
+	}
+	return nil
+}
+
+// performMGNTestFailover launches an MGN test instance for jobID's source
+// server and tears it down immediately after. A real test failover is meant
+// to be verified by an operator before teardown; this is a simplified
+// version that tears the test instance down as soon as StartTest reports
+// success rather than waiting on a separate "done verifying" signal.
+func (s *Scheduler) performMGNTestFailover(jobID int64, targetConfig map[string]interface{}) error {
+	sourceServerID, err := s.mgnSourceServerID(jobID)
+	if err != nil {
+		return err
+	}
+
+	client, err := newMGNClient(targetConfig)
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.StartTest(sourceServerID); err != nil {
+		return fmt.Errorf("start MGN test failover for source server %s: %w", sourceServerID, err)
+	}
+	if err := client.TerminateTestInstances(sourceServerID); err != nil {
+		return fmt.Errorf("tear down MGN test instance for source server %s: %w", sourceServerID, err)
+	}
+	return nil
+}
+
+// mgnSourceServerID returns jobID's VM's registered MGN source server ID,
+// erroring out if the VM hasn't been registered with MGN yet (see
+// vms.mgn_source_server_id in db.go) rather than guessing which source
+// server it might be.
+func (s *Scheduler) mgnSourceServerID(jobID int64) (string, error) {
+	var sourceServerID sql.NullString
+	err := s.db.QueryRow(`
+		SELECT v.mgn_source_server_id
+		FROM migration_jobs m
+		JOIN vms v ON m.vm_id = v.id
+		WHERE m.id = ?
+	`, jobID).Scan(&sourceServerID)
+	if err != nil {
+		return "", fmt.Errorf("look up MGN source server for job %d: %w", jobID, err)
+	}
+	if !sourceServerID.Valid || sourceServerID.String == "" {
+		return "", fmt.Errorf("job %d has no mgn_source_server_id; register its VM with MGN before syncing", jobID)
+	}
+	return sourceServerID.String, nil
+}
+
+// newMGNClient builds an aws.MGNClient from a target environment's decoded
+// config_json, the same region/access_key_id/secret_access_key fields
+// aws.Options decodes for the generic "aws" cloud.Provider driver.
+func newMGNClient(config map[string]interface{}) (*aws.MGNClient, error) {
+	region, _ := config["region"].(string)
+	accessKeyID, _ := config["access_key_id"].(string)
+	secretAccessKey, _ := config["secret_access_key"].(string)
+
+	client, err := aws.NewMGNClient(aws.Config{
+		Region:          region,
+		AccessKeyID:     accessKeyID,
+		SecretAccessKey: secretAccessKey,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("build MGN client: %w", err)
+	}
+	return client, nil
+}
+
+// reapSnapshots garbage-collects CBT reference snapshots past their
+// retention window, across every job that has any. For each snapshot
+// retention.Manager.Reap decides to remove, it resolves that job's source
+// cloud.Provider and type-asserts it for cloud.SnapshotDeleter - not every
+// driver implements snapshot deletion yet (today, only vmware does), so a
+// job on one of those is skipped with a log line rather than treated as an
+// error.
+func (s *Scheduler) reapSnapshots() {
+	s.snapshots.Reap(func(rec sync.SnapshotRecord) error {
+		var sourceType string
+		var sourceConfig sql.NullString
+		err := s.db.QueryRow(`
+			SELECT s.type, s.config_json
+			FROM migration_jobs m
+			JOIN source_environments s ON m.source_env_id = s.id
+			WHERE m.id = ?
+		`, rec.JobID).Scan(&sourceType, &sourceConfig)
+		if err != nil {
+			return fmt.Errorf("look up source environment for job %d: %w", rec.JobID, err)
+		}
+
+		configJSON, err := json.Marshal(s.decodeConfig(sourceConfig))
+		if err != nil {
+			return fmt.Errorf("encode source config for job %d: %w", rec.JobID, err)
+		}
+		provider, err := cloud.New(sourceType, configJSON)
+		if err != nil {
+			return fmt.Errorf("build %s provider for job %d: %w", sourceType, rec.JobID, err)
+		}
+
+		deleter, ok := provider.(cloud.SnapshotDeleter)
+		if !ok {
+			return fmt.Errorf("%s driver does not support snapshot deletion", sourceType)
+		}
+		return deleter.DeleteSnapshot(rec.ResourceID, rec.SnapshotID)
+	})
+}
+
+// jobLabel formats jobID as a Prometheus label value.
+func jobLabel(jobID int64) string {
+	return strconv.FormatInt(jobID, 10)
+}
+
+// updateSyncProgress upserts jobID's sync_progress row with p, overwriting
+// the previous sample rather than accumulating history (unlike sync_history,
+// this table only ever reflects the most recent sync's live state).
+func (s *Scheduler) updateSyncProgress(jobID int64, p sync.Progress) {
+	s.db.Exec(`
+		INSERT INTO sync_progress (job_id, phase, bytes_total, bytes_done, throughput_bps, eta_seconds, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT(job_id) DO UPDATE SET
+			phase = excluded.phase,
+			bytes_total = excluded.bytes_total,
+			bytes_done = excluded.bytes_done,
+			throughput_bps = excluded.throughput_bps,
+			eta_seconds = excluded.eta_seconds,
+			updated_at = excluded.updated_at
+	`, jobID, p.Phase, p.BytesTotal, p.BytesDone, p.ThroughputBPS, p.ETASeconds, time.Now())
+}
+
+// GetSyncProgress returns jobID's most recently reported sync progress. It
+// returns sql.ErrNoRows if the job has never synced (no sync_progress row
+// exists yet).
+func (s *Scheduler) GetSyncProgress(jobID int64) (*db.SyncProgress, error) {
+	var p db.SyncProgress
+	p.JobID = jobID
+	err := s.db.QueryRow(`
+		SELECT phase, bytes_total, bytes_done, throughput_bps, eta_seconds, updated_at
+		FROM sync_progress WHERE job_id = ?
+	`, jobID).Scan(&p.Phase, &p.BytesTotal, &p.BytesDone, &p.ThroughputBPS, &p.ETASeconds, &p.UpdatedAt)
+	if err != nil {
+		return nil, err
 	}
+	return &p, nil
 }
 
-// TriggerCutover triggers a cutover operation for a migration job
-func (s *Scheduler) TriggerCutover(jobID int64) error {
+// enqueueSyncRetry upserts jobID into sync_retry_queue after a failed
+// performSync, bumping its attempt count and backing off exponentially
+// (capped at mrfMaxBackoff) rather than queuing a duplicate row.
+func (s *Scheduler) enqueueSyncRetry(jobID int64, lastError string) {
+	var priorAttempt int
+	s.db.QueryRow(`SELECT attempt FROM sync_retry_queue WHERE job_id = ?`, jobID).Scan(&priorAttempt)
+
+	attempt := priorAttempt + 1
+	next := time.Now().Add(mrfBackoff(attempt))
+
+	s.db.Exec(`
+		INSERT INTO sync_retry_queue (job_id, attempt, next_attempt_at, last_error)
+		VALUES (?, ?, ?, ?)
+		ON CONFLICT(job_id) DO UPDATE SET
+			attempt = excluded.attempt,
+			next_attempt_at = excluded.next_attempt_at,
+			last_error = excluded.last_error
+	`, jobID, attempt, next, lastError)
+}
+
+// mrfBackoff returns the capped exponential backoff delay for attempt,
+// doubling from mrfBaseBackoff each attempt. attempt is 1-indexed; 0 is
+// treated the same as 1 so callers don't need to special-case the first try.
+func mrfBackoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	delay := mrfBaseBackoff
+	for i := 1; i < attempt && delay < mrfMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > mrfMaxBackoff {
+		delay = mrfMaxBackoff
+	}
+	return delay
+}
+
+// processMRFQueue retries migration_jobs queued in sync_retry_queue whose
+// next_attempt_at has elapsed. A successful retry's row is removed by
+// performSync itself; a repeat failure re-enqueues with a longer backoff via
+// enqueueSyncRetry.
+func (s *Scheduler) processMRFQueue() {
+	rows, err := s.db.Query(`
+		SELECT job_id FROM sync_retry_queue WHERE next_attempt_at <= ?
+	`, time.Now())
+	if err != nil {
+		log.Printf("Error fetching sync_retry_queue: %v", err)
+		return
+	}
+	defer rows.Close()
+
+	var jobIDs []int64
+	for rows.Next() {
+		var jobID int64
+		if err := rows.Scan(&jobID); err != nil {
+			continue
+		}
+		jobIDs = append(jobIDs, jobID)
+	}
+
+	for _, jobID := range jobIDs {
+		var job struct {
+			VMName             string
+			PreserveMAC        bool
+			PreservePortGroups bool
+		}
+		err := s.db.QueryRow(`
+			SELECT v.name, m.preserve_mac, m.preserve_port_groups
+			FROM migration_jobs m
+			JOIN vms v ON m.vm_id = v.id
+			WHERE m.id = ?
+		`, jobID).Scan(&job.VMName, &job.PreserveMAC, &job.PreservePortGroups)
+		if err != nil {
+			log.Printf("MRF retry: error loading job %d: %v", jobID, err)
+			continue
+		}
+
+		s.dispatchSync(jobID, job.VMName, job.PreserveMAC, job.PreservePortGroups)
+	}
+}
+
+// refreshGaugeMetrics recomputes the gauges that reflect current database
+// state rather than a single event (pending/failed job counts, MRF backlog),
+// so they stay accurate even across scheduler restarts.
+func (s *Scheduler) refreshGaugeMetrics() {
+	var pending, failed, mrfBacklog int64
+
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM migration_jobs WHERE status IN ('syncing', 'ready')`).Scan(&pending); err == nil {
+		metrics.SyncPendingCount.Set(float64(pending))
+	}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM migration_jobs WHERE status = 'failed'`).Scan(&failed); err == nil {
+		metrics.SyncFailedCount.Set(float64(failed))
+	}
+	if err := s.db.QueryRow(`SELECT COUNT(*) FROM sync_retry_queue`).Scan(&mrfBacklog); err == nil {
+		metrics.SyncMRFBacklog.Set(float64(mrfBacklog))
+	}
+}
+
+// TriggerCutover triggers a cutover operation for a migration job. ctx is
+// cancelled when the caller loses the migration lease (see
+// handlers.triggerCutover); performMGNCutover and SyncManager.PerformCutover
+// check ctx between phases so a lost lease aborts the cutover instead of
+// leaving it running unsupervised.
+func (s *Scheduler) TriggerCutover(ctx context.Context, jobID int64) error {
 	// Get job details
 	var vmName, sourceType, targetType string
+	var sourceConfig, targetConfig sql.NullString
 	err := s.db.QueryRow(`
-		SELECT v.name, s.type, t.type
+		SELECT v.name, s.type, s.config_json, t.type, t.config_json
 		FROM migration_jobs m
 		JOIN vms v ON m.vm_id = v.id
 		JOIN source_environments s ON m.source_env_id = s.id
 		JOIN target_environments t ON m.target_env_id = t.id
 		WHERE m.id = ?
-	`, jobID).Scan(&vmName, &sourceType, &targetType)
+	`, jobID).Scan(&vmName, &sourceType, &sourceConfig, &targetType, &targetConfig)
 	if err != nil {
 		return err
 	}
 
 	// Update status
 	s.db.Exec(`UPDATE migration_jobs SET status = 'cutting_over', started_at = ? WHERE id = ?`, time.Now(), jobID)
+	s.webhooks.Dispatch(webhooks.EventCuttingOver, "migration_job", jobID, "cutting_over", "")
+	s.stream.Publish(jobID, migrationstream.EventAdded, "cutting_over", 0, "cutover started")
+	s.events.Publish(events.TypeMigrationCutoverStarted, vmName, map[string]interface{}{"job_id": jobID})
+
+	cutoverStart := time.Now()
 
 	// Perform cutover
-	syncMgr := sync.NewSyncManager(jobID, sourceType, targetType, nil, nil)
-	err = syncMgr.PerformCutover(vmName)
+	if targetType == "aws" {
+		err = s.performMGNCutover(ctx, jobID, s.decodeConfig(targetConfig))
+	} else {
+		syncMgr := sync.NewSyncManager(jobID, sourceType, targetType, s.decodeConfig(sourceConfig), s.decodeConfig(targetConfig))
+		syncMgr.SetEventsPublisher(s.events)
+		syncMgr.SetTransferOptions(s.syncTransferWorkers, s.syncMaxInFlightBytes)
+		syncMgr.SetProgressFunc(func(percent int, message string) {
+			s.stream.Publish(jobID, migrationstream.EventModified, "cutting_over", percent, message)
+		})
+		if policy, err := s.snapshots.Policy(jobID); err != nil {
+			log.Printf("scheduler: failed to load snapshot policy for job %d, using default: %v", jobID, err)
+		} else {
+			syncMgr.SetSnapshotPolicy(policy)
+		}
+		syncMgr.SetSnapshotCreatedFunc(func(rec sync.SnapshotRecord) {
+			if err := s.snapshots.RecordSnapshot(rec); err != nil {
+				log.Printf("scheduler: failed to record snapshot %s for job %d: %v", rec.SnapshotID, jobID, err)
+			}
+		})
+		err = syncMgr.PerformCutover(ctx, vmName)
+	}
+	metrics.CutoverSeconds.Observe(time.Since(cutoverStart).Seconds())
 
 	if err != nil {
 		s.db.Exec(`UPDATE migration_jobs SET status = 'failed', error_message = ? WHERE id = ?`, err.Error(), jobID)
+		s.webhooks.Dispatch(webhooks.EventFailed, "migration_job", jobID, "failed", err.Error())
+		s.stream.Publish(jobID, migrationstream.EventError, "failed", 0, err.Error())
+		s.events.Publish(events.TypeMigrationCutoverFailed, vmName, map[string]interface{}{"job_id": jobID, "error": err.Error()})
 		return err
 	}
 
 	// Mark as completed
 	s.db.Exec(`UPDATE migration_jobs SET status = 'completed', completed_at = ?, progress = 100 WHERE id = ?`, time.Now(), jobID)
+	s.webhooks.Dispatch(webhooks.EventCompleted, "migration_job", jobID, "completed", "")
+	s.stream.Publish(jobID, migrationstream.EventModified, "completed", 100, "cutover finished")
+	s.events.Publish(events.TypeMigrationCutoverCompleted, vmName, map[string]interface{}{"job_id": jobID})
 
 	return nil
 }
@@ -292,18 +1158,24 @@ func (s *Scheduler) performTestFailover(jobID int64) error {
 	// without affecting the source VM
 
 	var vmName, targetType string
+	var targetConfig sql.NullString
 	err := s.db.QueryRow(`
-		SELECT v.name, t.type
+		SELECT v.name, t.type, t.config_json
 		FROM migration_jobs m
 		JOIN vms v ON m.vm_id = v.id
 		JOIN target_environments t ON m.target_env_id = t.id
 		WHERE m.id = ?
-	`, jobID).Scan(&vmName, &targetType)
+	`, jobID).Scan(&vmName, &targetType, &targetConfig)
 	if err != nil {
 		return err
 	}
 
 	log.Printf("Performing test failover for VM %s to %s", vmName, targetType)
+	s.events.Publish(events.TypeMigrationFailoverStarted, vmName, map[string]interface{}{"job_id": jobID, "target_type": targetType})
+
+	if targetType == "aws" {
+		return s.performMGNTestFailover(jobID, s.decodeConfig(targetConfig))
+	}
 
 	// Create a test VM at the target with a modified name
 	testVMName := vmName + "-test-failover"
@@ -357,3 +1229,37 @@ func (s *Scheduler) GetSyncHistory(jobID int64) ([]map[string]interface{}, error
 
 	return history, nil
 }
+
+// decodeConfig unmarshals an environment's config_json into the
+// map[string]interface{} form sync.NewSyncManager expects, returning nil
+// for an absent or unparseable column rather than failing the sync - the
+// cloud driver it's handed to will reject a genuinely missing field on its
+// own. Every top-level string value is passed through s.secrets.Decrypt, so
+// a secret field encrypted at rest by the API layer comes back as plaintext
+// here; Decrypt is a no-op for any value that isn't Protector-wrapped, so
+// this is safe to do blindly without knowing which fields are secrets.
+func (s *Scheduler) decodeConfig(raw sql.NullString) map[string]interface{} {
+	if !raw.Valid || raw.String == "" {
+		return nil
+	}
+
+	var config map[string]interface{}
+	if err := json.Unmarshal([]byte(raw.String), &config); err != nil {
+		log.Printf("scheduler: failed to decode config_json: %v", err)
+		return nil
+	}
+
+	for k, v := range config {
+		str, ok := v.(string)
+		if !ok {
+			continue
+		}
+		decrypted, err := s.secrets.Decrypt(str)
+		if err != nil {
+			log.Printf("scheduler: failed to decrypt config_json field %q: %v", k, err)
+			continue
+		}
+		config[k] = decrypted
+	}
+	return config
+}
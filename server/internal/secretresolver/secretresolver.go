@@ -0,0 +1,202 @@
+// Package secretresolver resolves secret reference strings to their live
+// value at the point of use, instead of a config field carrying the secret
+// itself in plaintext. ADBindPass and JWTSecret are resolved this way; cloud
+// credentials in an environment's config_json and env_variables values are
+// not resolved at use (an env_variables row can only be test-resolved
+// through the admin API). A reference can be
+// "vault://secret/data/octopus/aws#access_key" (HashiCorp Vault, KV v2,
+// resolved via AppRole login), "env://VAR_NAME", "file://path", or a
+// literal value that's returned unchanged so existing plaintext configs
+// keep working. Vault lookups are cached with a TTL that refreshes before
+// the lease expires, so short-lived dynamic credentials stay valid without
+// every caller round-tripping to Vault.
+package secretresolver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	vault "github.com/hashicorp/vault/api"
+	"github.com/hashicorp/vault/api/auth/approle"
+)
+
+// defaultCacheTTL bounds how long a resolved vault:// value is reused when
+// Vault didn't attach a lease duration to it (e.g. a static KV v2 secret).
+const defaultCacheTTL = 5 * time.Minute
+
+// VaultConfig configures the AppRole backend used to resolve vault://
+// references. Leave Addr empty to disable Vault support; Resolve will
+// return an error if a vault:// reference is used without it.
+type VaultConfig struct {
+	Addr     string
+	RoleID   string
+	SecretID string
+}
+
+type cacheEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// Resolver resolves secret references, mirroring secrets.Protector's
+// lazy-connect, cache-until-stale shape but for live lookups rather than
+// local AES wrapping.
+type Resolver struct {
+	vault VaultConfig
+
+	mu             sync.Mutex
+	client         *vault.Client
+	tokenExpiresAt time.Time
+	cache          map[string]cacheEntry
+}
+
+// New creates a Resolver. vaultCfg.Addr may be left empty if no vault://
+// references will ever be used.
+func New(vaultCfg VaultConfig) *Resolver {
+	return &Resolver{
+		vault: vaultCfg,
+		cache: make(map[string]cacheEntry),
+	}
+}
+
+// Resolve returns ref's live value. Recognized schemes are "vault://",
+// "env://", and "file://"; anything else is returned unchanged as a
+// literal, so a config field that isn't using dynamic secrets at all keeps
+// working exactly as before.
+func (r *Resolver) Resolve(ctx context.Context, ref string) (string, error) {
+	switch {
+	case strings.HasPrefix(ref, "vault://"):
+		return r.resolveVault(ctx, strings.TrimPrefix(ref, "vault://"))
+	case strings.HasPrefix(ref, "env://"):
+		name := strings.TrimPrefix(ref, "env://")
+		v := os.Getenv(name)
+		if v == "" {
+			return "", fmt.Errorf("secretresolver: environment variable %s is not set", name)
+		}
+		return v, nil
+	case strings.HasPrefix(ref, "file://"):
+		path := strings.TrimPrefix(ref, "file://")
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("secretresolver: read secret file %s: %w", path, err)
+		}
+		return strings.TrimSpace(string(data)), nil
+	default:
+		return ref, nil
+	}
+}
+
+// resolveVault reads "path#field" from Vault's KV v2 engine, e.g.
+// "secret/data/octopus/aws#access_key".
+func (r *Resolver) resolveVault(ctx context.Context, ref string) (string, error) {
+	if r.vault.Addr == "" {
+		return "", fmt.Errorf("secretresolver: vault:// reference used but no Vault address is configured")
+	}
+
+	path, field, err := splitVaultRef(ref)
+	if err != nil {
+		return "", err
+	}
+
+	r.mu.Lock()
+	if entry, ok := r.cache[ref]; ok && time.Now().Before(entry.expiresAt) {
+		r.mu.Unlock()
+		return entry.value, nil
+	}
+	r.mu.Unlock()
+
+	client, err := r.authenticatedClient(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	mount, secretPath := splitMountPath(path)
+	secret, err := client.KVv2(mount).Get(ctx, secretPath)
+	if err != nil {
+		return "", fmt.Errorf("secretresolver: read vault secret %s: %w", path, err)
+	}
+
+	raw, ok := secret.Data[field]
+	if !ok {
+		return "", fmt.Errorf("secretresolver: vault secret %s has no field %q", path, field)
+	}
+	value, ok := raw.(string)
+	if !ok {
+		return "", fmt.Errorf("secretresolver: vault secret %s field %q is not a string", path, field)
+	}
+
+	ttl := defaultCacheTTL
+	if secret.Raw != nil && secret.Raw.LeaseDuration > 0 {
+		// Refresh a bit before the lease actually expires so a caller
+		// never observes an expired dynamic credential.
+		ttl = time.Duration(float64(secret.Raw.LeaseDuration)*0.9) * time.Second
+	}
+
+	r.mu.Lock()
+	r.cache[ref] = cacheEntry{value: value, expiresAt: time.Now().Add(ttl)}
+	r.mu.Unlock()
+
+	return value, nil
+}
+
+// authenticatedClient returns a Vault client logged in via AppRole,
+// re-authenticating once the current token's lease is close to expiring.
+func (r *Resolver) authenticatedClient(ctx context.Context) (*vault.Client, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.client != nil && time.Now().Before(r.tokenExpiresAt) {
+		return r.client, nil
+	}
+
+	client, err := vault.NewClient(&vault.Config{Address: r.vault.Addr})
+	if err != nil {
+		return nil, fmt.Errorf("secretresolver: create vault client: %w", err)
+	}
+
+	auth, err := approle.NewAppRoleAuth(r.vault.RoleID, &approle.SecretID{FromString: r.vault.SecretID})
+	if err != nil {
+		return nil, fmt.Errorf("secretresolver: configure approle auth: %w", err)
+	}
+
+	authInfo, err := client.Auth().Login(ctx, auth)
+	if err != nil {
+		return nil, fmt.Errorf("secretresolver: approle login: %w", err)
+	}
+	if authInfo == nil || authInfo.Auth == nil {
+		return nil, fmt.Errorf("secretresolver: approle login returned no auth info")
+	}
+
+	r.client = client
+	// Log in again a bit before the token's lease actually runs out.
+	r.tokenExpiresAt = time.Now().Add(time.Duration(float64(authInfo.Auth.LeaseDuration)*0.9) * time.Second)
+	return client, nil
+}
+
+// splitVaultRef splits "path#field" into its path and field parts.
+func splitVaultRef(ref string) (path, field string, err error) {
+	idx := strings.LastIndex(ref, "#")
+	if idx < 0 {
+		return "", "", fmt.Errorf("secretresolver: vault reference %q is missing a #field suffix", ref)
+	}
+	return ref[:idx], ref[idx+1:], nil
+}
+
+// splitMountPath separates a KV v2 path's mount from the secret path under
+// it. KV v2 HTTP paths always have the form "<mount>/data/<secret path>";
+// callers are expected to write refs that way (mirroring the real API
+// path), so the mount is whatever precedes "/data/".
+func splitMountPath(path string) (mount, secretPath string) {
+	if idx := strings.Index(path, "/data/"); idx >= 0 {
+		return path[:idx], path[idx+len("/data/"):]
+	}
+	parts := strings.SplitN(path, "/", 2)
+	if len(parts) == 2 {
+		return parts[0], parts[1]
+	}
+	return path, ""
+}
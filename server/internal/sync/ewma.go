@@ -0,0 +1,70 @@
+package sync
+
+import "time"
+
+// rateTracker computes a smoothed transfer rate (bytes/sec) using an
+// exponentially weighted moving average, the same approach gh-ost uses for
+// its ETA: r_new = alpha*r_instant + (1-alpha)*r_old. An alpha around 0.5
+// balances responsiveness against the oscillation a plain instantaneous
+// rate would show when block sizes vary or the source stalls briefly.
+type rateTracker struct {
+	alpha     float64
+	smoothed  float64
+	haveRate  bool
+	startTime time.Time
+	lastTime  time.Time
+	lastBytes int64
+}
+
+// newRateTracker starts a tracker at now with the given smoothing factor.
+func newRateTracker(alpha float64, now time.Time) *rateTracker {
+	return &rateTracker{alpha: alpha, startTime: now, lastTime: now}
+}
+
+// sample folds in bytesDone (cumulative, not per-interval) observed at now
+// and returns the updated smoothed rate in bytes/sec. The very first sample
+// has no prior smoothed rate to blend with, so it falls back to the
+// cumulative average since the tracker started; a non-positive instantaneous
+// rate (e.g. two samples in the same instant, or the source stalling) is
+// left out of the blend rather than allowed to drag the average to zero or
+// negative.
+func (r *rateTracker) sample(bytesDone int64, now time.Time) float64 {
+	interval := now.Sub(r.lastTime).Seconds()
+	instant := 0.0
+	if interval > 0 {
+		instant = float64(bytesDone-r.lastBytes) / interval
+	}
+	if instant < 0 {
+		instant = 0
+	}
+
+	switch {
+	case !r.haveRate:
+		if sinceStart := now.Sub(r.startTime).Seconds(); sinceStart > 0 {
+			r.smoothed = float64(bytesDone) / sinceStart
+		} else {
+			r.smoothed = instant
+		}
+		r.haveRate = true
+	case instant > 0:
+		r.smoothed = r.alpha*instant + (1-r.alpha)*r.smoothed
+	}
+
+	if r.smoothed < 0 {
+		r.smoothed = 0
+	}
+
+	r.lastTime = now
+	r.lastBytes = bytesDone
+	return r.smoothed
+}
+
+// eta returns the estimated seconds remaining to transfer
+// remainingBytes at rateBPS, clamped to 0 when the rate is non-positive
+// (nothing sampled yet) rather than dividing by zero.
+func eta(remainingBytes int64, rateBPS float64) int64 {
+	if rateBPS <= 0 || remainingBytes <= 0 {
+		return 0
+	}
+	return int64(float64(remainingBytes) / rateBPS)
+}
@@ -0,0 +1,111 @@
+package sync
+
+import (
+	"fmt"
+	"time"
+)
+
+// Snapshot retention modes, named after the SnapLock modes AWS FSx ONTAP
+// exposes: Governance allows BypassRetentionRole to delete a snapshot early;
+// Compliance refuses early deletion outright, even with the bypass role.
+const (
+	ModeGovernance = "governance"
+	ModeCompliance = "compliance"
+)
+
+// SnapshotPolicy governs how long a migration job's CBT reference snapshots
+// are kept before the reaper is allowed to garbage-collect them.
+type SnapshotPolicy struct {
+	// MinRetention is how long a snapshot is protected from deletion,
+	// regardless of KeepLastN, unless the caller presents
+	// BypassRetentionRole and Mode is ModeGovernance.
+	MinRetention time.Duration
+	// MaxRetention is how long a snapshot may live before the reaper
+	// deletes it outright, even if it's within KeepLastN. Zero means no
+	// upper bound.
+	MaxRetention time.Duration
+	// Mode is ModeGovernance or ModeCompliance.
+	Mode string
+	// KeepLastN retains the N most recent snapshots for a job even past
+	// MinRetention, so a job keeps a base snapshot CBT can diff against. It
+	// does not override MaxRetention: an expired snapshot is reaped even
+	// when it is among the N most recent.
+	KeepLastN int
+	// BypassRetentionRole, if non-empty, is the role name a caller must
+	// present to DeleteSnapshot to remove a snapshot still within
+	// MinRetention. Ignored when Mode is ModeCompliance.
+	BypassRetentionRole string
+}
+
+// DefaultSnapshotPolicy is applied to a SyncManager that never calls
+// SetSnapshotPolicy: a conservative governance policy that protects
+// snapshots for 24h, keeps the most recent 3, and reaps any snapshot older
+// than 30 days.
+var DefaultSnapshotPolicy = SnapshotPolicy{
+	MinRetention: 24 * time.Hour,
+	MaxRetention: 30 * 24 * time.Hour,
+	Mode:         ModeGovernance,
+	KeepLastN:    3,
+}
+
+// Validate reports whether p is internally consistent.
+func (p SnapshotPolicy) Validate() error {
+	if p.Mode != ModeGovernance && p.Mode != ModeCompliance {
+		return fmt.Errorf("sync: snapshot policy mode must be %q or %q, got %q", ModeGovernance, ModeCompliance, p.Mode)
+	}
+	if p.MaxRetention > 0 && p.MinRetention > p.MaxRetention {
+		return fmt.Errorf("sync: snapshot policy min retention (%s) exceeds max retention (%s)", p.MinRetention, p.MaxRetention)
+	}
+	if p.KeepLastN < 0 {
+		return fmt.Errorf("sync: snapshot policy KeepLastN must be >= 0, got %d", p.KeepLastN)
+	}
+	return nil
+}
+
+// SnapshotRecord is a point-in-time record of one snapshot a SyncManager
+// created, tagged with the policy in effect when it was taken so the reaper
+// can evaluate retention without re-reading the job's current policy (which
+// may have changed since).
+type SnapshotRecord struct {
+	JobID      int64
+	ResourceID string
+	SnapshotID string
+	CreatedAt  time.Time
+	Policy     SnapshotPolicy
+}
+
+// minRetentionExpiry returns when the reaper is first allowed to consider r for
+// deletion under MinRetention, ignoring KeepLastN (which the reaper applies
+// separately across a job's whole snapshot set).
+func (r SnapshotRecord) minRetentionExpiry() time.Time {
+	return r.CreatedAt.Add(r.Policy.MinRetention)
+}
+
+// maxRetentionExpiry returns when r must be deleted outright, or the zero
+// time if Policy.MaxRetention is unbounded.
+func (r SnapshotRecord) maxRetentionExpiry() time.Time {
+	if r.Policy.MaxRetention <= 0 {
+		return time.Time{}
+	}
+	return r.CreatedAt.Add(r.Policy.MaxRetention)
+}
+
+// CanDelete reports whether r may be deleted now given bypassRole (the role
+// the caller presents, or "" for none). It does not account for KeepLastN -
+// callers reaping a whole job's snapshot set should apply that separately.
+func (r SnapshotRecord) CanDelete(now time.Time, bypassRole string) bool {
+	if !now.Before(r.minRetentionExpiry()) {
+		return true
+	}
+	if r.Policy.Mode == ModeCompliance {
+		return false
+	}
+	return r.Policy.BypassRetentionRole != "" && bypassRole == r.Policy.BypassRetentionRole
+}
+
+// IsExpired reports whether r is past MaxRetention and should be reaped
+// regardless of KeepLastN.
+func (r SnapshotRecord) IsExpired(now time.Time) bool {
+	expiry := r.maxRetentionExpiry()
+	return !expiry.IsZero() && now.After(expiry)
+}
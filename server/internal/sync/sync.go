@@ -1,19 +1,87 @@
 package sync
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
+
+	"github.com/sp00nznet/octopus/internal/cloud"
+	"github.com/sp00nznet/octopus/internal/events"
+	"github.com/sp00nznet/octopus/internal/metrics"
+	"github.com/sp00nznet/octopus/internal/pricing"
+	"github.com/sp00nznet/octopus/internal/providers/aws"
+	"github.com/sp00nznet/octopus/internal/providers/azure"
+	"github.com/sp00nznet/octopus/internal/providers/gcp"
 )
 
 // SyncManager handles VM synchronization between source and target
 type SyncManager struct {
-	jobID       int64
-	sourceType  string
-	targetType  string
+	jobID        int64
+	sourceType   string
+	targetType   string
 	sourceConfig map[string]interface{}
 	targetConfig map[string]interface{}
+
+	// sourceProvider and targetProvider are resolved from the cloud
+	// driver registry lazily, on first use, since not every sync step
+	// needs both sides (e.g. powerOnTarget never touches sourceProvider).
+	sourceProvider cloud.Provider
+	targetProvider cloud.Provider
+
+	onProgress     func(percent int, message string)
+	onByteProgress func(Progress)
+	events         *events.Publisher
+
+	// snapshotPolicy tags every snapshot createSourceSnapshot takes; it
+	// defaults to DefaultSnapshotPolicy so a caller that never calls
+	// SetSnapshotPolicy still gets a sane retention window instead of a
+	// zero-value policy that forbids nothing.
+	snapshotPolicy    SnapshotPolicy
+	onSnapshotCreated func(SnapshotRecord)
+
+	// transferPool flushes per-disk block batches concurrently during
+	// transferBlocks (see TransferPool); built lazily so a SyncManager that
+	// never transfers (e.g. only powers a VM on/off) doesn't pay for one.
+	transferPool     *TransferPool
+	transferWorkers  int
+	maxInFlightBytes int64
+}
+
+// defaultTransferWorkers and defaultMaxInFlightBytes apply when a
+// SyncManager's caller never calls SetTransferOptions.
+const (
+	defaultTransferWorkers  = 4
+	defaultMaxInFlightBytes = 256 << 20 // 256 MiB
+
+	// flushBatchSizeBytes is how large a single disk's accumulated batch
+	// grows before transferBlocks hands it to the TransferPool, rather than
+	// flushing on every block.
+	flushBatchSizeBytes int64 = 64 << 20 // 64 MiB
+)
+
+// Progress is a point-in-time sample of an in-flight transferBlocks run,
+// reported to onByteProgress at most once per progressSampleInterval while
+// batches complete, plus once when transferBlocks finishes and once at each
+// phase change PerformSync reports.
+type Progress struct {
+	Phase         string
+	BytesTotal    int64
+	BytesDone     int64
+	ThroughputBPS float64
+	ETASeconds    int64
 }
 
+// progressSampleInterval is how often transferBlocks samples the EWMA rate
+// tracker and reports a Progress, rather than on every block.
+const progressSampleInterval = 2 * time.Second
+
+// ewmaAlpha is the EWMA smoothing factor for the transfer rate. Higher is
+// more responsive to the latest sample, lower rides out brief stalls; 0.5 is
+// the gh-ost default and works well for CBT-sized blocks.
+const ewmaAlpha = 0.5
+
 // SyncResult represents the result of a sync operation
 type SyncResult struct {
 	Success          bool      `json:"success"`
@@ -26,22 +94,98 @@ type SyncResult struct {
 // NewSyncManager creates a new sync manager for a migration job
 func NewSyncManager(jobID int64, sourceType, targetType string, sourceConfig, targetConfig map[string]interface{}) *SyncManager {
 	return &SyncManager{
-		jobID:        jobID,
-		sourceType:   sourceType,
-		targetType:   targetType,
-		sourceConfig: sourceConfig,
-		targetConfig: targetConfig,
+		jobID:          jobID,
+		sourceType:     sourceType,
+		targetType:     targetType,
+		sourceConfig:   sourceConfig,
+		targetConfig:   targetConfig,
+		snapshotPolicy: DefaultSnapshotPolicy,
 	}
 }
 
+// SetSnapshotPolicy overrides the retention policy tagged onto snapshots
+// this SyncManager creates from here on. Callers typically load the job's
+// policy from internal/retention before constructing a SyncManager.
+func (s *SyncManager) SetSnapshotPolicy(policy SnapshotPolicy) {
+	s.snapshotPolicy = policy
+}
+
+// SetSnapshotCreatedFunc registers a callback invoked with a SnapshotRecord
+// each time createSourceSnapshot takes a new snapshot, so callers can
+// persist it (e.g. to internal/retention) for the reaper and for cutover/
+// rollback to locate a job's base snapshot. SyncManager itself has no
+// database access, the same reason progress is reported through
+// SetByteProgressFunc rather than written directly.
+func (s *SyncManager) SetSnapshotCreatedFunc(fn func(SnapshotRecord)) {
+	s.onSnapshotCreated = fn
+}
+
+// SetProgressFunc registers a callback invoked at each step of PerformSync
+// and PerformCutover with a percent-complete estimate and a human-readable
+// log line, so callers can stream progress to subscribers instead of only
+// seeing the final SyncResult.
+func (s *SyncManager) SetProgressFunc(fn func(percent int, message string)) {
+	s.onProgress = fn
+}
+
+// SetEventsPublisher registers a Publisher so instance start/stop and
+// snapshot completion emit CloudEvents as this SyncManager drives them. A
+// nil or unset Publisher is a no-op, so this is optional.
+func (s *SyncManager) SetEventsPublisher(p *events.Publisher) {
+	s.events = p
+}
+
+func (s *SyncManager) reportProgress(percent int, message string) {
+	if s.onProgress != nil {
+		s.onProgress(percent, message)
+	}
+}
+
+// SetByteProgressFunc registers a callback invoked roughly every
+// progressSampleInterval during transferBlocks with a live byte count and
+// EWMA-smoothed throughput/ETA, so callers can persist it (e.g. to
+// sync_progress) for polling clients that need more than the percent/message
+// reportProgress gives them.
+func (s *SyncManager) SetByteProgressFunc(fn func(Progress)) {
+	s.onByteProgress = fn
+}
+
+func (s *SyncManager) reportByteProgress(p Progress) {
+	if s.onByteProgress != nil {
+		s.onByteProgress(p)
+	}
+}
+
+// SetTransferOptions configures transferBlocks' TransferPool concurrency
+// and memory budget. Must be called before the first PerformSync/
+// PerformCutover; it has no effect afterward since the pool is built
+// lazily on first use.
+func (s *SyncManager) SetTransferOptions(workers int, maxInFlightBytes int64) {
+	s.transferWorkers = workers
+	s.maxInFlightBytes = maxInFlightBytes
+}
+
+func (s *SyncManager) pool() *TransferPool {
+	if s.transferPool == nil {
+		s.transferPool = NewTransferPool(s.transferWorkers, s.maxInFlightBytes)
+	}
+	return s.transferPool
+}
+
 // PerformSync executes a sync operation using CBT (Changed Block Tracking)
-func (s *SyncManager) PerformSync(vmName string, preserveMAC, preservePortGroups bool) (*SyncResult, error) {
+func (s *SyncManager) PerformSync(ctx context.Context, vmName string, preserveMAC, preservePortGroups bool) (*SyncResult, error) {
 	startTime := time.Now()
 	result := &SyncResult{
 		Timestamp: startTime,
 	}
 
 	// Step 1: Create a snapshot on the source VM
+	if err := ctx.Err(); err != nil {
+		result.Error = fmt.Sprintf("sync aborted before snapshot: %v", err)
+		return result, err
+	}
+	s.reportProgress(10, "creating source snapshot")
+	s.reportByteProgress(Progress{Phase: "snapshot"})
 	err := s.createSourceSnapshot(vmName)
 	if err != nil {
 		result.Error = fmt.Sprintf("failed to create snapshot: %v", err)
@@ -49,13 +193,24 @@ func (s *SyncManager) PerformSync(vmName string, preserveMAC, preservePortGroups
 	}
 
 	// Step 2: Get changed blocks since last sync (using CBT)
+	if err := ctx.Err(); err != nil {
+		result.Error = fmt.Sprintf("sync aborted before computing changed blocks: %v", err)
+		return result, err
+	}
+	s.reportProgress(35, "computing changed blocks")
 	changedBlocks, err := s.getChangedBlocks(vmName)
 	if err != nil {
 		result.Error = fmt.Sprintf("failed to get changed blocks: %v", err)
 		return result, err
 	}
+	metrics.CBTChangedBlocksTotal.Add(float64(len(changedBlocks)))
 
 	// Step 3: Transfer changed blocks to target
+	if err := ctx.Err(); err != nil {
+		result.Error = fmt.Sprintf("sync aborted before transferring blocks: %v", err)
+		return result, err
+	}
+	s.reportProgress(60, fmt.Sprintf("transferring %d changed blocks", len(changedBlocks)))
 	bytesTransferred, err := s.transferBlocks(vmName, changedBlocks)
 	if err != nil {
 		result.Error = fmt.Sprintf("failed to transfer blocks: %v", err)
@@ -64,6 +219,11 @@ func (s *SyncManager) PerformSync(vmName string, preserveMAC, preservePortGroups
 
 	// Step 4: Update target VM configuration if needed
 	if preserveMAC || preservePortGroups {
+		if err := ctx.Err(); err != nil {
+			result.Error = fmt.Sprintf("sync aborted before updating target config: %v", err)
+			return result, err
+		}
+		s.reportProgress(90, "updating target VM configuration")
 		err = s.updateTargetConfig(vmName, preserveMAC, preservePortGroups)
 		if err != nil {
 			result.Error = fmt.Sprintf("failed to update target config: %v", err)
@@ -74,22 +234,42 @@ func (s *SyncManager) PerformSync(vmName string, preserveMAC, preservePortGroups
 	result.Success = true
 	result.BytesTransferred = bytesTransferred
 	result.Duration = int64(time.Since(startTime).Seconds())
+	s.reportProgress(100, "sync complete")
+	s.reportByteProgress(Progress{Phase: "complete", BytesTotal: bytesTransferred, BytesDone: bytesTransferred})
 
 	return result, nil
 }
 
 // createSourceSnapshot creates a quiesced snapshot for CBT
 func (s *SyncManager) createSourceSnapshot(vmName string) error {
-	// Implementation depends on source type
-	// For VMware, use the VMware client to create a snapshot
-	switch s.sourceType {
-	case "vmware":
-		// Would use vmware.Client to create snapshot
-		// client.CreateSnapshot(vmName, "octopus-sync-"+time.Now().Format("20060102150405"), "", true, true)
-		return nil
-	default:
-		return fmt.Errorf("unsupported source type: %s", s.sourceType)
+	provider, err := s.provider(&s.sourceProvider, s.sourceType, s.sourceConfig)
+	if err != nil {
+		return fmt.Errorf("create source snapshot: %w", err)
+	}
+
+	snapshotName := "octopus-sync-" + time.Now().Format("20060102150405")
+	createdAt := time.Now()
+	_, err = provider.Snapshot(cloud.SnapshotSpec{
+		ResourceID: vmName,
+		Name:       snapshotName,
+		Quiesce:    true,
+	})
+	if err != nil {
+		return err
+	}
+
+	if s.onSnapshotCreated != nil {
+		s.onSnapshotCreated(SnapshotRecord{
+			JobID:      s.jobID,
+			ResourceID: vmName,
+			SnapshotID: snapshotName,
+			CreatedAt:  createdAt,
+			Policy:     s.snapshotPolicy,
+		})
 	}
+
+	s.events.Publish(events.TypeSnapshotCompleted, vmName, map[string]interface{}{"job_id": s.jobID, "snapshot_name": snapshotName})
+	return nil
 }
 
 // getChangedBlocks retrieves changed blocks using CBT
@@ -106,17 +286,110 @@ type BlockChange struct {
 	Length      int64
 }
 
-// transferBlocks transfers changed blocks to the target
+// transferBlocks transfers changed blocks to the target. Blocks are grouped
+// per disk and handed to a TransferPool once a disk's accumulated batch
+// reaches flushBatchSizeBytes, so ordinary flushes run concurrently in the
+// background instead of stalling this loop - but transferBlocks itself is a
+// real barrier: it calls pool.Wait before returning, so every byte is
+// actually written by the time PerformSync's caller (snapshot consolidation,
+// updateTargetConfig, or a cutover's next sync) proceeds. Progress is
+// reported via onByteProgress with an EWMA-smoothed throughput and ETA (see
+// rateTracker) as batches complete, not as blocks are merely enqueued.
 func (s *SyncManager) transferBlocks(vmName string, blocks []BlockChange) (int64, error) {
-	var totalBytes int64
+	var bytesTotal int64
+	for _, block := range blocks {
+		bytesTotal += block.Length
+	}
+
+	pool := s.pool()
+	tracker := newRateTracker(ewmaAlpha, time.Now())
+	lastSample := time.Now()
+
+	var mu sync.Mutex
+	var bytesDone int64
+	var firstErr error
+
+	onDone := func(n int64, err error) {
+		mu.Lock()
+		bytesDone += n
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		done := bytesDone
+		shouldSample := time.Since(lastSample) >= progressSampleInterval
+		if shouldSample {
+			lastSample = time.Now()
+		}
+		mu.Unlock()
+
+		if shouldSample {
+			rate := tracker.sample(done, time.Now())
+			s.reportByteProgress(Progress{
+				Phase:         "transferring",
+				BytesTotal:    bytesTotal,
+				BytesDone:     done,
+				ThroughputBPS: rate,
+				ETASeconds:    eta(bytesTotal-done, rate),
+			})
+		}
+	}
+
+	perDisk := make(map[int32][]BlockChange)
+	flush := func(diskKey int32) {
+		batch := perDisk[diskKey]
+		delete(perDisk, diskKey)
+		pool.Submit(batch, func(b []BlockChange) error {
+			return s.writeBlocks(vmName, diskKey, b)
+		}, onDone)
+	}
 
 	for _, block := range blocks {
-		// Read block from source
-		// Write block to target
-		totalBytes += block.Length
+		perDisk[block.DiskKey] = append(perDisk[block.DiskKey], block)
+		if batchBytes(perDisk[block.DiskKey]) >= flushBatchSizeBytes {
+			flush(block.DiskKey)
+		}
+	}
+	for diskKey, batch := range perDisk {
+		if len(batch) > 0 {
+			flush(diskKey)
+		}
+	}
+
+	// Real barrier: wait for every in-flight flush before reporting this
+	// sync's final byte count.
+	pool.Wait()
+
+	rate := tracker.sample(bytesDone, time.Now())
+	s.reportByteProgress(Progress{
+		Phase:         "transferring",
+		BytesTotal:    bytesTotal,
+		BytesDone:     bytesDone,
+		ThroughputBPS: rate,
+		ETASeconds:    eta(bytesTotal-bytesDone, rate),
+	})
+
+	if firstErr != nil {
+		return bytesDone, firstErr
+	}
+	return bytesDone, nil
+}
+
+// batchBytes sums a disk's accumulated block lengths.
+func batchBytes(blocks []BlockChange) int64 {
+	var total int64
+	for _, b := range blocks {
+		total += b.Length
 	}
+	return total
+}
 
-	return totalBytes, nil
+// writeBlocks flushes one disk's batch of changed blocks to the target.
+// Called from a TransferPool worker, so it must not touch SyncManager state
+// without its own synchronization.
+func (s *SyncManager) writeBlocks(vmName string, diskKey int32, blocks []BlockChange) error {
+	// Read block(s) from source disk diskKey
+	// Write block(s) to target disk diskKey
+	return nil
 }
 
 // updateTargetConfig updates the target VM configuration
@@ -126,63 +399,100 @@ func (s *SyncManager) updateTargetConfig(vmName string, preserveMAC, preservePor
 }
 
 // PerformCutover executes the final cutover
-func (s *SyncManager) PerformCutover(vmName string) error {
+func (s *SyncManager) PerformCutover(ctx context.Context, vmName string) error {
 	// Step 1: Perform final sync
-	_, err := s.PerformSync(vmName, true, true)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("cutover aborted before final sync: %w", err)
+	}
+	_, err := s.PerformSync(ctx, vmName, true, true)
 	if err != nil {
 		return fmt.Errorf("final sync failed: %w", err)
 	}
 
 	// Step 2: Power off source VM
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("cutover aborted before powering off source: %w", err)
+	}
+	s.reportProgress(40, "powering off source VM")
 	err = s.powerOffSource(vmName)
 	if err != nil {
 		return fmt.Errorf("failed to power off source: %w", err)
 	}
 
 	// Step 3: Do one more sync to capture any final changes
-	_, err = s.PerformSync(vmName, true, true)
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("cutover aborted before post-poweroff sync: %w", err)
+	}
+	_, err = s.PerformSync(ctx, vmName, true, true)
 	if err != nil {
 		return fmt.Errorf("post-poweroff sync failed: %w", err)
 	}
 
 	// Step 4: Power on target VM
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("cutover aborted before powering on target: %w", err)
+	}
+	s.reportProgress(95, "powering on target VM")
 	err = s.powerOnTarget(vmName)
 	if err != nil {
 		return fmt.Errorf("failed to power on target: %w", err)
 	}
 
+	s.reportProgress(100, "cutover complete")
 	return nil
 }
 
 // powerOffSource powers off the source VM
 func (s *SyncManager) powerOffSource(vmName string) error {
-	switch s.sourceType {
-	case "vmware":
-		// Would use vmware.Client to power off
-		return nil
-	default:
-		return fmt.Errorf("unsupported source type: %s", s.sourceType)
+	provider, err := s.provider(&s.sourceProvider, s.sourceType, s.sourceConfig)
+	if err != nil {
+		return fmt.Errorf("power off source: %w", err)
+	}
+	if err := provider.Stop(vmName); err != nil {
+		return err
 	}
+	s.events.Publish(events.TypeInstanceStopped, vmName, map[string]interface{}{"job_id": s.jobID})
+	return nil
 }
 
 // powerOnTarget powers on the target VM
 func (s *SyncManager) powerOnTarget(vmName string) error {
-	switch s.targetType {
-	case "vmware":
-		// Would use vmware.Client to power on
-		return nil
-	case "aws":
-		// Would use aws.Client to start instance
-		return nil
-	case "gcp":
-		// Would use gcp.Client to start instance
-		return nil
-	case "azure":
-		// Would use azure.Client to start VM
-		return nil
-	default:
-		return fmt.Errorf("unsupported target type: %s", s.targetType)
+	provider, err := s.provider(&s.targetProvider, s.targetType, s.targetConfig)
+	if err != nil {
+		return fmt.Errorf("power on target: %w", err)
+	}
+	if err := provider.Start(vmName); err != nil {
+		return err
 	}
+	s.events.Publish(events.TypeInstanceStarted, vmName, map[string]interface{}{"job_id": s.jobID})
+	return nil
+}
+
+// provider resolves the cloud.Provider registered under driverType, caching
+// it in cached so a SyncManager only connects once per source/target over
+// its lifetime even though createSourceSnapshot, powerOffSource, and
+// PerformSync's retries may all need it.
+func (s *SyncManager) provider(cached *cloud.Provider, driverType string, config map[string]interface{}) (cloud.Provider, error) {
+	if *cached != nil {
+		return *cached, nil
+	}
+
+	var options json.RawMessage
+	if config != nil {
+		encoded, err := json.Marshal(config)
+		if err != nil {
+			return nil, fmt.Errorf("encode %s driver options: %w", driverType, err)
+		}
+		options = encoded
+	}
+
+	p, err := cloud.New(driverType, options)
+	if err != nil {
+		return nil, err
+	}
+
+	*cached = p
+	return p, nil
 }
 
 // RAID overhead multipliers (physical space per unit of logical data)
@@ -215,22 +525,22 @@ var OrganicFactors = struct {
 
 // VXRailConfig holds VXRail-specific estimation parameters
 type VXRailConfig struct {
-	RAIDPolicy       string  `json:"raid_policy"`        // raid1_ftt1, raid5_ftt1, etc.
-	DedupEnabled     bool    `json:"dedup_enabled"`      // Is deduplication enabled
-	CompressionEnabled bool  `json:"compression_enabled"` // Is compression enabled
-	DedupRatio       float64 `json:"dedup_ratio"`        // Actual dedup ratio (e.g., 1.5 for 1.5:1)
-	CompressionRatio float64 `json:"compression_ratio"`  // Actual compression ratio
-	HasSnapshots     bool    `json:"has_snapshots"`      // Does VM have snapshots
+	RAIDPolicy         string  `json:"raid_policy"`         // raid1_ftt1, raid5_ftt1, etc.
+	DedupEnabled       bool    `json:"dedup_enabled"`       // Is deduplication enabled
+	CompressionEnabled bool    `json:"compression_enabled"` // Is compression enabled
+	DedupRatio         float64 `json:"dedup_ratio"`         // Actual dedup ratio (e.g., 1.5 for 1.5:1)
+	CompressionRatio   float64 `json:"compression_ratio"`   // Actual compression ratio
+	HasSnapshots       bool    `json:"has_snapshots"`       // Does VM have snapshots
 }
 
 // SizeEstimation represents a size estimate for a target
 type SizeEstimation struct {
-	SourceSizeGB     float64 `json:"source_size_gb"`      // What vCenter reports (includes RAID overhead)
-	LogicalSizeGB    float64 `json:"logical_size_gb"`     // Primary data only (RAID overhead removed)
-	EstimatedSizeGB  float64 `json:"estimated_size_gb"`   // Final migration estimate
-	SizeDifferenceGB float64 `json:"size_difference_gb"`  // Difference from source
-	ChangePercent    float64 `json:"change_percent"`      // Percentage change
-	Notes            string  `json:"notes"`               // Explanation of factors applied
+	SourceSizeGB     float64 `json:"source_size_gb"`     // What vCenter reports (includes RAID overhead)
+	LogicalSizeGB    float64 `json:"logical_size_gb"`    // Primary data only (RAID overhead removed)
+	EstimatedSizeGB  float64 `json:"estimated_size_gb"`  // Final migration estimate
+	SizeDifferenceGB float64 `json:"size_difference_gb"` // Difference from source
+	ChangePercent    float64 `json:"change_percent"`     // Percentage change
+	Notes            string  `json:"notes"`              // Explanation of factors applied
 }
 
 // EstimateSize estimates the size of a VM on a target platform
@@ -346,36 +656,114 @@ func EstimateSizeWithConfig(diskSizeGB, memoryGB float64, cpuCount int, targetTy
 	return estimation
 }
 
-// EstimateCost estimates the monthly cost for running a VM on a target platform
-func EstimateCost(cpuCount int, memoryGB, diskSizeGB float64, targetType, region string) map[string]float64 {
-	costs := make(map[string]float64)
+// CostSpec describes the VM shape and target EstimateCost prices.
+type CostSpec struct {
+	CPUCount   int
+	MemoryGB   float64
+	DiskSizeGB float64
+	TargetType string // "aws", "gcp", or "azure"
+	Region     string
+}
 
-	switch targetType {
-	case "aws":
-		// Rough AWS pricing (varies by region and instance type)
-		// Using m5.xlarge as baseline ($0.192/hour in us-east-1)
-		hourlyRate := 0.048 * float64(cpuCount) // Approximate
-		costs["compute_monthly"] = hourlyRate * 24 * 30
-		costs["storage_monthly"] = diskSizeGB * 0.10 // GP3 pricing
-		costs["total_monthly"] = costs["compute_monthly"] + costs["storage_monthly"]
+// CostEstimate is EstimateCost's result: the instance type the spec matched
+// to, when the price backing this estimate was last fetched, and the
+// monthly cost broken down by category.
+type CostEstimate struct {
+	MatchedInstanceType  string    `json:"matched_instance_type"`
+	PriceSourceTimestamp time.Time `json:"price_source_timestamp"`
+	// FromFallback is true when the live pricing API was unreachable and
+	// this estimate used the hardcoded per-vCPU/per-GB rates instead.
+	FromFallback bool `json:"from_fallback"`
+
+	ComputeMonthly float64 `json:"compute_monthly"`
+	StorageMonthly float64 `json:"storage_monthly"`
+	NetworkMonthly float64 `json:"network_monthly"`
+	EgressMonthly  float64 `json:"egress_monthly"`
+	TotalMonthly   float64 `json:"total_monthly"`
+}
 
-	case "gcp":
-		// Rough GCP pricing
-		hourlyRate := 0.0475 * float64(cpuCount) // n2-standard pricing
-		costs["compute_monthly"] = hourlyRate * 24 * 30
-		costs["storage_monthly"] = diskSizeGB * 0.17 // PD-SSD pricing
-		costs["total_monthly"] = costs["compute_monthly"] + costs["storage_monthly"]
+// defaultDiskType is the disk/volume type EstimateCost prices per target,
+// matching the storage tier EstimateSize already assumes VMs land on.
+var defaultDiskType = map[string]string{
+	"aws":   "gp3",
+	"gcp":   "pd-ssd",
+	"azure": "Premium_LRS",
+}
 
-	case "azure":
-		// Rough Azure pricing
-		hourlyRate := 0.05 * float64(cpuCount) // D-series pricing
-		costs["compute_monthly"] = hourlyRate * 24 * 30
-		costs["storage_monthly"] = diskSizeGB * 0.15 // Premium SSD pricing
-		costs["total_monthly"] = costs["compute_monthly"] + costs["storage_monthly"]
+// fallbackHourlyRate and fallbackStoragePerGB are the old hardcoded
+// per-vCPU/per-GB rates EstimateCost used before it could query a live
+// pricing API. They're kept as the fallback path for when that API (or its
+// cache) is unreachable.
+var fallbackHourlyRate = map[string]float64{
+	"aws":   0.048,  // m5.xlarge family, us-east-1
+	"gcp":   0.0475, // n2-standard family
+	"azure": 0.05,   // D-series
+}
 
-	default:
-		costs["total_monthly"] = 0
+var fallbackStoragePerGB = map[string]float64{
+	"aws":   0.10, // gp3
+	"gcp":   0.17, // pd-ssd
+	"azure": 0.15, // Premium SSD
+}
+
+// EstimateCost estimates the monthly cost of running spec on spec.TargetType,
+// querying source for live on-demand prices and falling back to a hardcoded
+// per-vCPU/per-GB table if source is nil or its API is unreachable.
+func EstimateCost(ctx context.Context, spec CostSpec, source pricing.PriceSource) (CostEstimate, error) {
+	instanceType := matchedInstanceType(spec)
+
+	var price *pricing.Price
+	if source != nil {
+		p, err := source.FetchPrice(ctx, pricing.Query{
+			Provider:       spec.TargetType,
+			Region:         spec.Region,
+			InstanceFamily: instanceType,
+			DiskType:       defaultDiskType[spec.TargetType],
+		})
+		if err == nil {
+			price = p
+		}
+	}
+
+	fromFallback := price == nil
+	if fromFallback {
+		rate, ok := fallbackHourlyRate[spec.TargetType]
+		if !ok {
+			return CostEstimate{}, fmt.Errorf("sync: unknown target type %q", spec.TargetType)
+		}
+		price = &pricing.Price{
+			ComputeHourly:       rate * float64(spec.CPUCount),
+			StorageMonthlyPerGB: fallbackStoragePerGB[spec.TargetType],
+			Timestamp:           time.Now(),
+		}
+	}
+
+	estimate := CostEstimate{
+		MatchedInstanceType:  instanceType,
+		PriceSourceTimestamp: price.Timestamp,
+		FromFallback:         fromFallback,
+		ComputeMonthly:       price.ComputeHourly * 24 * 30,
+		StorageMonthly:       price.StorageMonthlyPerGB * spec.DiskSizeGB,
+		NetworkMonthly:       0, // no network throughput estimate available from spec alone
+		EgressMonthly:        0, // ditto - egress depends on actual transfer volume, not VM shape
 	}
+	estimate.TotalMonthly = estimate.ComputeMonthly + estimate.StorageMonthly + estimate.NetworkMonthly + estimate.EgressMonthly
 
-	return costs
+	return estimate, nil
+}
+
+// matchedInstanceType reuses each provider's EstimateInstanceType/
+// EstimateVMSize/EstimateMachineType helper so the price lookup targets the
+// same instance type a real cutover would provision.
+func matchedInstanceType(spec CostSpec) string {
+	switch spec.TargetType {
+	case "aws":
+		return aws.EstimateInstanceType(spec.CPUCount, spec.MemoryGB)
+	case "gcp":
+		return gcp.EstimateMachineType(spec.CPUCount, spec.MemoryGB)
+	case "azure":
+		return azure.EstimateVMSize(spec.CPUCount, spec.MemoryGB)
+	default:
+		return ""
+	}
 }
@@ -0,0 +1,103 @@
+package sync
+
+import "sync"
+
+// memBudget is a counting semaphore sized in bytes rather than tokens, so a
+// handful of large block batches and a flood of small ones are bounded by
+// the same RAM budget. A single oversized acquire is still allowed through
+// once nothing else is in flight, so one batch larger than the whole budget
+// can't deadlock the pool.
+type memBudget struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	max      int64
+	inFlight int64
+}
+
+func newMemBudget(max int64) *memBudget {
+	b := &memBudget{max: max}
+	b.cond = sync.NewCond(&b.mu)
+	return b
+}
+
+func (b *memBudget) acquire(n int64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for b.inFlight > 0 && b.inFlight+n > b.max {
+		b.cond.Wait()
+	}
+	b.inFlight += n
+}
+
+func (b *memBudget) release(n int64) {
+	b.mu.Lock()
+	b.inFlight -= n
+	b.mu.Unlock()
+	b.cond.Broadcast()
+}
+
+// TransferPool runs per-disk block-batch flushes concurrently, bounded by a
+// worker count and a shared memory budget: Submit reserves the batch's bytes
+// against MaxInFlightBytes before handing it to a worker, and releases them
+// once the flush completes. This is the CockroachDB-style async-flush shape
+// - ordinary batches are hence not transferred inline on the caller's
+// goroutine - but real barriers (final cutover sync, snapshot consolidation,
+// updateTargetConfig) still need every byte actually written before
+// proceeding, which is what Wait is for.
+type TransferPool struct {
+	budget *memBudget
+	sem    chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewTransferPool creates a pool with the given worker concurrency and
+// memory budget. Non-positive values fall back to sane defaults rather than
+// creating a pool that can never run anything.
+func NewTransferPool(workers int, maxInFlightBytes int64) *TransferPool {
+	if workers < 1 {
+		workers = defaultTransferWorkers
+	}
+	if maxInFlightBytes <= 0 {
+		maxInFlightBytes = defaultMaxInFlightBytes
+	}
+	return &TransferPool{
+		budget: newMemBudget(maxInFlightBytes),
+		sem:    make(chan struct{}, workers),
+	}
+}
+
+// Submit reserves batch's total bytes against the pool's memory budget,
+// blocking the caller until they're available, then flushes batch on a
+// pool worker goroutine. onDone (if non-nil) is invoked with the batch's
+// byte count and flush's error after the worker completes, for progress
+// reporting. Submit itself does not block on the flush - only on the
+// memory reservation and an open worker slot - so the caller's read/enqueue
+// loop isn't stalled by a slow flush.
+func (p *TransferPool) Submit(batch []BlockChange, flush func([]BlockChange) error, onDone func(bytes int64, err error)) {
+	var bytes int64
+	for _, b := range batch {
+		bytes += b.Length
+	}
+
+	p.budget.acquire(bytes)
+	p.sem <- struct{}{}
+	p.wg.Add(1)
+
+	go func() {
+		defer p.wg.Done()
+		defer func() { <-p.sem }()
+		defer p.budget.release(bytes)
+
+		err := flush(batch)
+		if onDone != nil {
+			onDone(bytes, err)
+		}
+	}()
+}
+
+// Wait blocks until every batch Submit'd so far has finished flushing - the
+// barrier a real cutover/consolidation/config step needs before it can rely
+// on the transfer being complete.
+func (p *TransferPool) Wait() {
+	p.wg.Wait()
+}
@@ -0,0 +1,75 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sp00nznet/octopus/internal/providers/vmware"
+)
+
+// esxiDriver discovers VMs from a standalone ESXi host managed without
+// vCenter. govmomi talks to a bare host the same way it talks to vCenter,
+// so this reuses vmware.Client but defaults to ESXi's implicit
+// "ha-datacenter" instead of requiring one in config.
+type esxiDriver struct{}
+
+func newESXiDriver() *esxiDriver { return &esxiDriver{} }
+
+func (d *esxiDriver) Type() string { return "esxi" }
+
+func (d *esxiDriver) Capabilities() []string {
+	return []string{"power_state", "ip_addresses", "mac_addresses", "hardware_version", "vmware_tools_status"}
+}
+
+func (d *esxiDriver) Schema() []FieldSpec {
+	return []FieldSpec{
+		{Name: "host", Type: "string", Required: true},
+		{Name: "username", Type: "string", Required: true},
+		{Name: "password", Type: "string", Required: true, Secret: true},
+		{Name: "insecure", Type: "bool", Required: false},
+	}
+}
+
+func (d *esxiDriver) Connect(ctx context.Context, config map[string]interface{}) (Session, error) {
+	host, _ := config["host"].(string)
+	username, _ := config["username"].(string)
+	password, _ := config["password"].(string)
+	insecure, _ := config["insecure"].(bool)
+
+	client, err := vmware.NewClient(host, username, password, "ha-datacenter", insecure)
+	if err != nil {
+		return nil, fmt.Errorf("connect to esxi host: %w", err)
+	}
+	return &vmwareSession{client: client}, nil
+}
+
+func (d *esxiDriver) ListVMs(ctx context.Context, session Session) ([]VM, error) {
+	s, ok := session.(*vmwareSession)
+	if !ok {
+		return nil, fmt.Errorf("esxi: wrong session type")
+	}
+
+	infos, err := s.client.ListVMs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vms := make([]VM, len(infos))
+	for i, info := range infos {
+		vms[i] = VM{
+			Name:              info.Name,
+			UUID:              info.UUID,
+			CPUCount:          info.CPUCount,
+			MemoryMB:          info.MemoryMB,
+			DiskSizeGB:        info.DiskSizeGB,
+			GuestOS:           info.GuestOS,
+			PowerState:        info.PowerState,
+			IPAddresses:       info.IPAddresses,
+			MACAddresses:      info.MACAddresses,
+			PortGroups:        info.PortGroups,
+			HardwareVersion:   info.HardwareVersion,
+			VMwareToolsStatus: info.VMwareToolsStatus,
+		}
+	}
+	return vms, nil
+}
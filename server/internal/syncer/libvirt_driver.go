@@ -0,0 +1,157 @@
+package syncer
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// libvirtDriver discovers VMs from a KVM/libvirt host by shelling out to
+// virsh, which already knows how to reach local, TCP, and SSH-tunneled
+// libvirt connection URIs (qemu:///system, qemu+ssh://user@host/system,
+// ...) without octopus needing its own libvirt client bindings.
+type libvirtDriver struct{}
+
+func newLibvirtDriver() *libvirtDriver { return &libvirtDriver{} }
+
+func (d *libvirtDriver) Type() string { return "libvirt" }
+
+func (d *libvirtDriver) Capabilities() []string {
+	return []string{"power_state", "mac_addresses"}
+}
+
+func (d *libvirtDriver) Schema() []FieldSpec {
+	return []FieldSpec{
+		{Name: "uri", Type: "string", Required: true},
+	}
+}
+
+type libvirtSession struct {
+	uri string
+}
+
+func (s *libvirtSession) Close() error { return nil }
+
+func (d *libvirtDriver) Connect(ctx context.Context, config map[string]interface{}) (Session, error) {
+	uri, _ := config["uri"].(string)
+	if uri == "" {
+		return nil, fmt.Errorf("libvirt: missing connection uri")
+	}
+	if _, err := d.virsh(ctx, uri, "uri"); err != nil {
+		return nil, fmt.Errorf("libvirt: connect to %s: %w", uri, err)
+	}
+	return &libvirtSession{uri: uri}, nil
+}
+
+func (d *libvirtDriver) virsh(ctx context.Context, uri string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, "virsh", append([]string{"-c", uri}, args...)...)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("virsh %s: %w: %s", strings.Join(args, " "), err, out.String())
+	}
+	return out.String(), nil
+}
+
+func (d *libvirtDriver) ListVMs(ctx context.Context, session Session) ([]VM, error) {
+	s, ok := session.(*libvirtSession)
+	if !ok {
+		return nil, fmt.Errorf("libvirt: wrong session type")
+	}
+
+	out, err := d.virsh(ctx, s.uri, "list", "--all", "--name")
+	if err != nil {
+		return nil, err
+	}
+
+	var vms []VM
+	for _, name := range strings.Fields(out) {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		vm, err := d.domainInfo(ctx, s.uri, name)
+		if err != nil {
+			continue // skip domains we can't introspect
+		}
+		vms = append(vms, vm)
+	}
+	return vms, nil
+}
+
+// domainXML is the small slice of `virsh dumpxml` we care about.
+type domainXML struct {
+	UUID   string `xml:"uuid"`
+	Memory struct {
+		Unit  string `xml:"unit,attr"`
+		Value int    `xml:",chardata"`
+	} `xml:"memory"`
+	VCPU struct {
+		Value int `xml:",chardata"`
+	} `xml:"vcpu"`
+	OSType struct {
+		Value string `xml:",chardata"`
+	} `xml:"os>type"`
+	Devices struct {
+		Interfaces []struct {
+			MAC struct {
+				Address string `xml:"address,attr"`
+			} `xml:"mac"`
+		} `xml:"interface"`
+	} `xml:"devices"`
+}
+
+func (d *libvirtDriver) domainInfo(ctx context.Context, uri, name string) (VM, error) {
+	state, err := d.virsh(ctx, uri, "domstate", name)
+	if err != nil {
+		return VM{}, err
+	}
+
+	xmlOut, err := d.virsh(ctx, uri, "dumpxml", name)
+	if err != nil {
+		return VM{}, err
+	}
+
+	var dom domainXML
+	if err := xml.Unmarshal([]byte(xmlOut), &dom); err != nil {
+		return VM{}, fmt.Errorf("parse domain xml for %s: %w", name, err)
+	}
+
+	memoryMB := dom.Memory.Value
+	if strings.EqualFold(dom.Memory.Unit, "KiB") || dom.Memory.Unit == "" {
+		memoryMB = dom.Memory.Value / 1024
+	}
+
+	macs := make([]string, 0, len(dom.Devices.Interfaces))
+	for _, iface := range dom.Devices.Interfaces {
+		if iface.MAC.Address != "" {
+			macs = append(macs, iface.MAC.Address)
+		}
+	}
+
+	return VM{
+		Name:         name,
+		UUID:         dom.UUID,
+		CPUCount:     dom.VCPU.Value,
+		MemoryMB:     memoryMB,
+		GuestOS:      dom.OSType.Value,
+		PowerState:   libvirtPowerState(state),
+		MACAddresses: strings.Join(macs, ","),
+	}, nil
+}
+
+func libvirtPowerState(domstate string) string {
+	switch strings.ToLower(strings.TrimSpace(domstate)) {
+	case "running":
+		return "poweredOn"
+	case "shut off", "shutoff":
+		return "poweredOff"
+	case "paused":
+		return "suspended"
+	default:
+		return strings.TrimSpace(domstate)
+	}
+}
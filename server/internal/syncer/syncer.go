@@ -0,0 +1,150 @@
+// Package syncer defines the pluggable driver interface behind the unified
+// environments API, so octopus can discover VM inventory from more than
+// just vCenter. Each environment type (vmware, vmware-vxrail, esxi,
+// libvirt, ...) registers a Driver; CRUD handlers validate an
+// environment's config against its driver's Schema before it's ever
+// persisted, and sync jobs look the driver up by type instead of hardcoding
+// vmware.NewClient.
+package syncer
+
+import (
+	"context"
+	"fmt"
+)
+
+// VM is a provider-agnostic view of a discovered virtual machine. Fields a
+// driver can't populate (see Driver.Capabilities) are left zero-valued.
+type VM struct {
+	Name              string
+	UUID              string
+	CPUCount          int
+	MemoryMB          int
+	DiskSizeGB        float64
+	GuestOS           string
+	PowerState        string
+	IPAddresses       string
+	MACAddresses      string
+	PortGroups        string
+	HardwareVersion   string
+	VMwareToolsStatus string
+}
+
+// Session is an open connection to a provider, released once a sync pass
+// finishes.
+type Session interface {
+	Close() error
+}
+
+// FieldSpec describes one key a driver expects in its environment's
+// config_json, used to validate a config before it's persisted.
+type FieldSpec struct {
+	Name     string
+	Type     string // "string" or "bool"
+	Required bool
+	// Secret marks a field (e.g. "password") whose value should be
+	// encrypted at rest and redacted from API responses.
+	Secret bool
+}
+
+// Driver discovers VM inventory from one environment type.
+type Driver interface {
+	// Type is the environment.type value this driver handles.
+	Type() string
+	// Capabilities lists the VM fields this driver can actually populate
+	// (e.g. "power_state", "port_groups", "vmware_tools_status"), so
+	// callers know what to expect from a discovered VM.
+	Capabilities() []string
+	// Schema describes the keys expected in config_json for this type.
+	Schema() []FieldSpec
+	// Connect opens a session against config, already validated against
+	// Schema.
+	Connect(ctx context.Context, config map[string]interface{}) (Session, error)
+	// ListVMs returns every VM visible through session.
+	ListVMs(ctx context.Context, session Session) ([]VM, error)
+}
+
+// Registry looks drivers up by environment type.
+type Registry struct {
+	drivers map[string]Driver
+}
+
+// NewRegistry creates a Registry pre-populated with octopus's built-in
+// drivers.
+func NewRegistry() *Registry {
+	r := &Registry{drivers: make(map[string]Driver)}
+	r.Register(newVMwareDriver("vmware", true))
+	r.Register(newVMwareDriver("vmware-vxrail", true))
+	r.Register(newESXiDriver())
+	r.Register(newLibvirtDriver())
+	return r
+}
+
+// Register adds or replaces the driver handling d.Type().
+func (r *Registry) Register(d Driver) {
+	r.drivers[d.Type()] = d
+}
+
+// Get returns the driver registered for envType.
+func (r *Registry) Get(envType string) (Driver, bool) {
+	d, ok := r.drivers[envType]
+	return d, ok
+}
+
+// Types lists every registered environment type.
+func (r *Registry) Types() []string {
+	types := make([]string, 0, len(r.drivers))
+	for t := range r.drivers {
+		types = append(types, t)
+	}
+	return types
+}
+
+// SecretFields lists the config_json keys envType's driver marks as
+// secret, e.g. "password". Returns nil if envType isn't registered.
+func (r *Registry) SecretFields(envType string) []string {
+	d, ok := r.Get(envType)
+	if !ok {
+		return nil
+	}
+
+	var fields []string
+	for _, field := range d.Schema() {
+		if field.Secret {
+			fields = append(fields, field.Name)
+		}
+	}
+	return fields
+}
+
+// Validate checks that envType is registered and config satisfies its
+// driver's Schema, returning a descriptive error identifying the first
+// problem found rather than a generic rejection.
+func (r *Registry) Validate(envType string, config map[string]interface{}) error {
+	d, ok := r.Get(envType)
+	if !ok {
+		return fmt.Errorf("unsupported environment type %q", envType)
+	}
+
+	for _, field := range d.Schema() {
+		v, present := config[field.Name]
+		if !present || v == nil {
+			if field.Required {
+				return fmt.Errorf("%s: missing required field %q", envType, field.Name)
+			}
+			continue
+		}
+
+		switch field.Type {
+		case "string":
+			if _, ok := v.(string); !ok {
+				return fmt.Errorf("%s: field %q must be a string", envType, field.Name)
+			}
+		case "bool":
+			if _, ok := v.(bool); !ok {
+				return fmt.Errorf("%s: field %q must be a bool", envType, field.Name)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,89 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/sp00nznet/octopus/internal/providers/vmware"
+)
+
+// vmwareDriver discovers VMs through vCenter. It also backs
+// "vmware-vxrail" - Dell VxRail clusters are vSphere under the hood and
+// managed through the same vCenter API, so no separate client is needed.
+type vmwareDriver struct {
+	envType           string
+	requireDatacenter bool
+}
+
+func newVMwareDriver(envType string, requireDatacenter bool) *vmwareDriver {
+	return &vmwareDriver{envType: envType, requireDatacenter: requireDatacenter}
+}
+
+func (d *vmwareDriver) Type() string { return d.envType }
+
+func (d *vmwareDriver) Capabilities() []string {
+	return []string{"power_state", "ip_addresses", "mac_addresses", "port_groups", "hardware_version", "vmware_tools_status"}
+}
+
+func (d *vmwareDriver) Schema() []FieldSpec {
+	return []FieldSpec{
+		{Name: "host", Type: "string", Required: true},
+		{Name: "username", Type: "string", Required: true},
+		{Name: "password", Type: "string", Required: true, Secret: true},
+		{Name: "datacenter", Type: "string", Required: d.requireDatacenter},
+		{Name: "insecure", Type: "bool", Required: false},
+	}
+}
+
+type vmwareSession struct {
+	client *vmware.Client
+}
+
+func (s *vmwareSession) Close() error {
+	return s.client.Logout(context.Background())
+}
+
+func (d *vmwareDriver) Connect(ctx context.Context, config map[string]interface{}) (Session, error) {
+	host, _ := config["host"].(string)
+	username, _ := config["username"].(string)
+	password, _ := config["password"].(string)
+	datacenter, _ := config["datacenter"].(string)
+	insecure, _ := config["insecure"].(bool)
+
+	client, err := vmware.NewClient(host, username, password, datacenter, insecure)
+	if err != nil {
+		return nil, fmt.Errorf("connect to %s: %w", d.envType, err)
+	}
+	return &vmwareSession{client: client}, nil
+}
+
+func (d *vmwareDriver) ListVMs(ctx context.Context, session Session) ([]VM, error) {
+	s, ok := session.(*vmwareSession)
+	if !ok {
+		return nil, fmt.Errorf("%s: wrong session type", d.envType)
+	}
+
+	infos, err := s.client.ListVMs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	vms := make([]VM, len(infos))
+	for i, info := range infos {
+		vms[i] = VM{
+			Name:              info.Name,
+			UUID:              info.UUID,
+			CPUCount:          info.CPUCount,
+			MemoryMB:          info.MemoryMB,
+			DiskSizeGB:        info.DiskSizeGB,
+			GuestOS:           info.GuestOS,
+			PowerState:        info.PowerState,
+			IPAddresses:       info.IPAddresses,
+			MACAddresses:      info.MACAddresses,
+			PortGroups:        info.PortGroups,
+			HardwareVersion:   info.HardwareVersion,
+			VMwareToolsStatus: info.VMwareToolsStatus,
+		}
+	}
+	return vms, nil
+}
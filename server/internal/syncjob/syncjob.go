@@ -0,0 +1,322 @@
+// Package syncjob tracks asynchronous vCenter inventory pulls for the
+// unified environments API. A sync against a large vCenter inventory can
+// take too long to hold an HTTP request open, so Manager hands back a
+// SyncJob immediately and runs the pull in the background, persisting its
+// progress to the sync_jobs table as it goes.
+package syncjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/sp00nznet/octopus/internal/db"
+)
+
+// State is the lifecycle state of a SyncJob.
+type State string
+
+const (
+	StatePending   State = "pending"
+	StateRunning   State = "running"
+	StateSucceeded State = "succeeded"
+	StateFailed    State = "failed"
+	StateCancelled State = "cancelled"
+)
+
+func (s State) terminal() bool {
+	return s == StateSucceeded || s == StateFailed || s == StateCancelled
+}
+
+// ErrSyncInProgress is returned by Create when the environment already has
+// a non-terminal sync job.
+var ErrSyncInProgress = errors.New("syncjob: a sync is already in progress for this environment")
+
+// Snapshot is the JSON representation of a SyncJob returned by the API.
+type Snapshot struct {
+	ID          int64      `json:"id"`
+	EnvID       int64      `json:"env_id"`
+	State       State      `json:"state"`
+	StartedAt   time.Time  `json:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty"`
+	VMTotal     int        `json:"vm_total"`
+	VMProcessed int        `json:"vm_processed"`
+	VMCreated   int        `json:"vm_created"`
+	VMUpdated   int        `json:"vm_updated"`
+	VMDeleted   int        `json:"vm_deleted"`
+	Error       string     `json:"error,omitempty"`
+}
+
+// SyncJob tracks a single vCenter inventory pull against one environment.
+type SyncJob struct {
+	id    int64
+	envID int64
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu          sync.Mutex
+	state       State
+	startedAt   time.Time
+	finishedAt  *time.Time
+	vmTotal     int
+	vmProcessed int
+	vmCreated   int
+	vmUpdated   int
+	vmDeleted   int
+	errMsg      string
+	subscribers map[chan Snapshot]struct{}
+
+	manager *Manager
+}
+
+// Manager tracks all SyncJobs created by this process, persists them to the
+// sync_jobs table, and enforces one in-flight sync per environment.
+type Manager struct {
+	db *db.Database
+
+	mu    sync.Mutex
+	jobs  map[int64]*SyncJob
+	byEnv map[int64]*SyncJob
+}
+
+// NewManager creates a sync job Manager backed by database.
+func NewManager(database *db.Database) *Manager {
+	return &Manager{
+		db:    database,
+		jobs:  make(map[int64]*SyncJob),
+		byEnv: make(map[int64]*SyncJob),
+	}
+}
+
+// Create registers a new pending SyncJob for envID and persists its initial
+// row, refusing if that environment already has a sync underway.
+func (m *Manager) Create(envID int64) (*SyncJob, error) {
+	m.mu.Lock()
+	if existing, ok := m.byEnv[envID]; ok && !existing.Snapshot().State.terminal() {
+		m.mu.Unlock()
+		return nil, ErrSyncInProgress
+	}
+	m.mu.Unlock()
+
+	now := time.Now()
+	res, err := m.db.Exec(`
+		INSERT INTO sync_jobs (env_id, state, started_at, vm_total, vm_processed)
+		VALUES (?, ?, ?, 0, 0)
+	`, envID, StatePending, now)
+	if err != nil {
+		return nil, fmt.Errorf("create sync job for env %d: %w", envID, err)
+	}
+	id, err := res.LastInsertId()
+	if err != nil {
+		return nil, fmt.Errorf("create sync job for env %d: %w", envID, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &SyncJob{
+		id:          id,
+		envID:       envID,
+		ctx:         ctx,
+		cancel:      cancel,
+		state:       StatePending,
+		startedAt:   now,
+		subscribers: make(map[chan Snapshot]struct{}),
+		manager:     m,
+	}
+
+	m.mu.Lock()
+	m.jobs[id] = job
+	m.byEnv[envID] = job
+	m.mu.Unlock()
+
+	return job, nil
+}
+
+// Get returns a tracked SyncJob by ID.
+func (m *Manager) Get(id int64) (*SyncJob, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	job, ok := m.jobs[id]
+	return job, ok
+}
+
+// Run marks the job running and executes fn in a goroutine under its
+// cancellable context, moving the job to succeeded/failed/cancelled once fn
+// returns.
+func (j *SyncJob) Run(fn func(ctx context.Context) error) {
+	j.setState(StateRunning, "")
+
+	go func() {
+		err := fn(j.ctx)
+		switch {
+		case j.ctx.Err() == context.Canceled:
+			j.setState(StateCancelled, "")
+		case err != nil:
+			j.setState(StateFailed, err.Error())
+		default:
+			j.setState(StateSucceeded, "")
+		}
+	}()
+}
+
+// Cancel requests cancellation via the job's context, passed into
+// vmware.Client.ListVMs so an in-progress inventory pull can abort between
+// VMs rather than running to completion.
+func (j *SyncJob) Cancel() {
+	j.cancel()
+}
+
+// SetTotal records the number of VMs this job expects to process.
+func (j *SyncJob) SetTotal(total int) {
+	j.mu.Lock()
+	j.vmTotal = total
+	j.mu.Unlock()
+
+	j.broadcast()
+	j.persist()
+}
+
+// Increment advances the processed-VM count by one, broadcasting and
+// persisting the new progress so GET /sync_jobs/{id} and its event stream
+// reflect it immediately.
+func (j *SyncJob) Increment() {
+	j.mu.Lock()
+	j.vmProcessed++
+	j.mu.Unlock()
+
+	j.broadcast()
+	j.persist()
+}
+
+// RecordCreated counts one VM that didn't previously exist and was inserted
+// by this sync pass.
+func (j *SyncJob) RecordCreated() {
+	j.mu.Lock()
+	j.vmCreated++
+	j.mu.Unlock()
+
+	j.broadcast()
+	j.persist()
+}
+
+// RecordUpdated counts one VM that already existed and was refreshed by
+// this sync pass.
+func (j *SyncJob) RecordUpdated() {
+	j.mu.Lock()
+	j.vmUpdated++
+	j.mu.Unlock()
+
+	j.broadcast()
+	j.persist()
+}
+
+// SetDeleted records how many VMs the reconciliation pass soft-deleted -
+// rows that exist in the vms table but weren't seen in this sync.
+func (j *SyncJob) SetDeleted(count int) {
+	j.mu.Lock()
+	j.vmDeleted = count
+	j.mu.Unlock()
+
+	j.broadcast()
+	j.persist()
+}
+
+func (j *SyncJob) setState(state State, errMsg string) {
+	j.mu.Lock()
+	j.state = state
+	j.errMsg = errMsg
+	if state.terminal() {
+		now := time.Now()
+		j.finishedAt = &now
+	}
+	subscribers := j.subscribers
+	j.mu.Unlock()
+
+	j.broadcast()
+	j.persist()
+
+	if state.terminal() {
+		j.mu.Lock()
+		for ch := range subscribers {
+			delete(j.subscribers, ch)
+			close(ch)
+		}
+		j.mu.Unlock()
+	}
+}
+
+// Subscribe registers a channel that receives a Snapshot every time the
+// job's state or progress changes. The channel is closed once the job
+// reaches a terminal state. Call the returned function to unsubscribe
+// early.
+func (j *SyncJob) Subscribe() (<-chan Snapshot, func()) {
+	ch := make(chan Snapshot, 8)
+
+	j.mu.Lock()
+	j.subscribers[ch] = struct{}{}
+	j.mu.Unlock()
+
+	unsubscribe := func() {
+		j.mu.Lock()
+		if _, ok := j.subscribers[ch]; ok {
+			delete(j.subscribers, ch)
+			close(ch)
+		}
+		j.mu.Unlock()
+	}
+	return ch, unsubscribe
+}
+
+func (j *SyncJob) broadcast() {
+	snap := j.Snapshot()
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	for ch := range j.subscribers {
+		select {
+		case ch <- snap:
+		default:
+			// Subscriber too slow to keep up; drop the update rather than
+			// block the job that's making progress.
+		}
+	}
+}
+
+// Snapshot returns a point-in-time, JSON-serializable copy of the job's
+// state.
+func (j *SyncJob) Snapshot() Snapshot {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return Snapshot{
+		ID:          j.id,
+		EnvID:       j.envID,
+		State:       j.state,
+		StartedAt:   j.startedAt,
+		FinishedAt:  j.finishedAt,
+		VMTotal:     j.vmTotal,
+		VMProcessed: j.vmProcessed,
+		VMCreated:   j.vmCreated,
+		VMUpdated:   j.vmUpdated,
+		VMDeleted:   j.vmDeleted,
+		Error:       j.errMsg,
+	}
+}
+
+func (j *SyncJob) persist() {
+	snap := j.Snapshot()
+
+	_, err := j.manager.db.Exec(`
+		UPDATE sync_jobs
+		SET state = ?, finished_at = ?, vm_total = ?, vm_processed = ?,
+			vm_created = ?, vm_updated = ?, vm_deleted = ?, error = ?
+		WHERE id = ?
+	`, snap.State, snap.FinishedAt, snap.VMTotal, snap.VMProcessed,
+		snap.VMCreated, snap.VMUpdated, snap.VMDeleted, snap.Error, snap.ID)
+	if err != nil {
+		log.Printf("syncjob: failed to persist sync job %d: %v", snap.ID, err)
+	}
+}
@@ -0,0 +1,198 @@
+// Package webhooks notifies registered HTTP endpoints about migration
+// lifecycle events (syncing, cutting over, completed, failed, cancelled).
+// Deliveries are signed with HMAC-SHA256 so receivers can verify
+// authenticity, retried with exponential backoff on failure, and recorded to
+// an audit log.
+package webhooks
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sp00nznet/octopus/internal/db"
+)
+
+// signatureHeader carries the hex-encoded HMAC-SHA256 of the request body,
+// computed with the endpoint's signing secret.
+const signatureHeader = "X-Octopus-Signature"
+
+const deliveryTimeout = 10 * time.Second
+
+// Event is a migration lifecycle state change that an endpoint can subscribe
+// to, matched against WebhookEndpoint.Events.
+type Event string
+
+const (
+	EventSyncing     Event = "syncing"
+	EventCuttingOver Event = "cutting_over"
+	EventCompleted   Event = "completed"
+	EventFailed      Event = "failed"
+	EventCancelled   Event = "cancelled"
+)
+
+// Payload is the JSON body delivered to a webhook endpoint.
+type Payload struct {
+	Event        Event     `json:"event"`
+	ResourceType string    `json:"resource_type"`
+	ResourceID   int64     `json:"resource_id"`
+	Status       string    `json:"status"`
+	Message      string    `json:"message,omitempty"`
+	Timestamp    time.Time `json:"timestamp"`
+}
+
+// Manager dispatches events to the webhook endpoints registered in the
+// database, retrying failed deliveries with exponential backoff.
+type Manager struct {
+	db     *db.Database
+	client *http.Client
+}
+
+// NewManager creates a webhook Manager backed by database.
+func NewManager(database *db.Database) *Manager {
+	return &Manager{
+		db:     database,
+		client: &http.Client{Timeout: deliveryTimeout},
+	}
+}
+
+// Dispatch notifies every active endpoint subscribed to event about a
+// resource state change. Delivery happens asynchronously so callers like
+// cancelMigration and the scheduler aren't blocked on a slow or unreachable
+// receiver.
+func (m *Manager) Dispatch(event Event, resourceType string, resourceID int64, status, message string) {
+	endpoints, err := m.matchingEndpoints(event)
+	if err != nil {
+		log.Printf("webhooks: failed to list endpoints for event %s: %v", event, err)
+		return
+	}
+
+	payload := Payload{
+		Event:        event,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Status:       status,
+		Message:      message,
+		Timestamp:    time.Now(),
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("webhooks: failed to marshal payload for event %s: %v", event, err)
+		return
+	}
+
+	for _, ep := range endpoints {
+		go m.deliverWithRetry(ep, event, body)
+	}
+}
+
+// matchingEndpoints returns every active endpoint whose comma-separated
+// Events filter mask includes event.
+func (m *Manager) matchingEndpoints(event Event) ([]db.WebhookEndpoint, error) {
+	rows, err := m.db.Query(`
+		SELECT id, name, url, events, auth_token, signing_secret, max_retries, retry_backoff_seconds, is_active, created_at, updated_at
+		FROM webhook_endpoints WHERE is_active = TRUE
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var endpoints []db.WebhookEndpoint
+	for rows.Next() {
+		var ep db.WebhookEndpoint
+		if err := rows.Scan(&ep.ID, &ep.Name, &ep.URL, &ep.Events, &ep.AuthToken, &ep.SigningSecret,
+			&ep.MaxRetries, &ep.RetryBackoffSeconds, &ep.IsActive, &ep.CreatedAt, &ep.UpdatedAt); err != nil {
+			continue
+		}
+		if eventMatches(ep.Events, event) {
+			endpoints = append(endpoints, ep)
+		}
+	}
+	return endpoints, nil
+}
+
+func eventMatches(mask string, event Event) bool {
+	for _, e := range strings.Split(mask, ",") {
+		if strings.TrimSpace(e) == string(event) {
+			return true
+		}
+	}
+	return false
+}
+
+// deliverWithRetry POSTs body to ep.URL, making up to MaxRetries attempts in
+// all and sleeping RetryBackoffSeconds * 2^(attempt-1) between them, recording
+// every attempt to webhook_deliveries.
+func (m *Manager) deliverWithRetry(ep db.WebhookEndpoint, event Event, body []byte) {
+	backoff := time.Duration(ep.RetryBackoffSeconds) * time.Second
+	if backoff <= 0 {
+		backoff = 2 * time.Second
+	}
+	maxRetries := ep.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 5
+	}
+
+	for attempt := 1; attempt <= maxRetries; attempt++ {
+		statusCode, err := m.deliver(ep, body)
+		success := err == nil && statusCode >= 200 && statusCode < 300
+
+		errMsg := ""
+		if err != nil {
+			errMsg = err.Error()
+		}
+		m.recordDelivery(ep.ID, event, body, statusCode, attempt, success, errMsg)
+
+		if success {
+			return
+		}
+		if attempt < maxRetries {
+			time.Sleep(backoff * time.Duration(1<<uint(attempt-1)))
+		}
+	}
+}
+
+func (m *Manager) deliver(ep db.WebhookEndpoint, body []byte) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, ep.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(signatureHeader, sign(ep.SigningSecret, body))
+	if ep.AuthToken != "" {
+		req.Header.Set("Authorization", "Bearer "+ep.AuthToken)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+func (m *Manager) recordDelivery(endpointID int64, event Event, payload []byte, statusCode, attempt int, success bool, errMsg string) {
+	_, err := m.db.Exec(`
+		INSERT INTO webhook_deliveries (endpoint_id, event, payload_json, status_code, attempt, success, error_message)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, endpointID, event, string(payload), statusCode, attempt, success, errMsg)
+	if err != nil {
+		log.Printf("webhooks: failed to record delivery for endpoint %d: %v", endpointID, err)
+	}
+}
+
+// sign returns the hex-encoded HMAC-SHA256 of body using secret.
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}